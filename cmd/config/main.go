@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+)
+
+// config shows the configuration the binaries would run with, from CONFIG_FILE and the
+// environment:
+//
+//	go run ./cmd/config print
+//	CONFIG_FILE=config.yaml go run ./cmd/config check
+func main() {
+	if len(os.Args) != 2 {
+		usage()
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	switch os.Args[1] {
+	case "print":
+		// secrets are masked, so the output is safe to paste but not to run with
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal("Failed to print configuration:", err)
+		}
+
+	case "check":
+		fmt.Println("Configuration is valid")
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: config print | check")
+	os.Exit(2)
+}
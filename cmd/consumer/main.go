@@ -2,28 +2,51 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
-	"os"
-	"strings"
+	"log/slog"
+	"net/http"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/database"
 	"github.com/rohanreddymelachervu/ingestor/internal/events"
+	"github.com/rohanreddymelachervu/ingestor/internal/health"
 	"github.com/rohanreddymelachervu/ingestor/internal/kafka"
+	"github.com/rohanreddymelachervu/ingestor/internal/logging"
+	"github.com/rohanreddymelachervu/ingestor/internal/metrics"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
 	"github.com/rohanreddymelachervu/ingestor/internal/repository"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
+	"github.com/rohanreddymelachervu/ingestor/internal/tracing"
 )
 
 func main() {
-	log.Println("🚀 Starting Kafka Event Consumer...")
+	// Load configuration from CONFIG_FILE and the environment
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	logging.Setup(cfg.Logging)
+	slog.Info("starting Kafka event consumer")
 
-	// Load configuration
-	cfg := config.Load()
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, "ingestor-consumer")
+	if err != nil {
+		logging.Fatal("failed to set up tracing", err)
+	}
 
 	// Connect to database
-	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	db, err := database.Open(cfg.Database)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
+		logging.Fatal("failed to open database", err)
+	}
+	migrator, err := database.Migrator(db)
+	if err != nil {
+		logging.Fatal("failed to load migrations", err)
+	}
+	if err := database.CheckSchema(migrator); err != nil {
+		logging.Fatal("database schema is out of date", err)
 	}
 
 	// Initialize repositories
@@ -31,40 +54,74 @@ func main() {
 	quizRepo := repository.NewQuizRepository(db)
 	sessionRepo := repository.NewSessionRepository(db)
 	classroomRepo := repository.NewClassroomRepository(db)
+	teacherRepo := repository.NewTeacherRepository(db)
+	provisioningRepo := repository.NewProvisioningRepository(db)
+	tenantRepo := repository.NewTenantRepository(db)
 
 	// Initialize event service
-	eventService := events.NewService(eventRepo, quizRepo, sessionRepo, classroomRepo)
-
-	// Kafka configuration
-	kafkaBrokers := getKafkaBrokers()
-	groupID := "analytics-event-processors"
-	topics := []string{"quiz-events"}
+	eventService := events.NewService(eventRepo, quizRepo, sessionRepo, classroomRepo, teacherRepo, provisioningRepo, tenantRepo, cfg.Provisioning)
 
-	log.Printf("Connecting to Kafka brokers: %v", kafkaBrokers)
-	log.Printf("Consumer group: %s", groupID)
-	log.Printf("Topics: %v", topics)
+	slog.Info("connecting to Kafka", "brokers", cfg.Kafka.Brokers, "group", cfg.Kafka.Consumer.GroupID, "topics", cfg.Kafka.Consumer.Topics)
 
 	// Create Kafka consumer
-	consumer, err := kafka.NewConsumer(kafkaBrokers, groupID, topics, eventService)
+	// Each message is processed with the event service of the tenant that published it
+	consumer, err := kafka.NewConsumer(cfg.Kafka, func(ctx context.Context, tenantID uuid.UUID) kafka.EventProcessor {
+		return eventService.ForTenant(tenantID).WithContext(ctx)
+	})
 	if err != nil {
-		log.Fatal("Failed to create Kafka consumer:", err)
+		logging.Fatal("failed to create Kafka consumer", err)
 	}
 
+	// Health endpoints; the consumer has no other HTTP listener
+	monitor := health.NewMonitor(2 * time.Second)
+	monitor.Add("postgres", health.Postgres(db))
+	monitor.Add("migrations", health.Migrations(db, models.SchemaVersion))
+	monitor.Add("kafka_consumer", health.Pinger(consumer.Ping))
+	if sqlDB, err := db.DB(); err == nil {
+		metrics.RegisterDBStats(sqlDB)
+	}
+	healthServer := startHealthServer(cfg.HTTP, health.NewHandler(monitor))
+
 	// Start consuming
 	ctx := context.Background()
-	log.Println("📨 Starting event consumption...")
-
 	if err := consumer.Start(ctx); err != nil {
-		log.Fatal("Consumer failed:", err)
+		logging.Fatal("consumer failed", err)
 	}
 
-	log.Println("🛑 Consumer stopped")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	healthServer.Shutdown(shutdownCtx)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		slog.Error("failed to export remaining spans", "error", err)
+	}
+
+	slog.Info("consumer stopped")
 }
 
-func getKafkaBrokers() []string {
-	brokers := os.Getenv("KAFKA_BROKERS")
-	if brokers == "" {
-		brokers = "localhost:9092" // Default for local development
+// startHealthServer serves /healthz, /readyz, /status and /metrics on the health port. Unlike the
+// API server's, /status carries no auth, so the port must not be exposed outside the cluster.
+func startHealthServer(cfg config.HTTPConfig, h *health.Handler) *http.Server {
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		logging.Fatal("invalid TRUSTED_PROXIES", err)
+	}
+	r.Use(gin.Recovery())
+	r.GET("/healthz", h.Liveness)
+	r.GET("/readyz", h.Readiness)
+	r.GET("/status", h.Status)
+	r.GET("/metrics", metrics.Handler())
+
+	srv := &http.Server{
+		Addr:        ":" + cfg.HealthPort,
+		Handler:     r,
+		ReadTimeout: 5 * time.Second,
+		IdleTimeout: time.Minute,
 	}
-	return strings.Split(brokers, ",")
+	go func() {
+		slog.Info("health endpoints listening", "port", cfg.HealthPort)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("health server failed", err)
+		}
+	}()
+	return srv
 }
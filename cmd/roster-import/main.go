@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/database"
+	"github.com/rohanreddymelachervu/ingestor/internal/oneroster"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+)
+
+// roster-import loads a OneRoster CSV export from a directory into a tenant:
+//
+//	go run ./cmd/roster-import -tenant springfield -dir ./export -dry-run
+func main() {
+	dir := flag.String("dir", ".", "directory containing orgs.csv, classes.csv, users.csv and enrollments.csv")
+	dryRun := flag.Bool("dry-run", false, "print the diff without writing to the database")
+	tenantSlug := flag.String("tenant", "default", "slug of the tenant to import into")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// Connect to database
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+
+	tenant, err := repository.NewTenantRepository(db).GetTenantBySlug(*tenantSlug)
+	if err != nil {
+		log.Fatalf("Failed to load tenant %s: %v", *tenantSlug, err)
+	}
+
+	files := make(map[string]io.Reader, len(oneroster.Files))
+	for _, name := range oneroster.Files {
+		file, err := os.Open(filepath.Join(*dir, name))
+		if err != nil {
+			log.Fatalf("Failed to open %s: %v", name, err)
+		}
+		defer file.Close()
+		files[name] = file
+	}
+
+	importService := oneroster.NewService(repository.NewClassroomRepository(db)).ForTenant(tenant.TenantID)
+	report, err := importService.Import(files, *dryRun)
+	if err != nil {
+		log.Fatal("Roster import failed:", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		log.Fatal("Failed to write report:", err)
+	}
+
+	if len(report.Errors) > 0 {
+		log.Printf("%d rows were rejected, see errors in the report", len(report.Errors))
+		os.Exit(1)
+	}
+}
@@ -1,41 +1,115 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"gorm.io/driver/postgres"
-	"gorm.io/gorm"
-
 	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/database"
+	"github.com/rohanreddymelachervu/ingestor/internal/logging"
 	"github.com/rohanreddymelachervu/ingestor/internal/server"
+	"github.com/rohanreddymelachervu/ingestor/internal/tracing"
 )
 
 func main() {
-	// Load configuration from environment
-	cfg := config.Load()
+	// The migrate subcommand only needs the database settings
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+
+	// Load configuration from CONFIG_FILE and the environment
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	logging.Setup(cfg.Logging)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing, "ingestor-server")
+	if err != nil {
+		logging.Fatal("failed to set up tracing", err)
+	}
 
 	// Connect to Postgres
-	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+	db, err := database.Open(cfg.Database)
 	if err != nil {
-		log.Fatalf("failed to connect database: %v", err)
+		logging.Fatal("failed to open database", err)
 	}
 
-	// Skip auto-migration since we have explicit migration files
-	// The database schema is managed by the migration files in migrations/postgres/
-	log.Println("Using existing database schema from migration files")
+	// The schema is managed by the migrations embedded from migrations/postgres: the
+	// migrate subcommand applies them, and the server refuses to start until it has
+	migrator, err := database.Migrator(db)
+	if err != nil {
+		logging.Fatal("failed to load migrations", err)
+	}
+	if err := database.CheckSchema(migrator); err != nil {
+		logging.Fatal("database schema is out of date", err)
+	}
 
-	// Initialize Gin router
-	r := gin.Default()
+	// Initialize Gin router; requests are logged by the logging middleware
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.HTTP.TrustedProxies); err != nil {
+		logging.Fatal("invalid TRUSTED_PROXIES", err)
+	}
+	r.Use(gin.Recovery())
 
-	server.RegisterRoutes(r, cfg.JWTSecret, db)
+	closeHandlers := server.RegisterRoutes(r, cfg, db)
 
 	// Start server
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	srv := &http.Server{
+		Addr:         ":" + cfg.HTTP.Port,
+		Handler:      r,
+		ReadTimeout:  cfg.HTTP.ReadTimeout,
+		WriteTimeout: cfg.HTTP.WriteTimeout,
+		IdleTimeout:  cfg.HTTP.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "port", cfg.HTTP.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-serveErr:
+		logging.Fatal("server failed", err)
+	case <-ctx.Done():
+		// a second signal kills the process instead of waiting for the drain
+		stop()
+	}
+
+	// Shut down in order: drain in-flight requests, then flush and close the Kafka
+	// producer they may still publish to, then close the database pool, and finally
+	// export the spans of all that
+	slog.Info("shutting down, waiting for in-flight requests", "timeout", cfg.HTTP.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.HTTP.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Warn("requests still in flight at the shutdown deadline were cut off", "error", err)
+	}
+
+	closeHandlers()
+
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("failed to close database pool", "error", err)
+		}
+	}
+
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		slog.Error("failed to export remaining spans", "error", err)
 	}
-	log.Printf("Server starting on port %s", port)
-	r.Run(":" + port)
+	slog.Info("server stopped")
 }
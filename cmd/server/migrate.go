@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/database"
+)
+
+// runMigrate runs the migrate subcommand and returns the exit code:
+//
+//	server migrate up
+//	server migrate down [-steps 1] [-all]
+//	server migrate status
+//	server migrate force VERSION
+//
+// Up, down and force wait up to -lock-timeout, given before VERSION, for any other
+// migrator to finish. Only the database section of the configuration is validated.
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		return migrateUsage()
+	}
+	command := args[0]
+	switch command {
+	case "up", "down", "status", "force":
+	default:
+		return migrateUsage()
+	}
+
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	lockTimeout := flags.Duration("lock-timeout", 2*time.Minute, "how long to wait for another migrator")
+	steps := flags.Int("steps", 1, "number of migrations to revert (down)")
+	all := flags.Bool("all", false, "revert every migration (down)")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	dbConfig, err := config.LoadDatabase()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+	db, err := database.Open(*dbConfig)
+	if err != nil {
+		return migrateFailed(err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	migrator, err := database.Migrator(db)
+	if err != nil {
+		return migrateFailed(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *lockTimeout)
+	defer cancel()
+
+	switch command {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, m := range applied {
+			fmt.Printf("applied %06d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return migrateFailed(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+
+	case "down":
+		if *all {
+			*steps = int(migrator.Latest())
+		}
+		if *steps < 1 {
+			fmt.Fprintln(os.Stderr, "-steps must be at least 1")
+			return 2
+		}
+		reverted, err := migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Printf("reverted %06d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			return migrateFailed(err)
+		}
+
+	case "status":
+		state, pending, err := migrator.Status(ctx)
+		if err != nil {
+			return migrateFailed(err)
+		}
+		fmt.Printf("version: %d (latest %d)\n", state.Version, migrator.Latest())
+		if state.Dirty {
+			fmt.Println("dirty: the last migration failed halfway")
+		}
+		for _, m := range pending {
+			fmt.Printf("pending %06d_%s\n", m.Version, m.Name)
+		}
+
+	case "force":
+		if flags.NArg() != 1 {
+			return migrateUsage()
+		}
+		version, err := strconv.ParseUint(flags.Arg(0), 10, 64)
+		if err != nil {
+			return migrateUsage()
+		}
+		if err := migrator.Force(ctx, uint(version)); err != nil {
+			return migrateFailed(err)
+		}
+		fmt.Printf("schema marked clean at version %d\n", version)
+
+	default:
+		return migrateUsage()
+	}
+	return 0
+}
+
+func migrateFailed(err error) int {
+	fmt.Fprintln(os.Stderr, "migrate:", err)
+	return 1
+}
+
+func migrateUsage() int {
+	fmt.Fprintln(os.Stderr, "usage: server migrate [up | down [-steps N | -all] | status | force VERSION]")
+	return 2
+}
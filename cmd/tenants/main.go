@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/database"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+	"github.com/rohanreddymelachervu/ingestor/internal/tenant"
+)
+
+// tenants manages tenants and applies their retention settings:
+//
+//	go run ./cmd/tenants create -slug springfield -name "Springfield District"
+//	go run ./cmd/tenants list
+//	go run ./cmd/tenants admin -tenant springfield -name "Jane Doe" -email jane@example.com < password.txt
+//	go run ./cmd/tenants purge -dry-run
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// Connect to database
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	tenantService := tenant.NewService(repository.NewTenantRepository(db))
+
+	switch os.Args[1] {
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ExitOnError)
+		slug := flags.String("slug", "", "URL-safe identifier users sign up with")
+		name := flags.String("name", "", "display name")
+		flags.Parse(os.Args[2:])
+
+		created, err := tenantService.CreateTenant(*slug, *name)
+		if err != nil {
+			log.Fatal("Failed to create tenant:", err)
+		}
+		fmt.Printf("Created tenant %s (%s)\n", created.Slug, created.TenantID)
+
+	case "list":
+		tenants, err := tenantService.ListTenants()
+		if err != nil {
+			log.Fatal("Failed to list tenants:", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SLUG\tTENANT ID\tNAME\tLATE ANSWERS\tRETENTION")
+		for _, t := range tenants {
+			retention := "forever"
+			if t.RetentionDays != nil {
+				retention = fmt.Sprintf("%d days", *t.RetentionDays)
+			}
+			policy := t.LateAnswerPolicy
+			if policy == models.LateAnswerGrace {
+				policy = fmt.Sprintf("grace (%ds)", t.LateAnswerGraceSec)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", t.Slug, t.TenantID, t.Name, policy, retention)
+		}
+		w.Flush()
+
+	case "admin":
+		// Creates a tenant's first admin, who then manages users over the API
+		flags := flag.NewFlagSet("admin", flag.ExitOnError)
+		slug := flags.String("tenant", "default", "slug of the tenant")
+		name := flags.String("name", "", "display name")
+		email := flags.String("email", "", "login email")
+		flags.Parse(os.Args[2:])
+		if *name == "" || *email == "" {
+			usage()
+		}
+
+		// the password is read from stdin so it does not end up in shell history
+		password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && password == "" {
+			log.Fatal("Failed to read password from stdin:", err)
+		}
+		password = strings.TrimRight(password, "\r\n")
+
+		keys, err := auth.NewKeySet(cfg.Auth.JWTSecret, cfg.Auth.JWTKeysDir, cfg.Auth.JWTSigningKID)
+		if err != nil {
+			log.Fatal("Failed to load JWT signing keys:", err)
+		}
+		// no password resets are sent from here
+		authService := auth.NewService(db, keys, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL,
+			cfg.Auth.Passwords, cfg.Auth.Lockout, nil)
+		user, err := authService.CreateUser(*name, *email, password, auth.RoleAdmin, *slug)
+		if err != nil {
+			log.Fatal("Failed to create admin:", err)
+		}
+		fmt.Printf("Created admin %s (user %d) in tenant %s\n", user.Email, user.ID, *slug)
+
+	case "purge":
+		// Meant to run on a schedule, e.g. a nightly cron job
+		flags := flag.NewFlagSet("purge", flag.ExitOnError)
+		dryRun := flags.Bool("dry-run", false, "only count the sessions that would be deleted")
+		flags.Parse(os.Args[2:])
+
+		tenants, err := tenantService.ListTenants()
+		if err != nil {
+			log.Fatal("Failed to list tenants:", err)
+		}
+		now := time.Now()
+		for i := range tenants {
+			count, err := tenantService.PurgeExpired(&tenants[i], now, *dryRun)
+			if err != nil {
+				log.Fatalf("Failed to purge tenant %s: %v", tenants[i].Slug, err)
+			}
+			if *dryRun {
+				log.Printf("%s: %d expired sessions would be deleted", tenants[i].Slug, count)
+			} else {
+				log.Printf("%s: deleted %d expired sessions", tenants[i].Slug, count)
+			}
+		}
+
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: tenants create -slug SLUG -name NAME | list | admin -tenant SLUG -name NAME -email EMAIL | purge [-dry-run]")
+	os.Exit(2)
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Measure represents a quantitative metric that can be aggregated
@@ -40,10 +42,35 @@ type TimeRange struct {
 }
 
 type OrderBy struct {
-	Field string `json:"field"`
+	Field string `json:"field"` // a requested measure or dimension
 	Order string `json:"order"` // ASC, DESC
 }
 
+// Restriction is a mandatory filter added to every query of a caller with limited
+// access; the request cannot remove it. A nil *Restriction leaves queries unrestricted.
+type Restriction struct {
+	// StudentID limits answers to the student's own
+	StudentID *uuid.UUID
+	// ClassroomIDs limits answers to sessions of these classrooms when StudentID is nil;
+	// an empty list matches nothing
+	ClassroomIDs []uuid.UUID
+}
+
+// condition renders the restriction as a WHERE condition
+func (r *Restriction) condition() string {
+	if r.StudentID != nil {
+		return fmt.Sprintf("ase.student_id = '%s'", *r.StudentID)
+	}
+	if len(r.ClassroomIDs) == 0 {
+		return "FALSE"
+	}
+	ids := make([]string, len(r.ClassroomIDs))
+	for i, id := range r.ClassroomIDs {
+		ids[i] = "'" + id.String() + "'"
+	}
+	return fmt.Sprintf("qs.classroom_id IN (%s)", strings.Join(ids, ", "))
+}
+
 // Analytics cube definitions
 var QuizAnalyticsCube = map[string]interface{}{
 	"measures": map[string]Measure{
@@ -78,6 +105,25 @@ var QuizAnalyticsCube = map[string]interface{}{
 			Type:        "count",
 			SQL:         "COUNT(DISTINCT qpe.question_id)",
 		},
+		"average_score": {
+			Name:        "average_score",
+			DisplayName: "Average Score",
+			Type:        "avg",
+			SQL:         "ROUND(AVG(ase.score) * 100, 2)",
+			Format:      "percentage",
+		},
+		"total_score": {
+			Name:        "total_score",
+			DisplayName: "Total Score",
+			Type:        "sum",
+			SQL:         "ROUND(SUM(ase.score)::numeric, 2)",
+		},
+		"partial_credit_answers": {
+			Name:        "partial_credit_answers",
+			DisplayName: "Partial Credit Answers",
+			Type:        "count",
+			SQL:         "COUNT(CASE WHEN ase.score > 0 AND ase.score < 1 THEN 1 END)",
+		},
 		// STUDENT PERFORMANCE ANALYSIS MEASURES
 		"wrong_answers": {
 			Name:        "wrong_answers",
@@ -208,6 +254,24 @@ var QuizAnalyticsCube = map[string]interface{}{
 			Type:        "string",
 			SQL:         "ase.question_id",
 		},
+		"question_text": {
+			Name:        "question_text",
+			DisplayName: "Question Text",
+			Type:        "string",
+			SQL:         "COALESCE(qn.text, ase.question_id::text)",
+		},
+		"question_type": {
+			Name:        "question_type",
+			DisplayName: "Question Type",
+			Type:        "string",
+			SQL:         "COALESCE(qn.question_type, 'single_choice')",
+		},
+		"ingested_by": {
+			Name:        "ingested_by",
+			DisplayName: "Ingested By",
+			Type:        "string",
+			SQL:         "ase.ingested_by",
+		},
 		"answer_option": {
 			Name:        "answer_option",
 			DisplayName: "Answer Choice",
@@ -279,11 +343,11 @@ var QuizAnalyticsCube = map[string]interface{}{
 			Type:        "string",
 			SQL:         "CASE WHEN qpe.timer_duration_sec < 30 THEN 'Fast' WHEN qpe.timer_duration_sec < 60 THEN 'Medium' ELSE 'Slow' END",
 		},
-		"teacher_id": {
-			Name:        "teacher_id",
+		"teacher_name": {
+			Name:        "teacher_name",
 			DisplayName: "Teacher",
 			Type:        "string",
-			SQL:         "qpe.teacher_id",
+			SQL:         "COALESCE(t.name, t.teacher_id::text)",
 		},
 		// TEMPORAL DIMENSIONS
 		"event_week": {
@@ -316,8 +380,8 @@ var QuizAnalyticsCube = map[string]interface{}{
 	},
 }
 
-// QueryBuilder generates SQL from generic query request
-func (qr *QueryRequest) BuildSQL() (string, error) {
+// BuildSQL generates SQL from a generic query request, limited by the caller's restriction
+func (qr *QueryRequest) BuildSQL(restriction *Restriction) (string, error) {
 	measures := QuizAnalyticsCube["measures"].(map[string]Measure)
 	dimensions := QuizAnalyticsCube["dimensions"].(map[string]Dimension)
 
@@ -351,6 +415,8 @@ func (qr *QueryRequest) BuildSQL() (string, error) {
 		LEFT JOIN classrooms c ON qs.classroom_id = c.classroom_id
 		LEFT JOIN students s ON ase.student_id = s.student_id
 		LEFT JOIN question_published_events qpe ON ase.question_id = qpe.question_id AND ase.session_id = qpe.session_id
+		LEFT JOIN questions qn ON ase.question_id = qn.question_id
+		LEFT JOIN teachers t ON t.teacher_id = COALESCE(qs.teacher_id, qpe.teacher_id)
 	`, strings.Join(selectFields, ", "))
 
 	// Add filters
@@ -364,10 +430,14 @@ func (qr *QueryRequest) BuildSQL() (string, error) {
 
 	for field, value := range qr.Filters {
 		if dim, exists := dimensions[field]; exists {
-			whereConditions = append(whereConditions, fmt.Sprintf("%s = '%s'", dim.SQL, value))
+			whereConditions = append(whereConditions, fmt.Sprintf("(%s) = %s", dim.SQL, quoteLiteral(value)))
 		}
 	}
 
+	if restriction != nil {
+		whereConditions = append(whereConditions, restriction.condition())
+	}
+
 	if len(whereConditions) > 0 {
 		query += " WHERE " + strings.Join(whereConditions, " AND ")
 	}
@@ -377,11 +447,29 @@ func (qr *QueryRequest) BuildSQL() (string, error) {
 		query += " GROUP BY " + strings.Join(groupByFields, ", ")
 	}
 
-	// Add ORDER BY
+	// Add ORDER BY; only the requested columns can be sorted on
 	if len(qr.OrderBy) > 0 {
+		selected := make(map[string]bool, len(qr.Measures)+len(qr.Dimensions))
+		for _, name := range qr.Measures {
+			_, selected[name] = measures[name]
+		}
+		for _, name := range qr.Dimensions {
+			_, selected[name] = dimensions[name]
+		}
 		var orderFields []string
 		for _, order := range qr.OrderBy {
-			orderFields = append(orderFields, fmt.Sprintf("%s %s", order.Field, order.Order))
+			if !selected[order.Field] {
+				return "", fmt.Errorf("cannot order by %q: not a requested measure or dimension", order.Field)
+			}
+			direction := strings.ToUpper(order.Order)
+			switch direction {
+			case "":
+				direction = "ASC"
+			case "ASC", "DESC":
+			default:
+				return "", fmt.Errorf("order for %q must be ASC or DESC", order.Field)
+			}
+			orderFields = append(orderFields, order.Field+" "+direction)
 		}
 		query += " ORDER BY " + strings.Join(orderFields, ", ")
 	}
@@ -393,3 +481,8 @@ func (qr *QueryRequest) BuildSQL() (string, error) {
 
 	return query, nil
 }
+
+// quoteLiteral renders a filter value as a SQL string literal
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+)
+
+type Service struct {
+	AuditRepo repository.AuditRepository
+}
+
+func NewService(auditRepo repository.AuditRepository) *Service {
+	return &Service{AuditRepo: auditRepo}
+}
+
+// Record appends the entry to the log of its tenant. It is called after the action
+// happened, so a failure to record is logged rather than failing the request.
+func (s *Service) Record(entry *models.AuditEntry) {
+	tenantID := uuid.Nil
+	if entry.TenantID != nil {
+		tenantID = *entry.TenantID
+	}
+	if err := s.AuditRepo.ForTenant(tenantID).AppendEntry(entry); err != nil {
+		slog.Error("failed to record audit entry", "action", entry.Action, "error", err)
+	}
+}
+
+// ListEntries pages through the tenant's entries, newest first
+func (s *Service) ListEntries(tenantID uuid.UUID, filter repository.AuditFilter, pagination repository.PaginationParams) (*repository.PaginatedResponse[models.AuditEntry], error) {
+	return s.AuditRepo.ForTenant(tenantID).ListEntries(filter, pagination)
+}
+
+// ExportEntries passes the tenant's entries, oldest first, to fn in batches
+func (s *Service) ExportEntries(tenantID uuid.UUID, filter repository.AuditFilter, fn func(entries []models.AuditEntry) error) error {
+	return s.AuditRepo.ForTenant(tenantID).ExportEntries(filter, fn)
+}
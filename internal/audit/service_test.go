@@ -0,0 +1,46 @@
+package audit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+	"github.com/rohanreddymelachervu/ingestor/internal/testdb"
+)
+
+// The audit_log_append_only trigger rejects changes to recorded entries, whoever makes them
+func TestAuditLogIsAppendOnly(t *testing.T) {
+	db := testdb.Open(t)
+	tenantID := models.DefaultTenantID
+	entry := &models.AuditEntry{
+		TenantID: &tenantID,
+		Action:   "test.append_only",
+		Outcome:  models.AuditSuccess,
+		TargetID: uuid.NewString(),
+	}
+	if err := repository.NewAuditRepository(db).ForTenant(tenantID).AppendEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.EntryID == 0 {
+		t.Fatal("the entry was not given an ID")
+	}
+
+	update := db.Model(&models.AuditEntry{}).Where("entry_id = ?", entry.EntryID).Update("outcome", models.AuditFailure)
+	if update.Error == nil || !strings.Contains(update.Error.Error(), "append-only") {
+		t.Errorf("UPDATE: got %v, want the trigger's error", update.Error)
+	}
+	remove := db.Where("entry_id = ?", entry.EntryID).Delete(&models.AuditEntry{})
+	if remove.Error == nil || !strings.Contains(remove.Error.Error(), "append-only") {
+		t.Errorf("DELETE: got %v, want the trigger's error", remove.Error)
+	}
+
+	var stored models.AuditEntry
+	if err := db.First(&stored, entry.EntryID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.Outcome != models.AuditSuccess {
+		t.Errorf("the entry was changed to %+v", stored)
+	}
+}
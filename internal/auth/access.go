@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Access levels - matches 000018_report_access.up.sql
+const (
+	AccessTenant  = "tenant"  // every classroom of the tenant
+	AccessSchool  = "school"  // classrooms of one school, e.g. a principal
+	AccessTeacher = "teacher" // classrooms the teacher is assigned to
+	AccessStudent = "student" // the student's own results
+	AccessNone    = "none"    // signed up, waiting for an admin to grant access
+)
+
+// ErrInvalidAccess is returned for an access level without exactly its own subject ID
+var ErrInvalidAccess = errors.New("invalid access")
+
+// Access limits which classrooms and students a user may report on. Exactly the ID
+// matching Level is set; tenant-wide access and no access carry none.
+type Access struct {
+	Level     string     `json:"level"`
+	SchoolID  *uuid.UUID `json:"school_id,omitempty"`
+	TeacherID *uuid.UUID `json:"teacher_id,omitempty"`
+	StudentID *uuid.UUID `json:"student_id,omitempty"`
+}
+
+// Validate checks that the level is known and that only its subject ID is set
+func (a Access) Validate() error {
+	var want *uuid.UUID
+	switch a.Level {
+	case AccessTenant, AccessNone:
+	case AccessSchool:
+		want = a.SchoolID
+	case AccessTeacher:
+		want = a.TeacherID
+	case AccessStudent:
+		want = a.StudentID
+	default:
+		return fmt.Errorf("%w: level must be %s, %s, %s, %s or %s", ErrInvalidAccess, AccessTenant, AccessSchool, AccessTeacher, AccessStudent, AccessNone)
+	}
+	if a.Level != AccessTenant && a.Level != AccessNone && want == nil {
+		return fmt.Errorf("%w: %s access requires %s_id", ErrInvalidAccess, a.Level, a.Level)
+	}
+	set := 0
+	for _, id := range []*uuid.UUID{a.SchoolID, a.TeacherID, a.StudentID} {
+		if id != nil {
+			set++
+		}
+	}
+	if (want == nil && set > 0) || set > 1 {
+		return fmt.Errorf("%w: %s access only takes its own ID", ErrInvalidAccess, a.Level)
+	}
+	return nil
+}
+
+// TenantWide reports whether the access is unrestricted within the tenant
+func (a Access) TenantWide() bool {
+	return a.Level == AccessTenant
+}
+
+// UserAccess returns the reporting access of the authenticated caller
+func UserAccess(c *gin.Context) Access {
+	access, _ := c.Get("access")
+	a, _ := access.(Access)
+	return a
+}
+
+// RequireTenantAccess rejects callers whose access is restricted to a school, teacher or student
+func RequireTenantAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !UserAccess(c).TenantWide() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "requires tenant-wide access"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireGrantedAccess rejects callers who signed up and were not granted access yet
+func RequireGrantedAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if UserAccess(c).Level == AccessNone {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "your account is waiting for an admin to grant access"})
+			return
+		}
+		c.Next()
+	}
+}
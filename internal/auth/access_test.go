@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestAccessValidate(t *testing.T) {
+	id := uuid.New()
+	cases := []struct {
+		access Access
+		valid  bool
+	}{
+		{Access{Level: AccessTenant}, true},
+		{Access{Level: AccessNone}, true},
+		{Access{Level: AccessSchool, SchoolID: &id}, true},
+		{Access{Level: AccessTeacher, TeacherID: &id}, true},
+		{Access{Level: AccessStudent, StudentID: &id}, true},
+		{Access{}, false},
+		{Access{Level: "admin"}, false},
+		{Access{Level: AccessNone, StudentID: &id}, false},
+		{Access{Level: AccessTenant, SchoolID: &id}, false},
+		{Access{Level: AccessSchool}, false},
+		{Access{Level: AccessTeacher, SchoolID: &id}, false},
+		{Access{Level: AccessStudent, StudentID: &id, TeacherID: &id}, false},
+	}
+	for _, tc := range cases {
+		if err := tc.access.Validate(); (err == nil) != tc.valid {
+			t.Errorf("%+v: got %v, want valid %v", tc.access, err, tc.valid)
+		}
+	}
+}
+
+func TestRequireGrantedAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, level := range []string{AccessNone, AccessTenant} {
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) { c.Set("access", Access{Level: level}) }, RequireGrantedAccess(), func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+		})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		want := http.StatusNoContent
+		if level == AccessNone {
+			want = http.StatusForbidden
+		}
+		if w.Code != want {
+			t.Errorf("%s access: got %d, want %d", level, w.Code, want)
+		}
+	}
+}
+
+// Signup is refused before anything is stored: the handler has no service to call
+func TestSignUpRestrictions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := `{"name":"Jo","email":"jo@example.com","password":"Secure-Password-1","role":"writer","tenant":"springfield"}`
+	cases := []struct {
+		name        string
+		allowSignup bool
+		body        string
+	}{
+		{"disabled", false, body},
+		{"other tenant", true, body},
+		{"admin role", true, strings.NewReplacer(`"writer"`, `"admin"`, `"springfield"`, `"default"`).Replace(body)},
+	}
+	for _, tc := range cases {
+		h := NewHandler(nil, tc.allowSignup, nil)
+		r := gin.New()
+		r.POST("/signup", h.SignUp)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(tc.body)))
+		if w.Code != http.StatusForbidden && w.Code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want the signup refused", tc.name, w.Code)
+		}
+	}
+}
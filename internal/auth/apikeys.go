@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix starts every device key so that leaked keys are easy to recognise
+const apiKeyPrefix = "ik"
+
+// apiKeyScopes are the scopes granted to device keys
+var apiKeyScopes = []string{"WRITE"}
+
+// ErrAPIKeyNotFound is returned when a key does not exist in the tenant
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+// ErrInvalidAPIKey is returned for malformed, unknown or revoked keys
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// ErrUnknownClassroom is returned when a key is scoped to a classroom outside the tenant
+var ErrUnknownClassroom = errors.New("unknown classroom")
+
+// APIKey identifies a whiteboard or notebook device - matches 000021_api_keys.up.sql.
+// The key itself is only returned when it is issued or rotated.
+type APIKey struct {
+	APIKeyID     uuid.UUID   `gorm:"column:api_key_id;type:uuid;primary_key" json:"api_key_id"`
+	TenantID     uuid.UUID   `gorm:"type:uuid;not null" json:"tenant_id"`
+	Name         string      `gorm:"not null" json:"name"`
+	Prefix       string      `gorm:"not null;uniqueIndex" json:"prefix"` // shown in listings to tell keys apart
+	KeyHash      string      `gorm:"not null" json:"-"`
+	CreatedBy    *uint       `json:"created_by,omitempty"`
+	ClassroomIDs []uuid.UUID `gorm:"-" json:"classroom_ids"` // empty for tenant-wide keys
+	LastUsedAt   *time.Time  `json:"last_used_at,omitempty"`
+	RotatedAt    *time.Time  `json:"rotated_at,omitempty"`
+	RevokedAt    *time.Time  `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// APIKeyClassroom restricts a key to a classroom - matches 000021_api_keys.up.sql
+type APIKeyClassroom struct {
+	APIKeyID    uuid.UUID `gorm:"column:api_key_id;type:uuid;primaryKey"`
+	TenantID    uuid.UUID `gorm:"type:uuid;not null"`
+	ClassroomID uuid.UUID `gorm:"type:uuid;primaryKey"`
+}
+
+func (APIKeyClassroom) TableName() string {
+	return "api_key_classrooms"
+}
+
+// IssuedAPIKey carries the plaintext key; it cannot be retrieved again
+type IssuedAPIKey struct {
+	*APIKey
+	Key string `json:"key"`
+}
+
+// lastUsedResolution limits how often verifying a key writes its last_used_at
+const lastUsedResolution = time.Minute
+
+// IssueAPIKey creates a device key for the tenant, optionally limited to the given classrooms
+func (s *Service) IssueAPIKey(tenantID uuid.UUID, name string, classroomIDs []uuid.UUID, createdBy uint) (*IssuedAPIKey, error) {
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	apiKey := &APIKey{
+		APIKeyID:     uuid.New(),
+		TenantID:     tenantID,
+		Name:         name,
+		Prefix:       prefix,
+		KeyHash:      hashToken(key),
+		ClassroomIDs: dedupeIDs(classroomIDs),
+	}
+	if createdBy != 0 {
+		apiKey.CreatedBy = &createdBy
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkClassrooms(tx, tenantID, apiKey.ClassroomIDs); err != nil {
+			return err
+		}
+		if err := tx.Create(apiKey).Error; err != nil {
+			return err
+		}
+		for _, classroomID := range apiKey.ClassroomIDs {
+			link := &APIKeyClassroom{APIKeyID: apiKey.APIKeyID, TenantID: tenantID, ClassroomID: classroomID}
+			if err := tx.Create(link).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys returns the tenant's keys by name, revoked keys only when asked for
+func (s *Service) ListAPIKeys(tenantID uuid.UUID, includeRevoked bool) ([]APIKey, error) {
+	var keys []APIKey
+	query := s.db.Where("tenant_id = ?", tenantID)
+	if !includeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+	if err := query.Order("name, created_at").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		if err := s.loadKeyClassrooms(&keys[i]); err != nil {
+			return nil, err
+		}
+	}
+	return keys, nil
+}
+
+func (s *Service) GetAPIKey(tenantID, apiKeyID uuid.UUID) (*APIKey, error) {
+	var key APIKey
+	err := s.db.Where("api_key_id = ? AND tenant_id = ?", apiKeyID, tenantID).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.loadKeyClassrooms(&key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// RotateAPIKey replaces the secret of an active key; the old key stops working immediately
+func (s *Service) RotateAPIKey(tenantID, apiKeyID uuid.UUID) (*IssuedAPIKey, error) {
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+	result := s.db.Model(&APIKey{}).
+		Where("api_key_id = ? AND tenant_id = ? AND revoked_at IS NULL", apiKeyID, tenantID).
+		Updates(map[string]interface{}{
+			"prefix":     prefix,
+			"key_hash":   hashToken(key),
+			"rotated_at": gorm.Expr("NOW()"),
+			"updated_at": gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+	apiKey, err := s.GetAPIKey(tenantID, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedAPIKey{APIKey: apiKey, Key: key}, nil
+}
+
+// RevokeAPIKey permanently disables a key; revoking twice keeps the first revocation time
+func (s *Service) RevokeAPIKey(tenantID, apiKeyID uuid.UUID) (*APIKey, error) {
+	result := s.db.Model(&APIKey{}).
+		Where("api_key_id = ? AND tenant_id = ?", apiKeyID, tenantID).
+		Updates(map[string]interface{}{
+			"revoked_at": gorm.Expr("COALESCE(revoked_at, NOW())"),
+			"updated_at": gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAPIKeyNotFound
+	}
+	return s.GetAPIKey(tenantID, apiKeyID)
+}
+
+// VerifyAPIKey implements KeyVerifier. It records when the key was last used,
+// at most once per lastUsedResolution.
+func (s *Service) VerifyAPIKey(key string) (*APIKey, error) {
+	parts := strings.SplitN(key, "_", 3)
+	if len(parts) != 3 || parts[0] != apiKeyPrefix {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var apiKey APIKey
+	err := s.db.Where("prefix = ?", parts[1]).First(&apiKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(apiKey.KeyHash), []byte(hashToken(key))) != 1 || apiKey.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if err := s.loadKeyClassrooms(&apiKey); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		err := s.db.Model(&APIKey{}).Where("api_key_id = ?", apiKey.APIKeyID).Update("last_used_at", now).Error
+		if err != nil {
+			return nil, err
+		}
+		apiKey.LastUsedAt = &now
+	}
+	return &apiKey, nil
+}
+
+func (s *Service) loadKeyClassrooms(key *APIKey) error {
+	key.ClassroomIDs = []uuid.UUID{}
+	return s.db.Model(&APIKeyClassroom{}).
+		Where("api_key_id = ?", key.APIKeyID).
+		Order("classroom_id").
+		Pluck("classroom_id", &key.ClassroomIDs).Error
+}
+
+// checkClassrooms makes sure every classroom belongs to the tenant
+func checkClassrooms(tx *gorm.DB, tenantID uuid.UUID, classroomIDs []uuid.UUID) error {
+	if len(classroomIDs) == 0 {
+		return nil
+	}
+	var found []uuid.UUID
+	err := tx.Table("classrooms").
+		Where("tenant_id = ? AND classroom_id IN ?", tenantID, classroomIDs).
+		Pluck("classroom_id", &found).Error
+	if err != nil {
+		return err
+	}
+	known := make(map[uuid.UUID]bool, len(found))
+	for _, id := range found {
+		known[id] = true
+	}
+	for _, id := range classroomIDs {
+		if !known[id] {
+			return fmt.Errorf("%w: %s", ErrUnknownClassroom, id)
+		}
+	}
+	return nil
+}
+
+// generateAPIKey returns a key of the form ik_<prefix>_<secret> and its prefix
+func generateAPIKey() (string, string, error) {
+	prefix := make([]byte, 6)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", err
+	}
+	p := hex.EncodeToString(prefix)
+	return apiKeyPrefix + "_" + p + "_" + base64.RawURLEncoding.EncodeToString(secret), p, nil
+}
+
+func dedupeIDs(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := []uuid.UUID{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+)
+
+// Auditor appends entries to the audit log; implemented by audit.Service
+type Auditor interface {
+	Record(entry *models.AuditEntry)
+}
+
+// AuditEntry starts a successful audit entry for the action, attributed to the caller:
+// their tenant, user or device key, and client IP
+func AuditEntry(c *gin.Context, action string) *models.AuditEntry {
+	entry := &models.AuditEntry{
+		Action:   action,
+		Outcome:  models.AuditSuccess,
+		SourceIP: c.ClientIP(),
+	}
+	if tenantID := TenantID(c); tenantID != uuid.Nil {
+		entry.TenantID = &tenantID
+	}
+	if userID := UserID(c); userID != 0 {
+		entry.ActorUserID = &userID
+	}
+	if apiKeyID := APIKeyID(c); apiKeyID != uuid.Nil {
+		entry.ActorAPIKeyID = &apiKeyID
+	}
+	return entry
+}
+
+// APIKeyID returns the device key the caller authenticated with, or uuid.Nil for users
+func APIKeyID(c *gin.Context) uuid.UUID {
+	apiKeyID, _ := c.Get("apiKeyID")
+	id, _ := apiKeyID.(uuid.UUID)
+	return id
+}
+
+// loginEntry is an audit entry about authenticating as user, which is nil for unknown
+// emails. Failed attempts name the user as target only, as the actor is unknown.
+func loginEntry(c *gin.Context, action string, user *User, err error) *models.AuditEntry {
+	entry := AuditEntry(c, action)
+	if err != nil {
+		entry.Outcome = models.AuditFailure
+		entry.Details = models.JSONMap{"reason": failureReason(err)}
+	}
+	if user != nil {
+		entry.TenantID = &user.TenantID
+		entry.TargetType = "user"
+		entry.TargetID = fmt.Sprint(user.ID)
+		if err == nil {
+			entry.ActorUserID = &user.ID
+		}
+	}
+	return entry
+}
+
+// failureReason is the stable code recorded for a failed login. Error messages are not
+// recorded, as they may change and may carry more than the audit log should keep.
+func failureReason(err error) string {
+	var locked *LockedError
+	switch {
+	case errors.As(err, &locked):
+		return "locked_out"
+	case errors.Is(err, ErrInvalidCredentials):
+		return "invalid_credentials"
+	case errors.Is(err, ErrAccountDisabled):
+		return "disabled"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+)
+
+func TestLoginEntryRecordsReasonCode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cases := []struct {
+		err    error
+		reason string
+	}{
+		{ErrInvalidCredentials, "invalid_credentials"},
+		{&LockedError{RetryAfter: time.Minute}, "locked_out"},
+		{ErrAccountDisabled, "disabled"},
+		{fmt.Errorf("querying users: %w", errors.New("connection to 10.0.0.5 refused")), "error"},
+	}
+	for _, tc := range cases {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		entry := loginEntry(c, "auth.login", &User{ID: 7}, tc.err)
+		if entry.Outcome != models.AuditFailure || entry.Details["reason"] != tc.reason {
+			t.Errorf("%v: recorded %s with %v, want reason %s", tc.err, entry.Outcome, entry.Details, tc.reason)
+		}
+	}
+}
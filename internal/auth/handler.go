@@ -1,37 +1,77 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
 )
 
 type Handler struct {
-	service *Service
+	service     *Service
+	allowSignup bool
+	audit       Auditor
 }
 
-func NewHandler(s *Service) *Handler {
-	return &Handler{service: s}
+// NewHandler creates the auth handler; allowSignup enables POST /api/auth/signup.
+// Logins, user changes and API key changes are recorded with auditor.
+func NewHandler(s *Service, allowSignup bool, auditor Auditor) *Handler {
+	return &Handler{service: s, allowSignup: allowSignup, audit: auditor}
+}
+
+// record adds a successful action on the target to the audit log
+func (h *Handler) record(c *gin.Context, action, targetType string, targetID interface{}, details models.JSONMap) {
+	entry := AuditEntry(c, action)
+	entry.TargetType = targetType
+	entry.TargetID = fmt.Sprint(targetID)
+	entry.Details = details
+	h.audit.Record(entry)
 }
 
 type signupRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required"` // checked against the password policy
 	Role     string `json:"role" binding:"required,oneof=writer reader"`
+	Tenant   string `json:"tenant"` // only the default tenant is open to signup
 }
 
 func (h *Handler) SignUp(c *gin.Context) {
+	if !h.allowSignup {
+		c.JSON(http.StatusForbidden, gin.H{"error": "self-signup is disabled; ask an admin to create your account"})
+		return
+	}
 	var req signupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err := h.service.CreateUser(req.Name, req.Email, req.Password, req.Role)
+	if req.Tenant != "" && req.Tenant != "default" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "self-signup is only open to the default tenant; ask an admin of your tenant to create your account"})
+		return
+	}
+	user, err := h.service.SignUp(req.Name, req.Email, req.Password, req.Role)
+	if errors.Is(err, ErrWeakPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	entry := loginEntry(c, "auth.signup", user, nil)
+	entry.Details = models.JSONMap{"role": user.Role}
+	h.audit.Record(entry)
 	c.Status(http.StatusCreated)
 }
 
@@ -46,10 +86,410 @@ func (h *Handler) Login(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	token, err := h.service.Authenticate(req.Email, req.Password)
+	pair, user, err := h.service.Authenticate(req.Email, req.Password, c.ClientIP())
+	entry := loginEntry(c, "auth.login", user, err)
 	if err != nil {
+		entry.Details["email"] = req.Email
+	}
+	h.audit.Record(entry)
+
+	var locked *LockedError
+	switch {
+	case errors.As(err, &locked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidCredentials):
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, pair)
+	}
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ForgotPassword handles POST /api/auth/password/forgot. It answers the same whether or
+// not the email belongs to an account.
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req forgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := h.service.RequestPasswordReset(req.Email)
+	if errors.Is(err, ErrResetUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "password reset is not available; contact your administrator"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send password reset"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	if user != nil {
+		entry := loginEntry(c, "auth.password_reset_request", user, nil)
+		// the requester is whoever knows the email, not necessarily the user
+		entry.ActorUserID = nil
+		h.audit.Record(entry)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the email belongs to an account, a reset token is on its way"})
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// ResetPassword handles POST /api/auth/password/reset
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req resetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	user, err := h.service.ResetPassword(req.Token, req.Password)
+	if user != nil {
+		h.audit.Record(loginEntry(c, "auth.password_reset", user, nil))
+	}
+	switch {
+	case errors.Is(err, ErrWeakPassword), errors.Is(err, ErrInvalidResetToken):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.Status(http.StatusNoContent)
+	}
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh handles POST /api/auth/refresh; the presented refresh token is rotated
+func (h *Handler) Refresh(c *gin.Context) {
+	var req refreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	pair, err := h.service.Refresh(req.RefreshToken)
+	switch {
+	case errors.Is(err, ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidRefreshToken), errors.Is(err, ErrRefreshTokenReused):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusOK, pair)
+	}
+}
+
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout handles POST /api/auth/logout. The access token used for the call is revoked;
+// passing the refresh token also revokes every token issued from the same login.
+func (h *Handler) Logout(c *gin.Context) {
+	var req logoutRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	tokenID := c.GetString("tokenID")
+	if tokenID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "token has no id and cannot be revoked"})
+		return
+	}
+	err := h.service.Logout(UserID(c), tokenID, c.GetTime("tokenExpiresAt"), req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.audit.Record(AuditEntry(c, "auth.logout"))
+	c.Status(http.StatusNoContent)
+}
+
+// JWKS handles GET /.well-known/jwks.json, publishing the public keys of every signing
+// key so that other services can verify our tokens across key rotations
+func (h *Handler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.service.JWKS())
+}
+
+// Admin user management; every endpoint only sees users of the caller's tenant
+
+type createUserRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"` // checked against the password policy
+	Role     string `json:"role" binding:"required,oneof=admin writer reader"`
+}
+
+type roleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin writer reader"`
+}
+
+// ListUsers handles GET /api/admin/users
+func (h *Handler) ListUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	includeDisabled := c.Query("include_disabled") == "true"
+
+	data, err := h.service.ListUsers(TenantID(c), includeDisabled, repository.NewPaginationParams(page, pageSize))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// CreateUser handles POST /api/admin/users
+func (h *Handler) CreateUser(c *gin.Context) {
+	var req createUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.CreateTenantUser(TenantID(c), req.Name, req.Email, req.Password, req.Role)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, "user.create", "user", user.ID, models.JSONMap{"email": user.Email, "role": user.Role})
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// GetUser handles GET /api/admin/users/:user_id
+func (h *Handler) GetUser(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.GetUser(TenantID(c), userID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// SetRole handles PUT /api/admin/users/:user_id/role
+func (h *Handler) SetRole(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// an admin demoting themselves could leave the tenant without one
+	if userID == UserID(c) && req.Role != RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot change your own role"})
+		return
+	}
+
+	user, err := h.service.SetRole(TenantID(c), userID, req.Role)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, "user.role_change", "user", user.ID, models.JSONMap{"role": user.Role})
+
+	c.JSON(http.StatusOK, user)
+}
+
+// SetAccess handles PUT /api/admin/users/:user_id/access
+func (h *Handler) SetAccess(c *gin.Context) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var req Access
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.service.SetAccess(TenantID(c), userID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, "user.access_change", "user", user.ID, models.JSONMap{"access": user.Access()})
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DisableUser handles POST /api/admin/users/:user_id/disable
+func (h *Handler) DisableUser(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+// EnableUser handles POST /api/admin/users/:user_id/enable
+func (h *Handler) EnableUser(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *Handler) setDisabled(c *gin.Context, disabled bool) {
+	userID, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+	if disabled && userID == UserID(c) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot disable your own account"})
+		return
+	}
+
+	user, err := h.service.SetDisabled(TenantID(c), userID, disabled)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	action := "user.enable"
+	if disabled {
+		action = "user.disable"
+	}
+	h.record(c, action, "user", user.ID, nil)
+
+	c.JSON(http.StatusOK, user)
+}
+
+// Device API keys; like users, every endpoint only sees keys of the caller's tenant
+
+type apiKeyRequest struct {
+	Name         string      `json:"name" binding:"required"`
+	ClassroomIDs []uuid.UUID `json:"classroom_ids"` // omit for a tenant-wide key
+}
+
+// ListAPIKeys handles GET /api/admin/api-keys
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	includeRevoked := c.Query("include_revoked") == "true"
+
+	keys, err := h.service.ListAPIKeys(TenantID(c), includeRevoked)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// IssueAPIKey handles POST /api/admin/api-keys; the key is only shown in this response
+func (h *Handler) IssueAPIKey(c *gin.Context) {
+	var req apiKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.service.IssueAPIKey(TenantID(c), req.Name, req.ClassroomIDs, UserID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, "api_key.issue", "api_key", key.APIKeyID, models.JSONMap{"name": key.Name, "classroom_ids": key.ClassroomIDs})
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// GetAPIKey handles GET /api/admin/api-keys/:key_id
+func (h *Handler) GetAPIKey(c *gin.Context) {
+	keyID, ok := parseAPIKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.service.GetAPIKey(TenantID(c), keyID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// RotateAPIKey handles POST /api/admin/api-keys/:key_id/rotate
+func (h *Handler) RotateAPIKey(c *gin.Context) {
+	keyID, ok := parseAPIKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.service.RotateAPIKey(TenantID(c), keyID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, "api_key.rotate", "api_key", key.APIKeyID, nil)
+
+	c.JSON(http.StatusOK, key)
+}
+
+// RevokeAPIKey handles POST /api/admin/api-keys/:key_id/revoke
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	keyID, ok := parseAPIKeyID(c)
+	if !ok {
+		return
+	}
+
+	key, err := h.service.RevokeAPIKey(TenantID(c), keyID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	h.record(c, "api_key.revoke", "api_key", key.APIKeyID, nil)
+
+	c.JSON(http.StatusOK, key)
+}
+
+// parseAPIKeyID reads the key_id path parameter and writes a 400 when it is malformed
+func parseAPIKeyID(c *gin.Context) (uuid.UUID, bool) {
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid key_id"})
+		return uuid.Nil, false
+	}
+	return keyID, true
+}
+
+// parseUserID reads the user_id path parameter and writes a 400 when it is malformed
+func parseUserID(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 0)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// respondError maps service errors to HTTP status codes
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrAPIKeyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrInvalidAccess), errors.Is(err, ErrInvalidRole), errors.Is(err, ErrUnknownClassroom),
+		errors.Is(err, ErrWeakPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
@@ -1,29 +1,72 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// GenerateJWT creates a token with embedded scopes (e.g. "READ", "WRITE")
-func GenerateJWT(userID uint, secret string, scopes []string) (string, error) {
+// GenerateJWT creates a token for a user of a tenant with embedded scopes (e.g. "READ", "WRITE")
+// and reporting access, valid for ttl. Each token gets a unique ID (jti) so it can be revoked.
+func GenerateJWT(userID uint, tenantID uuid.UUID, access Access, keys *KeySet, scopes []string, ttl time.Duration) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Scopes: scopes,
+		UserID:   userID,
+		TenantID: tenantID,
+		Access:   access,
+		Scopes:   scopes,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
 			Subject:   fmt.Sprint(userID),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return keys.Sign(claims)
 }
 
-// Claims extends standard JWT claims with user ID and scopes
+// Claims extends standard JWT claims with user ID, tenant, reporting access and scopes
 type Claims struct {
-	UserID uint     `json:"user_id"`
-	Scopes []string `json:"scopes"`
+	UserID   uint      `json:"user_id"`
+	TenantID uuid.UUID `json:"tenant_id"`
+	Access   Access    `json:"access"`
+	Scopes   []string  `json:"scopes"`
 	jwt.RegisteredClaims
 }
+
+// Verifier checks bearer tokens issued by this service and, when configured, by an
+// external OpenID Connect provider, telling them apart by their issuer
+type Verifier struct {
+	keys *KeySet
+	oidc *OIDCProvider
+}
+
+// NewVerifier creates a verifier for the service's own tokens; oidc may be nil
+func NewVerifier(keys *KeySet, oidc *OIDCProvider) *Verifier {
+	return &Verifier{keys: keys, oidc: oidc}
+}
+
+// VerifyToken implements TokenVerifier
+func (v *Verifier) VerifyToken(tokenString string) (*Claims, error) {
+	if v.oidc != nil {
+		// the issuer only picks the keys to check against; it is verified with them
+		unverified := jwt.MapClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(tokenString, unverified); err != nil {
+			return nil, err
+		}
+		if issuer, _ := unverified.GetIssuer(); issuer == v.oidc.issuer {
+			return v.oidc.Verify(tokenString)
+		}
+	}
+
+	claims, err := v.keys.Verify(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	// tokens without an ID cannot be revoked and must be reissued
+	if claims.ID == "" {
+		return nil, errors.New("token has no id")
+	}
+	return claims, nil
+}
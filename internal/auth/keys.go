@@ -0,0 +1,200 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrUnknownKey is returned for tokens signed by a key that is not in the key set
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// signingKey is one key of a KeySet; its algorithm is fixed by the key type
+type signingKey struct {
+	kid     string
+	method  jwt.SigningMethod
+	private crypto.PrivateKey
+	public  crypto.PublicKey
+}
+
+// KeySet signs and verifies the tokens this service issues. It holds either an HS256
+// secret or RS256/ES256 key pairs, one of which signs while all of them verify, so keys
+// can be rotated without invalidating tokens already handed out.
+type KeySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+// NewKeySet loads the key pairs in keysDir, one PEM private key per <kid>.pem file, and
+// signs with signingKID (which may be left empty when there is a single key). Without
+// keysDir, tokens are signed with the HS256 secret.
+func NewKeySet(secret, keysDir, signingKID string) (*KeySet, error) {
+	if keysDir == "" {
+		if secret == "" {
+			return nil, errors.New("either a JWT secret or a key directory is required")
+		}
+		key := &signingKey{method: jwt.SigningMethodHS256, private: []byte(secret), public: []byte(secret)}
+		return &KeySet{active: key, keys: map[string]*signingKey{"": key}}, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(keysDir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	set := &KeySet{keys: make(map[string]*signingKey)}
+	for _, path := range paths {
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadSigningKey(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		set.keys[kid] = key
+	}
+	if len(set.keys) == 0 {
+		return nil, fmt.Errorf("no *.pem keys found in %s", keysDir)
+	}
+	if signingKID == "" && len(set.keys) == 1 {
+		for _, key := range set.keys {
+			set.active = key
+		}
+	} else if set.active = set.keys[signingKID]; set.active == nil {
+		return nil, fmt.Errorf("signing key %q not found in %s", signingKID, keysDir)
+	}
+	return set, nil
+}
+
+// Sign signs the claims with the active key, naming it in the kid header
+func (k *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	if k.active.kid != "" {
+		token.Header["kid"] = k.active.kid
+	}
+	return token.SignedString(k.active.private)
+}
+
+// Verify parses a token issued by this service. The algorithm is pinned to the one of
+// the key named in the kid header, so a token cannot choose how it is checked.
+func (k *KeySet) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.public, nil
+	}, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set by kid; it is empty for an HS256 secret
+func (k *KeySet) JWKS() JWKS {
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range k.keys {
+		jwk := JWK{Kid: key.kid, Use: "sig", Alg: key.method.Alg()}
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeBigInt(public.N)
+			jwk.E = encodeBigInt(big.NewInt(int64(public.E)))
+		case *ecdsa.PublicKey:
+			jwk.Kty = "EC"
+			jwk.Crv = public.Curve.Params().Name
+			// coordinates are padded to the curve size, as RFC 7518 requires
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.X = base64.RawURLEncoding.EncodeToString(public.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(public.Y.FillBytes(make([]byte, size)))
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
+
+// loadSigningKey reads an RSA (2048 bits or more) or P-256 private key in PKCS#1, SEC 1 or PKCS#8 form
+func loadSigningKey(kid, path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	var private crypto.PrivateKey
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		private, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		private, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		private, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	switch key := private.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("%s: RSA keys must have at least 2048 bits", path)
+		}
+		return &signingKey{kid: kid, method: jwt.SigningMethodRS256, private: key, public: &key.PublicKey}, nil
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("%s: EC keys must use the P-256 curve", path)
+		}
+		return &signingKey{kid: kid, method: jwt.SigningMethodES256, private: key, public: &key.PublicKey}, nil
+	default:
+		return nil, fmt.Errorf("%s: only RSA and EC keys are supported", path)
+	}
+}
+
+func encodeBigInt(n *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(n.Bytes())
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeECKey writes a new P-256 key to dir as <kid>.pem and returns it
+func writeECKey(t *testing.T, dir, kid string) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func testClaims() *Claims {
+	return &Claims{
+		UserID: 1,
+		Access: Access{Level: AccessTenant},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}
+}
+
+// sign signs the claims with the method and key, naming kid in the header when it is set
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestKeySetVerifyPinsAlgorithm(t *testing.T) {
+	dir := t.TempDir()
+	key := writeECKey(t, dir, "2026-01")
+	keys, err := NewKeySet("", dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := writeECKey(t, t.TempDir(), "other")
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	secretKeys, err := NewKeySet("test-secret", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signed, err := keys.Sign(testClaims())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims, err := keys.Verify(signed); err != nil || claims.UserID != 1 {
+		t.Fatalf("a token of the set: %+v, %v", claims, err)
+	}
+
+	noExpiry := testClaims()
+	noExpiry.ExpiresAt = nil
+	cases := []struct {
+		name  string
+		keys  *KeySet
+		token string
+	}{
+		{"alg none", keys, sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "2026-01", testClaims())},
+		{"HS256 with the public key as secret", keys, sign(t, jwt.SigningMethodHS256, publicPEM, "2026-01", testClaims())},
+		{"ES384 under an ES256 kid", keys, sign(t, jwt.SigningMethodES384, p384, "2026-01", testClaims())},
+		{"unknown kid", keys, sign(t, jwt.SigningMethodES256, other, "other", testClaims())},
+		{"no kid", keys, sign(t, jwt.SigningMethodES256, key, "", testClaims())},
+		{"wrong key under a known kid", keys, sign(t, jwt.SigningMethodES256, other, "2026-01", testClaims())},
+		{"no expiry", keys, sign(t, jwt.SigningMethodES256, key, "2026-01", noExpiry)},
+		{"ES256 against a secret", secretKeys, sign(t, jwt.SigningMethodES256, key, "", testClaims())},
+		{"HS256 with another secret", secretKeys, sign(t, jwt.SigningMethodHS256, []byte("other-secret"), "", testClaims())},
+	}
+	for _, tc := range cases {
+		if _, err := tc.keys.Verify(tc.token); err == nil {
+			t.Errorf("%s: the token was accepted", tc.name)
+		}
+	}
+	if _, err := keys.Verify(sign(t, jwt.SigningMethodES256, other, "other", testClaims())); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("unknown kid: got %v, want ErrUnknownKey", err)
+	}
+}
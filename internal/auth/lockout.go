@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LoginAttempt counts recent failed logins of an account or client IP -
+// matches 000022_password_security.up.sql
+type LoginAttempt struct {
+	AttemptKey   string     `gorm:"primary_key"` // "account:<email>" or "ip:<address>"
+	Failures     int        `gorm:"not null"`
+	LastFailedAt time.Time  `gorm:"not null"`
+	LockedUntil  *time.Time // logins are refused until then
+}
+
+func (LoginAttempt) TableName() string {
+	return "login_attempts"
+}
+
+// LockedError is returned while an account or client IP is locked out after failed logins
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, try again in %s", e.RetryAfter.Round(time.Second))
+}
+
+func accountKey(email string) string {
+	return "account:" + normalizeEmail(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// checkLockout returns a LockedError when any of the keys is locked out
+func (s *Service) checkLockout(keys ...string) error {
+	var attempts []LoginAttempt
+	err := s.db.Where("attempt_key IN ? AND locked_until > ?", keys, time.Now()).Find(&attempts).Error
+	if err != nil {
+		return err
+	}
+	var wait time.Duration
+	for _, attempt := range attempts {
+		if d := time.Until(*attempt.LockedUntil); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return &LockedError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// recordFailure counts a failed login against the key and locks it out once the
+// threshold is reached, doubling the lockout with every further failure
+func (s *Service) recordFailure(key string, threshold int) error {
+	now := time.Now()
+	attempt := LoginAttempt{AttemptKey: key, Failures: 1, LastFailedAt: now}
+	err := s.db.Clauses(
+		clause.OnConflict{
+			Columns: []clause.Column{{Name: "attempt_key"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				// failures are forgotten after a quiet period as long as the longest lockout
+				"failures": gorm.Expr("CASE WHEN login_attempts.last_failed_at < ? THEN 1 ELSE login_attempts.failures + 1 END",
+					now.Add(-s.lockout.MaxDelay)),
+				"last_failed_at": now,
+			}),
+		},
+		clause.Returning{Columns: []clause.Column{{Name: "failures"}}},
+	).Create(&attempt).Error
+	if err != nil {
+		return err
+	}
+	if attempt.Failures < threshold {
+		return nil
+	}
+
+	delay := s.lockout.BaseDelay
+	for i := threshold; i < attempt.Failures && delay < s.lockout.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > s.lockout.MaxDelay {
+		delay = s.lockout.MaxDelay
+	}
+	return s.db.Model(&LoginAttempt{}).Where("attempt_key = ?", key).Update("locked_until", now.Add(delay)).Error
+}
+
+// clearFailures forgets the failed logins of the key after a successful login
+func (s *Service) clearFailures(key string) error {
+	return s.db.Where("attempt_key = ?", key).Delete(&LoginAttempt{}).Error
+}
@@ -1,41 +1,103 @@
 package auth
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 )
 
-// AuthMiddleware verifies JWT and stores claims in context
-func AuthMiddleware(secret string) gin.HandlerFunc {
+// Denylist reports whether a token ID (jti) was revoked before the token expired
+type Denylist interface {
+	IsRevoked(tokenID string) (bool, error)
+}
+
+// KeyVerifier resolves a device API key, failing with ErrInvalidAPIKey for unknown or revoked keys
+type KeyVerifier interface {
+	VerifyAPIKey(key string) (*APIKey, error)
+}
+
+// TokenVerifier checks a bearer token and returns its claims
+type TokenVerifier interface {
+	VerifyToken(token string) (*Claims, error)
+}
+
+// AuthMiddleware verifies bearer tokens, rejects revoked tokens and stores claims in context.
+// When apiKeys is set, device API keys sent in the X-API-Key header are accepted as well.
+func AuthMiddleware(tokens TokenVerifier, denylist Denylist, apiKeys KeyVerifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader("X-API-Key"); apiKeys != nil && apiKey != "" {
+			authenticateAPIKey(c, apiKeys, apiKey)
+			return
+		}
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header missing or invalid"})
 			return
 		}
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-		if err != nil || !token.Valid {
+		claims, err := tokens.VerifyToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
-		claims, ok := token.Claims.(*Claims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		// tokens issued before tenancy carry no tenant and must be reissued
+		if claims.TenantID == uuid.Nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has no tenant"})
 			return
 		}
+		// tokens issued before access levels carry none and must be reissued
+		if claims.Access.Validate() != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has no valid access"})
+			return
+		}
+		// external tokens may come without an ID; only tokens with one can be revoked
+		if claims.ID != "" {
+			revoked, err := denylist.IsRevoked(claims.ID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to check token revocation"})
+				return
+			}
+			if revoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token has been revoked"})
+				return
+			}
+		}
 		// store claims in context for downstream handlers
 		c.Set("userID", claims.UserID)
+		c.Set("subject", claims.Subject)
+		c.Set("tenantID", claims.TenantID)
+		c.Set("access", claims.Access)
+		c.Set("tokenID", claims.ID)
+		c.Set("tokenExpiresAt", claims.ExpiresAt.Time)
 		c.Set("scopes", claims.Scopes)
 		c.Next()
 	}
 }
 
+// authenticateAPIKey stores the device key's tenant, scopes and classrooms in context
+func authenticateAPIKey(c *gin.Context, keys KeyVerifier, key string) {
+	apiKey, err := keys.VerifyAPIKey(key)
+	if errors.Is(err, ErrInvalidAPIKey) {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify api key"})
+		return
+	}
+	c.Set("apiKeyID", apiKey.APIKeyID)
+	c.Set("tenantID", apiKey.TenantID)
+	c.Set("access", Access{Level: AccessTenant})
+	c.Set("scopes", apiKeyScopes)
+	if len(apiKey.ClassroomIDs) > 0 {
+		c.Set("classroomIDs", apiKey.ClassroomIDs)
+	}
+	c.Next()
+}
+
 // RequireScope checks that the token has the required scope
 func RequireScope(scope string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -54,3 +116,39 @@ func RequireScope(scope string) gin.HandlerFunc {
 		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope"})
 	}
 }
+
+// TenantID returns the tenant of the authenticated caller, or uuid.Nil when there is none
+func TenantID(c *gin.Context) uuid.UUID {
+	tenantID, _ := c.Get("tenantID")
+	id, _ := tenantID.(uuid.UUID)
+	return id
+}
+
+// UserID returns the ID of the authenticated caller, or 0 when there is none
+func UserID(c *gin.Context) uint {
+	userID, _ := c.Get("userID")
+	id, _ := userID.(uint)
+	return id
+}
+
+// Caller identifies who is calling, for per-caller quotas and the ingested_by of events:
+// the device key, the user, the external provider's subject, or else the client IP
+func Caller(c *gin.Context) string {
+	if apiKeyID := APIKeyID(c); apiKeyID != uuid.Nil {
+		return "key:" + apiKeyID.String()
+	}
+	if userID := UserID(c); userID != 0 {
+		return fmt.Sprintf("user:%d", userID)
+	}
+	if subject := c.GetString("subject"); subject != "" {
+		return "sub:" + subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ClassroomIDs returns the classrooms a device key is limited to, or nil when the caller is not limited
+func ClassroomIDs(c *gin.Context) []uuid.UUID {
+	classroomIDs, _ := c.Get("classroomIDs")
+	ids, _ := classroomIDs.([]uuid.UUID)
+	return ids
+}
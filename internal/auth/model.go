@@ -1,25 +1,53 @@
 package auth
 
 import (
+	"strings"
 	"time"
 
-	"gorm.io/gorm"
+	"github.com/google/uuid"
 )
 
+// Roles
+const (
+	RoleAdmin  = "admin"  // manages users and their access
+	RoleWriter = "writer" // ingests events and edits content and rosters
+	RoleReader = "reader" // reads reports
+)
+
+// roleScopes are the token scopes granted to each role
+var roleScopes = map[string][]string{
+	RoleAdmin:  {"ADMIN", "WRITE", "READ"},
+	RoleWriter: {"WRITE"},
+	RoleReader: {"READ"},
+}
+
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"size:100;not null" json:"name"`
-	Email     string    `gorm:"size:100;uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	Role      string    `gorm:"size:20;not null;default:'writer'" json:"role"` // 'writer' or 'reader'
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;not null" json:"tenant_id"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	Email       string     `gorm:"size:100;uniqueIndex;not null" json:"email"`
+	Password    string     `gorm:"not null" json:"-"`
+	Role        string     `gorm:"size:20;not null;default:'writer'" json:"role"` // admin, writer or reader
+	AccessLevel string     `gorm:"not null;default:'tenant'" json:"access_level"` // see Access
+	SchoolID    *uuid.UUID `gorm:"type:uuid" json:"school_id,omitempty"`
+	TeacherID   *uuid.UUID `gorm:"type:uuid" json:"teacher_id,omitempty"`
+	StudentID   *uuid.UUID `gorm:"type:uuid" json:"student_id,omitempty"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Access returns the reporting access recorded on the user
+func (u *User) Access() Access {
+	return Access{Level: u.AccessLevel, SchoolID: u.SchoolID, TeacherID: u.TeacherID, StudentID: u.StudentID}
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
-func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(&User{})
+// normalizeEmail is the form emails are stored, looked up and locked out by, so that
+// Bob@example.com and bob@example.com are one account
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
@@ -0,0 +1,344 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+)
+
+const (
+	// jwksMaxAge is how long fetched provider keys are used before they are fetched again
+	jwksMaxAge = time.Hour
+	// jwksMinRefresh limits refetches triggered by tokens naming an unknown kid
+	jwksMinRefresh = time.Minute
+)
+
+// ErrNoAccessMapping is returned for provider tokens whose access claim has no mapped value
+var ErrNoAccessMapping = errors.New("token grants no access level")
+
+// accessRank orders the access levels from the most to the least restrictive
+var accessRank = map[string]int{AccessNone: 0, AccessStudent: 1, AccessTeacher: 2, AccessSchool: 3, AccessTenant: 4}
+
+// OIDCProvider verifies access tokens issued by an external OpenID Connect provider.
+// Its users have no local account: they act in one tenant with the access level and
+// scopes mapped from claims of their token.
+type OIDCProvider struct {
+	issuer       string
+	audience     string
+	algorithms   []string
+	scopeClaim   string
+	scopeMap     map[string][]string
+	accessClaim  string
+	accessMap    map[string]string
+	subjectClaim string
+	tenantID     uuid.UUID
+	keys         *jwksCache
+}
+
+// NewOIDCProvider creates a provider for the tenant and loads its signing keys
+func NewOIDCProvider(cfg config.OIDCConfig, tenantID uuid.UUID) (*OIDCProvider, error) {
+	p := &OIDCProvider{
+		issuer:       cfg.Issuer,
+		audience:     cfg.Audience,
+		algorithms:   cfg.Algorithms,
+		scopeClaim:   cfg.ScopeClaim,
+		scopeMap:     cfg.ScopeMap,
+		accessClaim:  cfg.AccessClaim,
+		accessMap:    cfg.AccessMap,
+		subjectClaim: cfg.SubjectClaim,
+		tenantID:     tenantID,
+		keys: &jwksCache{
+			url:    cfg.JWKSURL,
+			file:   cfg.JWKSFile,
+			client: &http.Client{Timeout: 10 * time.Second},
+		},
+	}
+	if err := p.keys.load(); err != nil {
+		return nil, fmt.Errorf("failed to load provider keys: %v", err)
+	}
+	return p, nil
+}
+
+// Verify checks the token's signature, issuer, audience and expiry. Only the configured
+// algorithms are accepted, and a key that names its algorithm is only used with it.
+func (p *OIDCProvider) Verify(tokenString string) (*Claims, error) {
+	mapClaims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, mapClaims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.keys.key(kid)
+		if err != nil {
+			return nil, err
+		}
+		if key.alg != "" && key.alg != token.Method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		return key.public, nil
+	},
+		jwt.WithValidMethods(p.algorithms),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.audience),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	access, err := p.mapAccess(mapClaims)
+	if err != nil {
+		return nil, err
+	}
+	claims := &Claims{
+		TenantID: p.tenantID,
+		Access:   access,
+		Scopes:   p.mapScopes(mapClaims[p.scopeClaim]),
+	}
+	claims.Issuer = p.issuer
+	claims.Subject, _ = mapClaims.GetSubject()
+	claims.ExpiresAt, _ = mapClaims.GetExpirationTime()
+	claims.ID, _ = mapClaims["jti"].(string)
+	return claims, nil
+}
+
+// mapAccess translates the provider's access claim into an access level. Without a
+// configured claim users get no access; a token whose claim has no mapped value is
+// rejected, and of several mapped values the most restrictive wins. School, teacher and
+// student access take their ID from the subject claim.
+func (p *OIDCProvider) mapAccess(mapClaims jwt.MapClaims) (Access, error) {
+	if p.accessClaim == "" {
+		return Access{Level: AccessNone}, nil
+	}
+	level := ""
+	for _, value := range claimValues(mapClaims[p.accessClaim]) {
+		mapped, ok := p.accessMap[value]
+		if ok && (level == "" || accessRank[mapped] < accessRank[level]) {
+			level = mapped
+		}
+	}
+	if level == "" {
+		return Access{}, ErrNoAccessMapping
+	}
+
+	access := Access{Level: level}
+	if level == AccessTenant || level == AccessNone {
+		return access, nil
+	}
+	subject, _ := mapClaims[p.subjectClaim].(string)
+	id, err := uuid.Parse(subject)
+	if err != nil {
+		return Access{}, fmt.Errorf("%s access requires a %s ID in the %s claim", level, level, p.subjectClaim)
+	}
+	switch level {
+	case AccessSchool:
+		access.SchoolID = &id
+	case AccessTeacher:
+		access.TeacherID = &id
+	case AccessStudent:
+		access.StudentID = &id
+	}
+	return access, nil
+}
+
+// claimValues reads a claim that is a space-separated string or a list of strings
+func claimValues(claim interface{}) []string {
+	var values []string
+	switch v := claim.(type) {
+	case string:
+		values = strings.Fields(v)
+	case []interface{}:
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// mapScopes translates the provider's scope claim into this service's scopes. Values
+// without a mapping are ignored.
+func (p *OIDCProvider) mapScopes(claim interface{}) []string {
+	seen := map[string]bool{}
+	scopes := []string{}
+	for _, value := range claimValues(claim) {
+		for _, scope := range p.scopeMap[value] {
+			if !seen[scope] {
+				seen[scope] = true
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+	return scopes
+}
+
+// providerKey is a verification key of the provider; alg is empty when the JWK leaves it open
+type providerKey struct {
+	alg    string
+	public crypto.PublicKey
+}
+
+// jwksCache holds the provider's keys, read from a file or fetched from a URL
+type jwksCache struct {
+	url    string
+	file   string
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]providerKey
+	fetchedAt time.Time
+}
+
+// key returns the key named kid, reloading the key set when it is stale or when an unknown
+// kid shows up, which is how providers announce rotated keys. Tokens without a kid are
+// accepted when the provider has a single key.
+func (c *jwksCache) key(kid string) (providerKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	age := time.Since(c.fetchedAt)
+	_, known := c.keys[kid]
+	if age > jwksMaxAge || (!known && kid != "" && age > jwksMinRefresh) {
+		if err := c.loadLocked(); err != nil {
+			// keep using the keys we have; the provider may be briefly unavailable
+			if c.keys == nil {
+				return providerKey{}, err
+			}
+		}
+	}
+
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, nil
+		}
+	}
+	key, ok := c.keys[kid]
+	if !ok {
+		return providerKey{}, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (c *jwksCache) load() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.loadLocked()
+}
+
+func (c *jwksCache) loadLocked() error {
+	c.fetchedAt = time.Now()
+
+	var data []byte
+	var err error
+	if c.file != "" {
+		data, err = os.ReadFile(c.file)
+	} else {
+		data, err = c.fetch()
+	}
+	if err != nil {
+		return err
+	}
+
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("invalid JWKS: %v", err)
+	}
+	keys := make(map[string]providerKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		public, err := jwk.publicKey()
+		if err != nil {
+			return fmt.Errorf("invalid JWK %q: %v", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = providerKey{alg: jwk.Alg, public: public}
+	}
+	if len(keys) == 0 {
+		return errors.New("JWKS has no signing keys")
+	}
+	c.keys = keys
+	return nil
+}
+
+func (c *jwksCache) fetch() ([]byte, error) {
+	resp, err := c.client.Get(c.url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching %s: %s", c.url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// publicKey decodes an RSA or EC JWK
+func (k JWK) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("RSA exponent is out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("EC point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
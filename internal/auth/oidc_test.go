@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+)
+
+const testIssuer = "https://login.example.com/"
+
+// newTestProvider returns a provider trusting a single ES256 key, written as a JWKS file
+func newTestProvider(t *testing.T, cfg config.OIDCConfig) (*OIDCProvider, *ecdsa.PrivateKey) {
+	t.Helper()
+	dir := t.TempDir()
+	key := writeECKey(t, dir, "provider")
+	keys, err := NewKeySet("", dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(keys.JWKS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Issuer = testIssuer
+	cfg.Audience = "ingestor"
+	cfg.JWKSFile = filepath.Join(dir, "jwks.json")
+	cfg.Algorithms = []string{"ES256"}
+	cfg.ScopeClaim = "scope"
+	if err := os.WriteFile(cfg.JWKSFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewOIDCProvider(cfg, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p, key
+}
+
+// providerClaims are the claims of a valid provider token, to be changed per case
+func providerClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":   testIssuer,
+		"aud":   "ingestor",
+		"sub":   "user-1",
+		"exp":   time.Now().Add(time.Minute).Unix(),
+		"scope": "ingestor.read",
+		"role":  "district-admins",
+	}
+}
+
+func TestOIDCVerifyRejects(t *testing.T) {
+	p, key := newTestProvider(t, config.OIDCConfig{
+		AccessClaim: "role",
+		AccessMap:   map[string]string{"district-admins": AccessTenant},
+	})
+	if claims, err := p.Verify(sign(t, jwt.SigningMethodES256, key, "provider", providerClaims())); err != nil || claims.TenantID != p.tenantID {
+		t.Fatalf("a valid token: %+v, %v", claims, err)
+	}
+
+	cases := []struct {
+		name   string
+		change func(jwt.MapClaims)
+	}{
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com/" }},
+		{"no issuer", func(c jwt.MapClaims) { delete(c, "iss") }},
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "another-service" }},
+		{"audience list without ours", func(c jwt.MapClaims) { c["aud"] = []string{"a", "b"} }},
+		{"no audience", func(c jwt.MapClaims) { delete(c, "aud") }},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }},
+	}
+	for _, tc := range cases {
+		claims := providerClaims()
+		tc.change(claims)
+		if _, err := p.Verify(sign(t, jwt.SigningMethodES256, key, "provider", claims)); err == nil {
+			t.Errorf("%s: the token was accepted", tc.name)
+		}
+	}
+
+	if _, err := p.Verify(sign(t, jwt.SigningMethodHS256, []byte("secret"), "provider", providerClaims())); err == nil {
+		t.Error("an HS256 token was accepted")
+	}
+}
+
+func TestOIDCVerifyMapsAccess(t *testing.T) {
+	teacherID := uuid.New()
+	p, key := newTestProvider(t, config.OIDCConfig{
+		ScopeMap:     map[string][]string{"ingestor.read": {"READ"}},
+		AccessClaim:  "role",
+		AccessMap:    map[string]string{"district-admins": AccessTenant, "teachers": AccessTeacher},
+		SubjectClaim: "staff_id",
+	})
+	verify := func(change func(jwt.MapClaims)) (*Claims, error) {
+		claims := providerClaims()
+		change(claims)
+		return p.Verify(sign(t, jwt.SigningMethodES256, key, "provider", claims))
+	}
+
+	claims, err := verify(func(jwt.MapClaims) {})
+	if err != nil || claims.Access.Level != AccessTenant || len(claims.Scopes) != 1 || claims.Scopes[0] != "READ" {
+		t.Errorf("district admin: %+v, %v", claims, err)
+	}
+
+	// of several values the most restrictive wins, taking its ID from the subject claim
+	claims, err = verify(func(c jwt.MapClaims) {
+		c["role"] = []interface{}{"district-admins", "teachers"}
+		c["staff_id"] = teacherID.String()
+	})
+	if err != nil || claims.Access.Level != AccessTeacher || claims.Access.TeacherID == nil || *claims.Access.TeacherID != teacherID {
+		t.Errorf("teacher: %+v, %v", claims, err)
+	}
+
+	if _, err := verify(func(c jwt.MapClaims) { c["role"] = "teachers" }); err == nil {
+		t.Error("teacher access without a subject ID was accepted")
+	}
+	if _, err := verify(func(c jwt.MapClaims) { c["role"] = "students" }); !errors.Is(err, ErrNoAccessMapping) {
+		t.Errorf("unmapped role: got %v, want ErrNoAccessMapping", err)
+	}
+	if _, err := verify(func(c jwt.MapClaims) { delete(c, "role") }); !errors.Is(err, ErrNoAccessMapping) {
+		t.Errorf("no role: got %v, want ErrNoAccessMapping", err)
+	}
+}
+
+// Without a configured access claim provider users are authenticated but granted nothing
+func TestOIDCVerifyDefaultsToNoAccess(t *testing.T) {
+	p, key := newTestProvider(t, config.OIDCConfig{})
+	claims, err := p.Verify(sign(t, jwt.SigningMethodES256, key, "provider", providerClaims()))
+	if err != nil || claims.Access.Level != AccessNone {
+		t.Errorf("got %+v, %v; want no access", claims, err)
+	}
+}
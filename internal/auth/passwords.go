@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxPasswordBytes is where bcrypt stops reading a password
+const maxPasswordBytes = 72
+
+// ErrWeakPassword is returned for passwords that do not meet the password policy
+var ErrWeakPassword = errors.New("password does not meet the password policy")
+
+// ErrInvalidResetToken is returned for unknown, used or expired password reset tokens
+var ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+
+// ErrResetUnavailable is returned when there is no notifier to deliver reset tokens
+var ErrResetUnavailable = errors.New("password reset is not configured")
+
+// PasswordReset is a single-use reset token - matches 000022_password_security.up.sql.
+// Only a hash of the token is stored.
+type PasswordReset struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key"`
+	UserID    uint       `gorm:"not null;index:idx_password_resets_user"`
+	TokenHash string     `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // set once the password was reset, or when a newer token was requested
+	CreatedAt time.Time
+}
+
+func (PasswordReset) TableName() string {
+	return "password_resets"
+}
+
+// Notifier delivers messages to users, e.g. by email
+type Notifier interface {
+	SendPasswordReset(user *User, token string, expiresAt time.Time) error
+}
+
+// FileNotifier writes messages as JSON lines to a file. Meant for local use, where no
+// mail server is around.
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+// SendPasswordReset implements Notifier
+func (n *FileNotifier) SendPasswordReset(user *User, token string, expiresAt time.Time) error {
+	line, err := json.Marshal(map[string]interface{}{
+		"type":       "password_reset",
+		"to":         user.Email,
+		"name":       user.Name,
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// checkPassword applies the password policy
+func checkPassword(policy config.PasswordConfig, password string) error {
+	if len(password) < policy.MinLength {
+		return fmt.Errorf("%w: use at least %d characters", ErrWeakPassword, policy.MinLength)
+	}
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("%w: use at most %d bytes", ErrWeakPassword, maxPasswordBytes)
+	}
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < policy.MinClasses {
+		return fmt.Errorf("%w: mix at least %d of lowercase letters, uppercase letters, digits and symbols", ErrWeakPassword, policy.MinClasses)
+	}
+	return nil
+}
+
+// hashPassword checks the password against the policy and hashes it
+func (s *Service) hashPassword(password string) (string, error) {
+	if err := checkPassword(s.passwords, password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// RequestPasswordReset sends a reset token to the user with the email, if there is an
+// active one, and returns that user. Earlier tokens of the user stop working. Unknown
+// emails are not an error, so the endpoint cannot be used to find out who has an account.
+// Without a notifier it fails with ErrResetUnavailable, whatever the email.
+func (s *Service) RequestPasswordReset(email string) (*User, error) {
+	if s.notifier == nil {
+		return nil, ErrResetUnavailable
+	}
+	var user User
+	err := s.db.Where("email = ? AND disabled_at IS NULL", normalizeEmail(email)).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+	reset := &PasswordReset{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(s.passwords.ResetTTL),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&PasswordReset{}).Where("user_id = ? AND used_at IS NULL", user.ID).Update("used_at", time.Now()).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, s.notifier.SendPasswordReset(&user, token, reset.ExpiresAt)
+}
+
+// ResetPassword sets a new password with a reset token. The token is used up, the
+// account's failed logins are forgotten and its refresh tokens are revoked.
+func (s *Service) ResetPassword(token, password string) (*User, error) {
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	var user User
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var reset PasswordReset
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(token)).First(&reset).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidResetToken
+		}
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if reset.UsedAt != nil || !reset.ExpiresAt.After(now) {
+			return ErrInvalidResetToken
+		}
+
+		if err := tx.Where("id = ?", reset.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		if user.DisabledAt != nil {
+			return ErrInvalidResetToken
+		}
+
+		if err := tx.Model(&reset).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		err = tx.Model(&User{}).Where("id = ?", user.ID).
+			Updates(map[string]interface{}{"password": hash, "updated_at": now}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", user.ID).Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("attempt_key = ?", accountKey(user.Email)).Delete(&LoginAttempt{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+)
+
+// Without a notifier the request fails before any account is looked up, so the answer
+// is the same for every email
+func TestPasswordResetWithoutNotifier(t *testing.T) {
+	s := NewService(nil, nil, time.Minute, time.Hour, config.Defaults().Auth.Passwords, config.Defaults().Auth.Lockout, nil)
+	if _, err := s.RequestPasswordReset("jo@example.com"); !errors.Is(err, ErrResetUnavailable) {
+		t.Fatalf("got %v, want ErrResetUnavailable", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/forgot", NewHandler(s, false, nil).ForgotPassword)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/forgot", strings.NewReader(`{"email":"jo@example.com"}`)))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("got %d, want 503", w.Code)
+	}
+}
+
+func TestFileNotifier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifications.jsonl")
+	notifier := NewFileNotifier(path)
+	user := &User{Name: "Jo", Email: "jo@example.com"}
+	for _, token := range []string{"first-token", "second-token"} {
+		if err := notifier.SendPasswordReset(user, token, time.Now().Add(time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("file mode %v, want 0600", info.Mode().Perm())
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(lines))
+	}
+	var message map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[1]), &message); err != nil {
+		t.Fatal(err)
+	}
+	if message["to"] != user.Email || message["token"] != "second-token" || message["type"] != "password_reset" {
+		t.Errorf("message %v", message)
+	}
+}
@@ -1,56 +1,266 @@
 package auth
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// ErrUnknownTenant is returned when signing up to a tenant slug that does not exist
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// ErrUserNotFound is returned when a user does not exist in the tenant
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrEmailTaken is returned when creating a user with an email that is already registered
+var ErrEmailTaken = errors.New("email is already registered")
+
+// ErrInvalidCredentials is returned for an unknown email or a wrong password
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// ErrAccountDisabled is returned when a disabled user logs in with valid credentials
+var ErrAccountDisabled = errors.New("account is disabled")
+
+// ErrInvalidRole is returned for a role other than admin, writer or reader
+var ErrInvalidRole = fmt.Errorf("role must be %s, %s or %s", RoleAdmin, RoleWriter, RoleReader)
+
 type Service struct {
-	db        *gorm.DB
-	jwtSecret string
+	db         *gorm.DB
+	keys       *KeySet
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	passwords  config.PasswordConfig
+	lockout    config.LockoutConfig
+	notifier   Notifier
+}
+
+// NewService creates the auth service; access tokens are signed with keys and live for
+// accessTTL, refresh tokens live for refreshTTL. Password reset tokens go out through
+// notifier; with a nil notifier password reset is unavailable.
+func NewService(db *gorm.DB, keys *KeySet, accessTTL, refreshTTL time.Duration,
+	passwords config.PasswordConfig, lockout config.LockoutConfig, notifier Notifier) *Service {
+	return &Service{
+		db:         db,
+		keys:       keys,
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		passwords:  passwords,
+		lockout:    lockout,
+		notifier:   notifier,
+	}
 }
 
-func NewService(db *gorm.DB, jwtSecret string) *Service {
-	// Ensure users table exists
-	AutoMigrate(db)
-	return &Service{db: db, jwtSecret: jwtSecret}
+// JWKS returns the public keys that verify the service's tokens
+func (s *Service) JWKS() JWKS {
+	return s.keys.JWKS()
 }
 
-func (s *Service) CreateUser(name, email, password, role string) error {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// CreateUser registers a user in the tenant identified by tenantSlug
+func (s *Service) CreateUser(name, email, password, role, tenantSlug string) (*User, error) {
+	var tenantID uuid.UUID
+	err := s.db.Raw("SELECT tenant_id FROM tenants WHERE slug = ?", tenantSlug).Row().Scan(&tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownTenant
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	user := &User{Name: name, Email: email, Password: string(hash), Role: role}
-	return s.db.Create(user).Error
+	return s.CreateTenantUser(tenantID, name, email, password, role)
 }
 
-// Authenticate verifies credentials and issues a WRITE-scoped JWT
-func (s *Service) Authenticate(email, password string) (string, error) {
-	var user User
-	err := s.db.Where("email = ?", email).First(&user).Error
+// CreateTenantUser registers a user with tenant-wide access in the tenant
+func (s *Service) CreateTenantUser(tenantID uuid.UUID, name, email, password, role string) (*User, error) {
+	return s.createUser(tenantID, name, email, password, role, AccessTenant)
+}
+
+// SignUp registers a self-signed-up user in the default tenant. The user has no access
+// until an admin grants some with SetAccess.
+func (s *Service) SignUp(name, email, password, role string) (*User, error) {
+	return s.createUser(models.DefaultTenantID, name, email, password, role, AccessNone)
+}
+
+func (s *Service) createUser(tenantID uuid.UUID, name, email, password, role, accessLevel string) (*User, error) {
+	if _, ok := roleScopes[role]; !ok {
+		return nil, ErrInvalidRole
+	}
+	hash, err := s.hashPassword(password)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", fmt.Errorf("invalid credentials")
+		return nil, err
+	}
+	user := &User{TenantID: tenantID, Name: name, Email: normalizeEmail(email), Password: hash, Role: role, AccessLevel: accessLevel}
+	if err := s.db.Create(user).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrEmailTaken
 		}
-		return "", err
-	}
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", fmt.Errorf("invalid credentials")
-	}
-	// Determine scopes by role
-	var scopes []string
-	switch user.Role {
-	case "writer":
-		scopes = []string{"WRITE"}
-	case "reader":
-		scopes = []string{"READ"}
-	default:
-		return "", fmt.Errorf("unrecognized role")
-	}
-	// Issue token with appropriate scopes
-	return GenerateJWT(user.ID, s.jwtSecret, scopes)
+		return nil, err
+	}
+	return user, nil
+}
+
+// Authenticate verifies credentials and issues an access token with the scopes of the
+// user's role, plus a refresh token starting a new rotation family. Repeated failures lock
+// out the account and the client IP for a while, see config.LockoutConfig.
+// The user is returned whenever the email belongs to an account, also on failure.
+func (s *Service) Authenticate(email, password, ip string) (*TokenPair, *User, error) {
+	email = normalizeEmail(email)
+	var found User
+	var user *User
+	err := s.db.Where("email = ?", email).First(&found).Error
+	if err == nil {
+		user = &found
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	account, client := accountKey(email), ipKey(ip)
+	if err := s.checkLockout(account, client); err != nil {
+		return nil, user, err
+	}
+	// unknown emails count as failures too, so that lockouts do not reveal which accounts exist
+	if user == nil || bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		if err := s.recordFailure(account, s.lockout.AccountThreshold); err != nil {
+			return nil, user, err
+		}
+		if err := s.recordFailure(client, s.lockout.IPThreshold); err != nil {
+			return nil, user, err
+		}
+		return nil, user, ErrInvalidCredentials
+	}
+	if err := s.clearFailures(account); err != nil {
+		return nil, user, err
+	}
+	// Only reveal that an account is disabled to someone who knows its password
+	if user.DisabledAt != nil {
+		return nil, user, ErrAccountDisabled
+	}
+	pair, err := s.issueTokens(s.db, user, uuid.New())
+	return pair, user, err
+}
+
+// SetAccess changes which classrooms and students a user of the tenant may report on.
+// It takes effect at the user's next token refresh.
+func (s *Service) SetAccess(tenantID uuid.UUID, userID uint, access Access) (*User, error) {
+	if err := access.Validate(); err != nil {
+		return nil, err
+	}
+	if subject := accessSubject(access); subject != nil {
+		var count int64
+		err := s.db.Table(subject.table).Where("tenant_id = ? AND "+subject.column+" = ?", tenantID, *subject.id).Count(&count).Error
+		if err != nil {
+			return nil, err
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: unknown %s %s", ErrInvalidAccess, access.Level, *subject.id)
+		}
+	}
+
+	return s.updateUser(tenantID, userID, map[string]interface{}{
+		"access_level": access.Level,
+		"school_id":    access.SchoolID,
+		"teacher_id":   access.TeacherID,
+		"student_id":   access.StudentID,
+	})
+}
+
+// ListUsers pages through the tenant's users by email
+func (s *Service) ListUsers(tenantID uuid.UUID, includeDisabled bool, pagination repository.PaginationParams) (*repository.PaginatedResponse[User], error) {
+	var users []User
+	var totalCount int64
+
+	query := s.db.Model(&User{}).Where("tenant_id = ?", tenantID)
+	if !includeDisabled {
+		query = query.Where("disabled_at IS NULL")
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Order("email").Limit(pagination.PageSize).Offset(pagination.Offset).Find(&users).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := repository.NewPaginatedResponse(users, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (s *Service) GetUser(tenantID uuid.UUID, userID uint) (*User, error) {
+	var user User
+	err := s.db.Where("id = ? AND tenant_id = ?", userID, tenantID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SetRole changes the user's role. It takes effect at the user's next token refresh.
+func (s *Service) SetRole(tenantID uuid.UUID, userID uint, role string) (*User, error) {
+	if _, ok := roleScopes[role]; !ok {
+		return nil, ErrInvalidRole
+	}
+	return s.updateUser(tenantID, userID, map[string]interface{}{"role": role})
+}
+
+// SetDisabled disables or re-enables the user's account. Disabled users cannot log in
+// and their refresh tokens are revoked; access tokens they hold stay valid until they expire.
+func (s *Service) SetDisabled(tenantID uuid.UUID, userID uint, disabled bool) (*User, error) {
+	if !disabled {
+		return s.updateUser(tenantID, userID, map[string]interface{}{"disabled_at": nil})
+	}
+	now := time.Now()
+	user, err := s.updateUser(tenantID, userID, map[string]interface{}{"disabled_at": gorm.Expr("COALESCE(disabled_at, ?)", now)})
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Model(&RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", userID).Update("revoked_at", now).Error
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// updateUser applies the updates to a user of the tenant and returns the updated user
+func (s *Service) updateUser(tenantID uuid.UUID, userID uint, updates map[string]interface{}) (*User, error) {
+	updates["updated_at"] = gorm.Expr("NOW()")
+	result := s.db.Model(&User{}).Where("id = ? AND tenant_id = ?", userID, tenantID).Updates(updates)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrUserNotFound
+	}
+	return s.GetUser(tenantID, userID)
+}
+
+type subjectRef struct {
+	table  string
+	column string
+	id     *uuid.UUID
+}
+
+// accessSubject names the row a restricted access level points at
+func accessSubject(access Access) *subjectRef {
+	switch access.Level {
+	case AccessSchool:
+		return &subjectRef{"schools", "school_id", access.SchoolID}
+	case AccessTeacher:
+		return &subjectRef{"teachers", "teacher_id", access.TeacherID}
+	case AccessStudent:
+		return &subjectRef{"students", "student_id", access.StudentID}
+	}
+	return nil
 }
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReused is returned when an already rotated refresh token is presented
+// again; the whole token family is revoked because one of its tokens has leaked
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected, please log in again")
+
+// RefreshToken is one link of a rotation chain - matches 000020_refresh_tokens.up.sql.
+// Only a hash of the token is stored; every login starts a new family.
+type RefreshToken struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key"`
+	FamilyID  uuid.UUID  `gorm:"type:uuid;not null;index:idx_refresh_tokens_family"`
+	UserID    uint       `gorm:"not null;index:idx_refresh_tokens_user"`
+	TokenHash string     `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `gorm:"not null"`
+	UsedAt    *time.Time // set when the token was rotated
+	RevokedAt *time.Time // set on logout, reuse or when the user is disabled
+	CreatedAt time.Time
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RevokedToken denylists an access token until it expires - matches 000020_refresh_tokens.up.sql
+type RevokedToken struct {
+	TokenID   string    `gorm:"primary_key"`
+	ExpiresAt time.Time `gorm:"not null;index:idx_revoked_tokens_expires_at"`
+}
+
+func (RevokedToken) TableName() string {
+	return "revoked_tokens"
+}
+
+// TokenPair is issued on login and on every refresh
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"` // seconds until the access token expires
+}
+
+// Refresh exchanges a refresh token for a new pair. The presented token is used up;
+// presenting it again revokes every token descended from the same login.
+func (s *Service) Refresh(refreshToken string) (*TokenPair, error) {
+	var pair *TokenPair
+	reused := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var stored RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(refreshToken)).First(&stored).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		switch {
+		case stored.RevokedAt != nil:
+			return ErrInvalidRefreshToken
+		case stored.UsedAt != nil:
+			reused = true
+			return revokeFamily(tx, stored.FamilyID, now)
+		case !stored.ExpiresAt.After(now):
+			return ErrInvalidRefreshToken
+		}
+
+		// role, access and disabling take effect here rather than at the next login
+		var user User
+		if err := tx.Where("id = ?", stored.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		if user.DisabledAt != nil {
+			return ErrAccountDisabled
+		}
+
+		if err := tx.Model(&stored).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		pair, err = s.issueTokens(tx, &user, stored.FamilyID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+	return pair, nil
+}
+
+// Logout revokes the caller's access token and, if given, the refresh token family it belongs to
+func (s *Service) Logout(userID uint, tokenID string, tokenExpiresAt time.Time, refreshToken string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&RevokedToken{TokenID: tokenID, ExpiresAt: tokenExpiresAt}).Error
+		if err != nil {
+			return err
+		}
+		if refreshToken != "" {
+			var stored RefreshToken
+			err := tx.Where("token_hash = ? AND user_id = ?", hashToken(refreshToken), userID).First(&stored).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if err == nil {
+				if err := revokeFamily(tx, stored.FamilyID, now); err != nil {
+					return err
+				}
+			}
+		}
+		return purgeExpiredTokens(tx, now)
+	})
+}
+
+// IsRevoked implements Denylist
+func (s *Service) IsRevoked(tokenID string) (bool, error) {
+	var count int64
+	err := s.db.Model(&RevokedToken{}).Where("token_id = ?", tokenID).Count(&count).Error
+	return count > 0, err
+}
+
+// issueTokens signs an access token and stores a new refresh token in the family
+func (s *Service) issueTokens(tx *gorm.DB, user *User, familyID uuid.UUID) (*TokenPair, error) {
+	scopes, ok := roleScopes[user.Role]
+	if !ok {
+		return nil, ErrInvalidRole
+	}
+	accessToken, err := GenerateJWT(user.ID, user.TenantID, user.Access(), s.keys, scopes, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(secret)
+	err = tx.Create(&RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  familyID,
+		UserID:    user.ID,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.refreshTTL),
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: int(s.accessTTL.Seconds())}, nil
+}
+
+func revokeFamily(tx *gorm.DB, familyID uuid.UUID, now time.Time) error {
+	return tx.Model(&RefreshToken{}).Where("family_id = ? AND revoked_at IS NULL", familyID).Update("revoked_at", now).Error
+}
+
+// purgeExpiredTokens drops denylist entries and refresh tokens that can no longer be used
+func purgeExpiredTokens(tx *gorm.DB, now time.Time) error {
+	if err := tx.Where("expires_at < ?", now).Delete(&RevokedToken{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("expires_at < ?", now).Delete(&RefreshToken{}).Error
+}
+
+// hashToken is the stored form of a refresh token; the tokens are random, so no salt is needed
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/testdb"
+)
+
+const testPassword = "Secure-Password-1"
+
+// newTokenService returns a service on the test database and a user of the default
+// tenant who can log in with testPassword
+func newTokenService(t *testing.T) (*Service, *User) {
+	t.Helper()
+	db := testdb.Open(t)
+	keys, err := NewKeySet("test-secret", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := config.Defaults().Auth
+	s := NewService(db, keys, time.Minute, time.Hour, defaults.Passwords, defaults.Lockout, nil)
+	user, err := s.CreateTenantUser(models.DefaultTenantID, "Token Test", uuid.NewString()+"@example.com", testPassword, RoleWriter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, user
+}
+
+func login(t *testing.T, s *Service, user *User) *TokenPair {
+	t.Helper()
+	pair, _, err := s.Authenticate(user.Email, testPassword, "192.0.2.1")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	return pair
+}
+
+func storedToken(t *testing.T, s *Service, refreshToken string) RefreshToken {
+	t.Helper()
+	var stored RefreshToken
+	if err := s.db.Where("token_hash = ?", hashToken(refreshToken)).First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	return stored
+}
+
+func TestRefreshRotates(t *testing.T) {
+	s, user := newTokenService(t)
+	first := login(t, s, user)
+
+	second, err := s.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("the refresh token was not rotated")
+	}
+	claims, err := s.keys.Verify(second.AccessToken)
+	if err != nil || claims.UserID != user.ID || claims.ID == "" {
+		t.Errorf("refreshed access token: %+v, %v", claims, err)
+	}
+
+	used, next := storedToken(t, s, first.RefreshToken), storedToken(t, s, second.RefreshToken)
+	if used.UsedAt == nil || next.UsedAt != nil || used.FamilyID != next.FamilyID {
+		t.Errorf("rotation stored %+v then %+v", used, next)
+	}
+
+	if _, err := s.Refresh(second.RefreshToken); err != nil {
+		t.Errorf("refreshing the rotated token: %v", err)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	s, user := newTokenService(t)
+	first := login(t, s, user)
+	otherLogin := login(t, s, user)
+
+	second, err := s.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, err := s.Refresh(first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reusing a rotated token: got %v, want ErrRefreshTokenReused", err)
+	}
+
+	// the thief's or the user's newer token is revoked with the rest of the family
+	if _, err := s.Refresh(second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refreshing after reuse: got %v, want ErrInvalidRefreshToken", err)
+	}
+	if stored := storedToken(t, s, second.RefreshToken); stored.RevokedAt == nil {
+		t.Error("the family was not revoked")
+	}
+	// other logins of the same user are another family
+	if _, err := s.Refresh(otherLogin.RefreshToken); err != nil {
+		t.Errorf("refreshing another login: %v", err)
+	}
+}
+
+func TestRefreshRejects(t *testing.T) {
+	s, user := newTokenService(t)
+
+	if _, err := s.Refresh("not-a-token"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("unknown token: got %v", err)
+	}
+
+	expired := login(t, s, user)
+	s.db.Model(&RefreshToken{}).Where("token_hash = ?", hashToken(expired.RefreshToken)).Update("expires_at", time.Now().Add(-time.Second))
+	if _, err := s.Refresh(expired.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("expired token: got %v", err)
+	}
+
+	disabled := login(t, s, user)
+	s.db.Model(&User{}).Where("id = ?", user.ID).Update("disabled_at", time.Now())
+	if _, err := s.Refresh(disabled.RefreshToken); !errors.Is(err, ErrAccountDisabled) {
+		t.Errorf("disabled user: got %v", err)
+	}
+}
+
+func TestLogoutDenylistsToken(t *testing.T) {
+	s, user := newTokenService(t)
+	pair := login(t, s, user)
+	claims, err := s.keys.Verify(pair.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", AuthMiddleware(NewVerifier(s.keys, nil), s, nil), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	call := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+pair.AccessToken)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if revoked, err := s.IsRevoked(claims.ID); err != nil || revoked {
+		t.Fatalf("before logout: revoked %v, %v", revoked, err)
+	}
+	if code := call(); code != http.StatusNoContent {
+		t.Fatalf("before logout: got %d", code)
+	}
+
+	if err := s.Logout(user.ID, claims.ID, claims.ExpiresAt.Time, pair.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := s.IsRevoked(claims.ID); err != nil || !revoked {
+		t.Errorf("after logout: revoked %v, %v", revoked, err)
+	}
+	if revoked, err := s.IsRevoked(uuid.NewString()); err != nil || revoked {
+		t.Errorf("another token: revoked %v, %v", revoked, err)
+	}
+	if code := call(); code != http.StatusUnauthorized {
+		t.Errorf("after logout: got %d, want 401", code)
+	}
+	if _, err := s.Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("refreshing after logout: got %v", err)
+	}
+}
@@ -0,0 +1,252 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(s *Service) *Handler {
+	return &Handler{service: s}
+}
+
+// tenantService returns the service scoped to the caller's tenant
+func (h *Handler) tenantService(c *gin.Context) *Service {
+	return h.service.ForTenant(auth.TenantID(c))
+}
+
+// Helper function to parse pagination parameters
+func parsePaginationParams(c *gin.Context) repository.PaginationParams {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	return repository.NewPaginationParams(page, pageSize)
+}
+
+// CreateQuiz handles POST /api/quizzes
+func (h *Handler) CreateQuiz(c *gin.Context) {
+	var req QuizInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quiz, err := h.tenantService(c).CreateQuiz(req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, quiz)
+}
+
+// ListQuizzes handles GET /api/quizzes
+func (h *Handler) ListQuizzes(c *gin.Context) {
+	includeArchived := c.Query("include_archived") == "true"
+	pagination := parsePaginationParams(c)
+
+	data, err := h.tenantService(c).ListQuizzes(includeArchived, pagination)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetQuiz handles GET /api/quizzes/:quiz_id
+func (h *Handler) GetQuiz(c *gin.Context) {
+	quizID, ok := parseIDParam(c, "quiz_id")
+	if !ok {
+		return
+	}
+
+	quiz, err := h.tenantService(c).GetQuiz(quizID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quiz)
+}
+
+// UpdateQuiz handles PUT /api/quizzes/:quiz_id
+func (h *Handler) UpdateQuiz(c *gin.Context) {
+	quizID, ok := parseIDParam(c, "quiz_id")
+	if !ok {
+		return
+	}
+
+	var req QuizInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quiz, err := h.tenantService(c).UpdateQuiz(quizID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, quiz)
+}
+
+// ArchiveQuiz handles DELETE /api/quizzes/:quiz_id
+func (h *Handler) ArchiveQuiz(c *gin.Context) {
+	quizID, ok := parseIDParam(c, "quiz_id")
+	if !ok {
+		return
+	}
+
+	if err := h.tenantService(c).ArchiveQuiz(quizID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListQuizQuestions handles GET /api/quizzes/:quiz_id/questions
+func (h *Handler) ListQuizQuestions(c *gin.Context) {
+	quizID, ok := parseIDParam(c, "quiz_id")
+	if !ok {
+		return
+	}
+
+	includeArchived := c.Query("include_archived") == "true"
+	pagination := parsePaginationParams(c)
+
+	data, err := h.tenantService(c).ListQuizQuestions(quizID, includeArchived, pagination)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// CreateQuestion handles POST /api/quizzes/:quiz_id/questions
+func (h *Handler) CreateQuestion(c *gin.Context) {
+	quizID, ok := parseIDParam(c, "quiz_id")
+	if !ok {
+		return
+	}
+
+	var req QuestionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	question, err := h.tenantService(c).CreateQuestion(quizID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, question)
+}
+
+// GetQuestion handles GET /api/questions/:question_id
+func (h *Handler) GetQuestion(c *gin.Context) {
+	questionID, ok := parseIDParam(c, "question_id")
+	if !ok {
+		return
+	}
+
+	question, err := h.tenantService(c).GetQuestion(questionID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+// UpdateQuestion handles PUT /api/questions/:question_id
+func (h *Handler) UpdateQuestion(c *gin.Context) {
+	questionID, ok := parseIDParam(c, "question_id")
+	if !ok {
+		return
+	}
+
+	var req QuestionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	question, err := h.tenantService(c).UpdateQuestion(questionID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+// ArchiveQuestion handles DELETE /api/questions/:question_id
+func (h *Handler) ArchiveQuestion(c *gin.Context) {
+	questionID, ok := parseIDParam(c, "question_id")
+	if !ok {
+		return
+	}
+
+	if err := h.tenantService(c).ArchiveQuestion(questionID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetQuestionVersions handles GET /api/questions/:question_id/versions
+func (h *Handler) GetQuestionVersions(c *gin.Context) {
+	questionID, ok := parseIDParam(c, "question_id")
+	if !ok {
+		return
+	}
+
+	versions, err := h.tenantService(c).GetQuestionVersions(questionID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"question_id": questionID,
+		"versions":    versions,
+	})
+}
+
+// parseIDParam reads a UUID path parameter and writes a 400 when it is malformed
+func parseIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + " format"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondError maps service errors to HTTP status codes
+func respondError(c *gin.Context, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,217 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/grading"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a quiz or question does not exist
+var ErrNotFound = errors.New("resource not found")
+
+type Service struct {
+	QuizRepo repository.QuizRepository
+}
+
+func NewService(quizRepo repository.QuizRepository) *Service {
+	return &Service{QuizRepo: quizRepo}
+}
+
+// ForTenant returns a copy of the service that manages the tenant's catalog
+func (s *Service) ForTenant(tenantID uuid.UUID) *Service {
+	return &Service{QuizRepo: s.QuizRepo.ForTenant(tenantID)}
+}
+
+// QuizInput carries editable quiz fields
+type QuizInput struct {
+	QuizID      *uuid.UUID `json:"quiz_id"`
+	Title       string     `json:"title" binding:"required"`
+	Description *string    `json:"description"`
+}
+
+// QuestionInput carries editable question content
+type QuestionInput struct {
+	QuestionID   *uuid.UUID             `json:"question_id"`
+	QuestionType string                 `json:"question_type"`
+	AnswerKey    json.RawMessage        `json:"answer_key"`
+	Text         *string                `json:"text"`
+	Options      models.QuestionOptions `json:"options"`
+	Tags         models.StringList      `json:"tags"`
+	Position     *int                   `json:"position"`
+	Points       *float64               `json:"points"`
+}
+
+func (s *Service) CreateQuiz(input QuizInput) (*models.Quiz, error) {
+	quiz := &models.Quiz{
+		QuizID:      uuid.New(),
+		Title:       input.Title,
+		Description: input.Description,
+	}
+	if input.QuizID != nil {
+		quiz.QuizID = *input.QuizID
+	}
+	if err := s.QuizRepo.CreateQuiz(quiz); err != nil {
+		return nil, err
+	}
+	return s.GetQuiz(quiz.QuizID)
+}
+
+func (s *Service) GetQuiz(quizID uuid.UUID) (*models.Quiz, error) {
+	quiz, err := s.QuizRepo.GetQuizByID(quizID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return quiz, nil
+}
+
+func (s *Service) ListQuizzes(includeArchived bool, pagination repository.PaginationParams) (*repository.PaginatedResponse[models.Quiz], error) {
+	return s.QuizRepo.ListQuizzes(includeArchived, pagination)
+}
+
+func (s *Service) UpdateQuiz(quizID uuid.UUID, input QuizInput) (*models.Quiz, error) {
+	quiz, err := s.GetQuiz(quizID)
+	if err != nil {
+		return nil, err
+	}
+	quiz.Title = input.Title
+	quiz.Description = input.Description
+	if err := s.QuizRepo.UpdateQuiz(quiz); err != nil {
+		return nil, err
+	}
+	return s.GetQuiz(quizID)
+}
+
+func (s *Service) ArchiveQuiz(quizID uuid.UUID) error {
+	return notFound(s.QuizRepo.ArchiveQuiz(quizID))
+}
+
+func (s *Service) ListQuizQuestions(quizID uuid.UUID, includeArchived bool, pagination repository.PaginationParams) (*repository.PaginatedResponse[models.Question], error) {
+	if _, err := s.GetQuiz(quizID); err != nil {
+		return nil, err
+	}
+	return s.QuizRepo.ListQuizQuestions(quizID, includeArchived, pagination)
+}
+
+func (s *Service) CreateQuestion(quizID uuid.UUID, input QuestionInput) (*models.Question, error) {
+	if _, err := s.GetQuiz(quizID); err != nil {
+		return nil, err
+	}
+
+	question := &models.Question{
+		QuestionID: uuid.New(),
+		QuizID:     quizID,
+	}
+	if input.QuestionID != nil {
+		question.QuestionID = *input.QuestionID
+	}
+	if err := applyQuestionInput(question, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.QuizRepo.CreateQuestion(question); err != nil {
+		return nil, err
+	}
+	return s.GetQuestion(question.QuestionID)
+}
+
+func (s *Service) GetQuestion(questionID uuid.UUID) (*models.Question, error) {
+	question, err := s.QuizRepo.GetQuestionByID(questionID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return question, nil
+}
+
+// UpdateQuestion replaces the question content and records it as a new version.
+// Answers already graded keep pointing at the version they were graded against.
+func (s *Service) UpdateQuestion(questionID uuid.UUID, input QuestionInput) (*models.Question, error) {
+	question, err := s.GetQuestion(questionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyQuestionInput(question, input); err != nil {
+		return nil, err
+	}
+	if err := s.QuizRepo.UpdateQuestion(question); err != nil {
+		return nil, notFound(err)
+	}
+	return s.GetQuestion(questionID)
+}
+
+func (s *Service) ArchiveQuestion(questionID uuid.UUID) error {
+	return notFound(s.QuizRepo.ArchiveQuestion(questionID))
+}
+
+func (s *Service) GetQuestionVersions(questionID uuid.UUID) ([]models.QuestionVersion, error) {
+	if _, err := s.GetQuestion(questionID); err != nil {
+		return nil, err
+	}
+	return s.QuizRepo.GetQuestionVersions(questionID)
+}
+
+// ValidationError marks input rejected by catalog rules
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+// applyQuestionInput copies content onto the question and validates the result
+func applyQuestionInput(question *models.Question, input QuestionInput) error {
+	question.QuestionType = input.QuestionType
+	if question.QuestionType == "" {
+		question.QuestionType = grading.TypeSingleChoice
+	}
+	question.AnswerKey = input.AnswerKey
+	question.Text = input.Text
+	question.Options = input.Options
+	question.Tags = input.Tags
+	question.Position = input.Position
+	question.Points = 1
+	if input.Points != nil {
+		question.Points = *input.Points
+	}
+
+	if err := grading.ValidateAnswerKey(question.QuestionType, question.AnswerKey); err != nil {
+		return &ValidationError{Reason: err.Error()}
+	}
+	if question.Points <= 0 {
+		return &ValidationError{Reason: "points must be greater than zero"}
+	}
+	if question.Position != nil && *question.Position < 0 {
+		return &ValidationError{Reason: "position cannot be negative"}
+	}
+
+	// keys are compared as graded, so "a" and " A" are the same option
+	seen := make(map[string]bool, len(question.Options))
+	for _, opt := range question.Options {
+		key := grading.OptionKey(opt.Key)
+		if key == "" {
+			return &ValidationError{Reason: "every option needs a key"}
+		}
+		if seen[key] {
+			return &ValidationError{Reason: fmt.Sprintf("duplicate option key %q", opt.Key)}
+		}
+		seen[key] = true
+	}
+	if err := grading.ValidateKeyOptions(question.QuestionType, question.AnswerKey, question.Options.Keys()); err != nil {
+		return &ValidationError{Reason: err.Error()}
+	}
+	return nil
+}
+
+// notFound translates missing-record errors into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
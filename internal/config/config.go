@@ -1,26 +1,431 @@
 package config
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Provisioning modes for events that reference unknown entities
+const (
+	ProvisionReject     = "reject"
+	ProvisionAutoCreate = "auto_create"
 )
 
+// Config is the configuration of every binary. It is read from the YAML file named by
+// CONFIG_FILE, if any, and then from environment variables, which take precedence.
 type Config struct {
-	DatabaseURL string
-	JWTSecret   string
+	HTTP         HTTPConfig         `yaml:"http"`
+	Database     DatabaseConfig     `yaml:"database"`
+	Kafka        KafkaConfig        `yaml:"kafka"`
+	Auth         AuthConfig         `yaml:"auth"`
+	Provisioning ProvisioningConfig `yaml:"provisioning"`
+	RateLimits   RateLimitConfig    `yaml:"rate_limits"`
+	Reports      ReportsConfig      `yaml:"reports"`
+	Tracing      TracingConfig      `yaml:"tracing"`
+	Logging      LoggingConfig      `yaml:"logging"`
+}
+
+// HTTPConfig bounds how long the HTTP server spends on a connection. On SIGTERM it stops
+// accepting connections and waits up to ShutdownTimeout for in-flight requests.
+type HTTPConfig struct {
+	Port string `yaml:"port"`
+	// HealthPort serves the probes and metrics of cmd/consumer, which has no other listener
+	HealthPort   string        `yaml:"health_port"`
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout"`
+	// ShutdownTimeout should stay below the orchestrator's grace period, e.g. 30s on Kubernetes
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout"`
+	// TrustedProxies lists the IPs and CIDRs of proxies whose X-Forwarded-For header is
+	// believed when taking the client IP; none are trusted by default
+	TrustedProxies []string `yaml:"trusted_proxies"`
+}
+
+// DatabaseConfig locates Postgres and sizes the connection pool of each process
+type DatabaseConfig struct {
+	URL             string        `yaml:"url"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"`
+}
+
+// Kafka acknowledgement levels, compression codecs and initial offsets
+const (
+	AcksNone   = "none"
+	AcksLeader = "leader"
+	AcksAll    = "all"
+
+	OffsetOldest = "oldest"
+	OffsetNewest = "newest"
+)
+
+var compressionCodecs = []string{"none", "gzip", "snappy", "lz4", "zstd"}
+
+// KafkaConfig selects Kafka mode, in which the server publishes events for cmd/consumer
+// to process instead of writing them itself
+type KafkaConfig struct {
+	Enabled  bool                `yaml:"enabled"`
+	Brokers  []string            `yaml:"brokers"`
+	Topic    string              `yaml:"topic"`
+	Producer KafkaProducerConfig `yaml:"producer"`
+	Consumer KafkaConsumerConfig `yaml:"consumer"`
+}
+
+// KafkaProducerConfig trades publish latency for throughput; messages are flushed after
+// FlushFrequency or once FlushMessages are buffered, whichever comes first
+type KafkaProducerConfig struct {
+	RequiredAcks   string        `yaml:"required_acks"`
+	MaxRetries     int           `yaml:"max_retries"`
+	FlushFrequency time.Duration `yaml:"flush_frequency"`
+	FlushMessages  int           `yaml:"flush_messages"`
+	Compression    string        `yaml:"compression"`
+}
+
+// KafkaConsumerConfig is the group cmd/consumer joins; Topics defaults to the producer's topic
+type KafkaConsumerConfig struct {
+	GroupID string   `yaml:"group_id"`
+	Topics  []string `yaml:"topics"`
+	// InitialOffset is where a group without committed offsets starts, oldest or newest
+	InitialOffset     string        `yaml:"initial_offset"`
+	SessionTimeout    time.Duration `yaml:"session_timeout"`
+	MaxProcessingTime time.Duration `yaml:"max_processing_time"`
+}
+
+// AuthConfig covers token signing, accounts and the external identity provider
+type AuthConfig struct {
+	// JWTSecret signs tokens with HS256 unless JWTKeysDir holds RS256/ES256 keys;
+	// JWTSigningKID picks the key that signs when the directory holds several
+	JWTSecret     string `yaml:"jwt_secret"`
+	JWTKeysDir    string `yaml:"jwt_keys_dir"`
+	JWTSigningKID string `yaml:"jwt_signing_kid"`
+	// OIDC trusts tokens of an external OpenID Connect provider when its Issuer is set
+	OIDC OIDCConfig `yaml:"oidc"`
+	// AllowSignup enables self-registration into the default tenant, without access until
+	// an admin grants some; when off, admins create accounts
+	AllowSignup bool `yaml:"allow_signup"`
+	// AccessTokenTTL and RefreshTokenTTL bound the lifetime of issued tokens
+	AccessTokenTTL  time.Duration  `yaml:"access_token_ttl"`
+	RefreshTokenTTL time.Duration  `yaml:"refresh_token_ttl"`
+	Passwords       PasswordConfig `yaml:"passwords"`
+	Lockout         LockoutConfig  `yaml:"lockout"`
+	// NotifierFile receives password reset messages as JSON lines; empty disables password reset
+	NotifierFile string `yaml:"notifier_file"`
+}
+
+// ReportsConfig bounds the size and cost of report responses
+type ReportsConfig struct {
+	DefaultPageSize int `yaml:"default_page_size"`
+	// MaxPageSize is at most repository.MaxPageSize, 1000
+	MaxPageSize int `yaml:"max_page_size"`
+	// QueryMaxRows caps the rows of a generic query, whatever limit it asks for
+	QueryMaxRows int           `yaml:"query_max_rows"`
+	QueryTimeout time.Duration `yaml:"query_timeout"`
+}
+
+// Trace exporters
+const (
+	TracingOff    = "none"
+	TracingOTLP   = "otlp"   // OTLP over HTTP to a collector, see OTEL_EXPORTER_OTLP_ENDPOINT
+	TracingStdout = "stdout" // pretty-printed spans on stdout, for local debugging
+)
+
+// TracingConfig selects where OpenTelemetry spans go and which share of new traces is kept
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// Log formats
+const (
+	LogJSON = "json"
+	LogText = "text" // key=value lines, easier to read locally
+)
+
+// LoggingConfig sets the level and format of the structured logs. RedactFields are
+// attribute and JSON keys whose values are replaced in log lines; by default they cover
+// event payloads and credentials, so that student answers do not reach the logs.
+type LoggingConfig struct {
+	Level        slog.Level `yaml:"level"`
+	Format       string     `yaml:"format"`
+	RedactFields []string   `yaml:"redact_fields"`
+}
+
+// RateLimitConfig holds the per-caller limit of each route group. A request counts
+// against every group it belongs to, so generic queries also count as reports.
+type RateLimitConfig struct {
+	// Auth covers the public login, signup, refresh and password endpoints, per client IP
+	Auth    RateLimit `yaml:"auth"`
+	Events  RateLimit `yaml:"events"`
+	Reports RateLimit `yaml:"reports"`
+	Query   RateLimit `yaml:"query"`
+	// Default covers every authenticated route but event ingestion, reports included
+	Default RateLimit `yaml:"default"`
+}
+
+// RateLimit is a token bucket of Requests tokens that refills completely every Per;
+// a zero RateLimit does not limit
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// minRateLimitPer is the shortest period a limit may refill over; shorter periods such
+// as 2/1ns are typos that would make the limit meaningless
+const minRateLimitPer = time.Millisecond
+
+// UnmarshalText reads a limit such as 600/m, 10/s or 1000/5m; "off" disables the limit
+func (l *RateLimit) UnmarshalText(text []byte) error {
+	value := string(text)
+	if value == "off" {
+		*l = RateLimit{}
+		return nil
+	}
+	requests, period, ok := strings.Cut(value, "/")
+	n, err := strconv.Atoi(requests)
+	if period != "" && !strings.ContainsAny(period[:1], "0123456789") {
+		period = "1" + period
+	}
+	per, perErr := time.ParseDuration(period)
+	if !ok || err != nil || n < 1 || perErr != nil || per <= 0 {
+		return fmt.Errorf("must look like 600/m, 10/s or 1000/5m, or be off, got %q", value)
+	}
+	if per < minRateLimitPer {
+		return fmt.Errorf("must refill over at least %s, got %q", minRateLimitPer, value)
+	}
+	*l = RateLimit{Requests: n, Per: per}
+	return nil
 }
 
-func Load() *Config {
-	db := os.Getenv("DATABASE_URL")
-	if db == "" {
-		log.Fatal("DATABASE_URL is required")
+// MarshalText writes the limit back in the form UnmarshalText reads, e.g. 600/m
+func (l RateLimit) MarshalText() ([]byte, error) {
+	if l.Requests == 0 {
+		return []byte("off"), nil
+	}
+	per := l.Per.String()
+	switch {
+	case l.Per%time.Hour == 0:
+		per = strconv.Itoa(int(l.Per/time.Hour)) + "h"
+	case l.Per%time.Minute == 0:
+		per = strconv.Itoa(int(l.Per/time.Minute)) + "m"
 	}
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET is required")
+	if len(per) == 2 && per[0] == '1' {
+		per = per[1:]
 	}
+	return []byte(strconv.Itoa(l.Requests) + "/" + per), nil
+}
+
+// PasswordConfig is the password policy and the lifetime of password reset tokens
+type PasswordConfig struct {
+	// MinLength is at most 72, as bcrypt ignores anything past 72 bytes
+	MinLength int `yaml:"min_length"`
+	// MinClasses is how many of lowercase, uppercase, digits and symbols a password mixes
+	MinClasses int           `yaml:"min_classes"`
+	ResetTTL   time.Duration `yaml:"reset_ttl"`
+}
+
+// LockoutConfig locks logins out after repeated failures, per account and per client IP.
+// Each failure past the threshold doubles the lockout, starting at BaseDelay and capped
+// at MaxDelay; failures are forgotten after MaxDelay without one.
+type LockoutConfig struct {
+	AccountThreshold int           `yaml:"account_threshold"`
+	IPThreshold      int           `yaml:"ip_threshold"`
+	BaseDelay        time.Duration `yaml:"base_delay"`
+	MaxDelay         time.Duration `yaml:"max_delay"`
+}
+
+// OIDCConfig describes an external OpenID Connect provider whose access tokens are accepted
+type OIDCConfig struct {
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// JWKSURL or JWKSFile locate the provider's signing keys
+	JWKSURL  string `yaml:"jwks_url"`
+	JWKSFile string `yaml:"jwks_file"`
+	// Algorithms pins the signing algorithms accepted from the provider
+	Algorithms []string `yaml:"algorithms"`
+	// ScopeClaim names the claim holding the provider's scopes, roles or groups, and
+	// ScopeMap maps each of its values to the scopes it grants here
+	ScopeClaim string              `yaml:"scope_claim"`
+	ScopeMap   map[string][]string `yaml:"scope_map"`
+	// AccessClaim names the claim whose value selects the access level through AccessMap.
+	// Tokens without a mapped value are rejected; without AccessClaim every user gets no
+	// access until the claim is configured.
+	AccessClaim string            `yaml:"access_claim"`
+	AccessMap   map[string]string `yaml:"access_map"`
+	// SubjectClaim names the claim holding the school, teacher or student ID of
+	// school, teacher and student access
+	SubjectClaim string `yaml:"subject_claim"`
+	// Tenant is the slug of the tenant the provider's users act in
+	Tenant string `yaml:"tenant"`
+}
+
+// Enabled reports whether an external provider is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
+}
+
+// oidcAccessLevels are the access levels a provider value may map to
+var oidcAccessLevels = map[string]bool{
+	"tenant": true, "school": true, "teacher": true, "student": true, "none": true,
+}
+
+// oidcAlgorithms are the asymmetric algorithms a provider may be pinned to
+var oidcAlgorithms = map[string]bool{
+	"RS256": true, "RS384": true, "RS512": true,
+	"PS256": true, "PS384": true, "PS512": true,
+	"ES256": true, "ES384": true, "ES512": true,
+}
+
+// ProvisioningConfig selects, per entity, whether unknown references are
+// auto-created as placeholders or rejected with 422
+type ProvisioningConfig struct {
+	Sessions  string `yaml:"sessions"`
+	Questions string `yaml:"questions"`
+	Students  string `yaml:"students"`
+	Teachers  string `yaml:"teachers"`
+}
+
+// Defaults returns the configuration used for anything neither the file nor the
+// environment sets
+func Defaults() *Config {
 	return &Config{
-		DatabaseURL: db,
-		JWTSecret:   secret,
+		HTTP: HTTPConfig{
+			Port:            "8080",
+			HealthPort:      "8081",
+			ReadTimeout:     30 * time.Second,
+			WriteTimeout:    2 * time.Minute,
+			IdleTimeout:     2 * time.Minute,
+			ShutdownTimeout: 25 * time.Second,
+		},
+		Database: DatabaseConfig{
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Kafka: KafkaConfig{
+			Brokers: []string{"localhost:9092"},
+			Topic:   "quiz-events",
+			Producer: KafkaProducerConfig{
+				RequiredAcks:   AcksAll,
+				MaxRetries:     5,
+				FlushFrequency: 100 * time.Millisecond,
+				FlushMessages:  100,
+				Compression:    "snappy",
+			},
+			Consumer: KafkaConsumerConfig{
+				GroupID:           "analytics-event-processors",
+				InitialOffset:     OffsetOldest,
+				SessionTimeout:    10 * time.Second,
+				MaxProcessingTime: time.Minute,
+			},
+		},
+		Auth: AuthConfig{
+			OIDC: OIDCConfig{
+				Algorithms: []string{"RS256"},
+				ScopeClaim: "scope",
+			},
+			AccessTokenTTL:  15 * time.Minute,
+			RefreshTokenTTL: 30 * 24 * time.Hour,
+			Passwords: PasswordConfig{
+				MinLength:  10,
+				MinClasses: 2,
+				ResetTTL:   time.Hour,
+			},
+			Lockout: LockoutConfig{
+				AccountThreshold: 5,
+				IPThreshold:      20,
+				BaseDelay:        30 * time.Second,
+				MaxDelay:         time.Hour,
+			},
+		},
+		Provisioning: ProvisioningConfig{
+			Sessions:  ProvisionReject,
+			Questions: ProvisionReject,
+			Students:  ProvisionReject,
+			Teachers:  ProvisionReject,
+		},
+		RateLimits: RateLimitConfig{
+			Auth:    RateLimit{Requests: 30, Per: time.Minute},
+			Events:  RateLimit{Requests: 600, Per: time.Minute},
+			Reports: RateLimit{Requests: 300, Per: time.Minute},
+			Query:   RateLimit{Requests: 30, Per: time.Minute},
+			Default: RateLimit{Requests: 600, Per: time.Minute},
+		},
+		Reports: ReportsConfig{
+			DefaultPageSize: 50,
+			MaxPageSize:     1000,
+			QueryMaxRows:    10000,
+			QueryTimeout:    30 * time.Second,
+		},
+		Tracing: TracingConfig{
+			Exporter:    TracingOff,
+			SampleRatio: 1,
+		},
+		Logging: LoggingConfig{
+			Level:        slog.LevelInfo,
+			Format:       LogJSON,
+			RedactFields: []string{"payload", "answer", "answer_payload", "password", "token", "refresh_token", "secret"},
+		},
+	}
+}
+
+// Load reads the file named by CONFIG_FILE over the defaults, applies the environment
+// and validates the result. The error lists every problem found, one per line.
+func Load() (*Config, error) {
+	return load((*Config).validate)
+}
+
+// LoadDatabase is Load for commands that only use the database, such as migrations: the
+// other sections are read but not validated, so they need no JWT secret or Kafka brokers
+func LoadDatabase() (*DatabaseConfig, error) {
+	cfg, err := load((*Config).validateDatabase)
+	if err != nil {
+		return nil, err
+	}
+	return &cfg.Database, nil
+}
+
+func load(validate func(*Config) []error) (*Config, error) {
+	cfg := Defaults()
+	var errs []error
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := cfg.readFile(path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	errs = append(errs, cfg.applyEnv()...)
+	if len(cfg.Kafka.Consumer.Topics) == 0 {
+		cfg.Kafka.Consumer.Topics = []string{cfg.Kafka.Topic}
+	}
+	errs = append(errs, validate(cfg)...)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// readFile decodes the YAML file over cfg; unknown keys are errors, so that typos do
+// not silently fall back to defaults
+func (c *Config) readFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("CONFIG_FILE: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %w", path, err)
 	}
+	return nil
 }
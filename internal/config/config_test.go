@@ -0,0 +1,135 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimitUnmarshalText(t *testing.T) {
+	cases := []struct {
+		text string
+		want RateLimit
+		ok   bool
+	}{
+		{"600/m", RateLimit{600, time.Minute}, true},
+		{"10/s", RateLimit{10, time.Second}, true},
+		{"1000/5m", RateLimit{1000, 5 * time.Minute}, true},
+		{"5/1ms", RateLimit{5, time.Millisecond}, true},
+		{"off", RateLimit{}, true},
+		{"2/1ns", RateLimit{}, false},
+		{"2/999us", RateLimit{}, false},
+		{"0/m", RateLimit{}, false},
+		{"10", RateLimit{}, false},
+		{"10/0s", RateLimit{}, false},
+		{"ten/m", RateLimit{}, false},
+	}
+	for _, tc := range cases {
+		var got RateLimit
+		err := got.UnmarshalText([]byte(tc.text))
+		if (err == nil) != tc.ok || got != tc.want {
+			t.Errorf("%q: got %+v, %v; want %+v, ok %v", tc.text, got, err, tc.want, tc.ok)
+		}
+	}
+}
+
+// clearEnv empties every variable Load reads, so that the test's environment decides
+func clearEnv(t *testing.T) {
+	t.Helper()
+	t.Setenv("CONFIG_FILE", "")
+	for _, s := range Defaults().settings() {
+		t.Setenv(s.env, "")
+	}
+}
+
+func TestLoadReportsEveryError(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DATABASE_URL", "postgres://db/ingestor")
+	t.Setenv("DB_MAX_OPEN_CONNS", "many")
+	t.Setenv("RATE_LIMIT_EVENTS", "2/1ns")
+	t.Setenv("KAFKA_REQUIRED_ACKS", "some")
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,proxy.internal")
+	t.Setenv("OIDC_ISSUER", "https://login.example.com/")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("an invalid configuration was loaded")
+	}
+	want := []string{
+		"DB_MAX_OPEN_CONNS: must be a whole number",
+		"RATE_LIMIT_EVENTS: must refill over at least 1ms",
+		"kafka.producer.required_acks (KAFKA_REQUIRED_ACKS): must be one of",
+		"auth.jwt_secret (JWT_SECRET): is required",
+		`http.trusted_proxies (TRUSTED_PROXIES): entries must be IPs or CIDRs, got "proxy.internal"`,
+		"auth.oidc.audience (OIDC_AUDIENCE): is required",
+		"auth.oidc.tenant (OIDC_TENANT): is required",
+		"auth.oidc.jwks_url (OIDC_JWKS_URL): exactly one of it and auth.oidc.jwks_file (OIDC_JWKS_FILE) is required",
+	}
+	lines := strings.Split(err.Error(), "\n")
+	if len(lines) != len(want) {
+		t.Errorf("got %d errors, want %d:\n%v", len(lines), len(want), err)
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("missing %q in:\n%v", w, err)
+		}
+	}
+}
+
+func TestLoadDatabaseOnlyValidatesTheDatabase(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("DATABASE_URL", "postgres://db/ingestor")
+	t.Setenv("KAFKA_REQUIRED_ACKS", "some")
+	if _, err := LoadDatabase(); err != nil {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestMaskedHidesSecrets(t *testing.T) {
+	cases := []struct {
+		url, want string
+	}{
+		{"postgres://app:s3cret@db:5432/ingestor?sslmode=disable", "postgres://app:********@db:5432/ingestor?sslmode=disable"},
+		{"postgres://app:p%40ss@db/ingestor", "postgres://app:********@db/ingestor"},
+		{"postgres://app@db/ingestor?password=s3cret&sslmode=disable", "postgres://app@db/ingestor?password=********&sslmode=disable"},
+		{"postgres://app@db/ingestor?sslmode=disable&password=s3cret", "postgres://app@db/ingestor?sslmode=disable&password=********"},
+		{"host=db user=app password=s3cret dbname=ingestor", "host=db user=app password=******** dbname=ingestor"},
+		{"host=db user=app password='s3 cret' dbname=ingestor", "host=db user=app password=******** dbname=ingestor"},
+		{"postgres://app@db/ingestor", "postgres://app@db/ingestor"},
+	}
+	for _, tc := range cases {
+		cfg := Defaults()
+		cfg.Database.URL = tc.url
+		cfg.Auth.JWTSecret = "jwt-s3cret"
+		masked := cfg.Masked()
+		if masked.Database.URL != tc.want {
+			t.Errorf("%s: got %s, want %s", tc.url, masked.Database.URL, tc.want)
+		}
+		if masked.Auth.JWTSecret != mask {
+			t.Errorf("JWT secret printed as %q", masked.Auth.JWTSecret)
+		}
+		if cfg.Database.URL != tc.url || cfg.Auth.JWTSecret != "jwt-s3cret" {
+			t.Error("Masked changed the configuration it copied")
+		}
+	}
+
+	if masked := Defaults().Masked(); masked.Auth.JWTSecret != "" {
+		t.Errorf("an unset secret was printed as %q", masked.Auth.JWTSecret)
+	}
+}
+
+func TestPrintMasksSecrets(t *testing.T) {
+	cfg := Defaults()
+	cfg.Database.URL = "postgres://app:s3cret@db/ingestor?password=s3cret"
+	cfg.Auth.JWTSecret = "jwt-s3cret"
+	var out strings.Builder
+	if err := cfg.Print(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cret") {
+		t.Errorf("a secret was printed:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "jwt_secret: '"+mask+"'") && !strings.Contains(out.String(), `jwt_secret: "`+mask+`"`) {
+		t.Errorf("the JWT secret is not shown masked:\n%s", out.String())
+	}
+}
@@ -0,0 +1,195 @@
+package config
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting binds a field to its path in the YAML file and the environment variable that
+// overrides it
+type setting struct {
+	path  string
+	env   string
+	value interface{}
+}
+
+// settings lists every field that can be set, in the order of the file
+func (c *Config) settings() []setting {
+	return []setting{
+		{"http.port", "PORT", &c.HTTP.Port},
+		{"http.health_port", "HEALTH_PORT", &c.HTTP.HealthPort},
+		{"http.read_timeout", "HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout},
+		{"http.write_timeout", "HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout},
+		{"http.idle_timeout", "HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout},
+		{"http.shutdown_timeout", "SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout},
+		{"http.trusted_proxies", "TRUSTED_PROXIES", &c.HTTP.TrustedProxies},
+
+		{"database.url", "DATABASE_URL", &c.Database.URL},
+		{"database.max_open_conns", "DB_MAX_OPEN_CONNS", &c.Database.MaxOpenConns},
+		{"database.max_idle_conns", "DB_MAX_IDLE_CONNS", &c.Database.MaxIdleConns},
+		{"database.conn_max_lifetime", "DB_CONN_MAX_LIFETIME", &c.Database.ConnMaxLifetime},
+		{"database.conn_max_idle_time", "DB_CONN_MAX_IDLE_TIME", &c.Database.ConnMaxIdleTime},
+
+		{"kafka.enabled", "KAFKA_ENABLED", &c.Kafka.Enabled},
+		{"kafka.brokers", "KAFKA_BROKERS", &c.Kafka.Brokers},
+		{"kafka.topic", "KAFKA_TOPIC", &c.Kafka.Topic},
+		{"kafka.producer.required_acks", "KAFKA_REQUIRED_ACKS", &c.Kafka.Producer.RequiredAcks},
+		{"kafka.producer.max_retries", "KAFKA_MAX_RETRIES", &c.Kafka.Producer.MaxRetries},
+		{"kafka.producer.flush_frequency", "KAFKA_FLUSH_FREQUENCY", &c.Kafka.Producer.FlushFrequency},
+		{"kafka.producer.flush_messages", "KAFKA_FLUSH_MESSAGES", &c.Kafka.Producer.FlushMessages},
+		{"kafka.producer.compression", "KAFKA_COMPRESSION", &c.Kafka.Producer.Compression},
+		{"kafka.consumer.group_id", "KAFKA_CONSUMER_GROUP", &c.Kafka.Consumer.GroupID},
+		{"kafka.consumer.topics", "KAFKA_CONSUMER_TOPICS", &c.Kafka.Consumer.Topics},
+		{"kafka.consumer.initial_offset", "KAFKA_INITIAL_OFFSET", &c.Kafka.Consumer.InitialOffset},
+		{"kafka.consumer.session_timeout", "KAFKA_SESSION_TIMEOUT", &c.Kafka.Consumer.SessionTimeout},
+		{"kafka.consumer.max_processing_time", "KAFKA_MAX_PROCESSING_TIME", &c.Kafka.Consumer.MaxProcessingTime},
+
+		{"auth.jwt_secret", "JWT_SECRET", &c.Auth.JWTSecret},
+		{"auth.jwt_keys_dir", "JWT_KEYS_DIR", &c.Auth.JWTKeysDir},
+		{"auth.jwt_signing_kid", "JWT_SIGNING_KID", &c.Auth.JWTSigningKID},
+		{"auth.oidc.issuer", "OIDC_ISSUER", &c.Auth.OIDC.Issuer},
+		{"auth.oidc.audience", "OIDC_AUDIENCE", &c.Auth.OIDC.Audience},
+		{"auth.oidc.jwks_url", "OIDC_JWKS_URL", &c.Auth.OIDC.JWKSURL},
+		{"auth.oidc.jwks_file", "OIDC_JWKS_FILE", &c.Auth.OIDC.JWKSFile},
+		{"auth.oidc.algorithms", "OIDC_ALGORITHMS", &c.Auth.OIDC.Algorithms},
+		{"auth.oidc.scope_claim", "OIDC_SCOPE_CLAIM", &c.Auth.OIDC.ScopeClaim},
+		{"auth.oidc.scope_map", "OIDC_SCOPE_MAP", &c.Auth.OIDC.ScopeMap},
+		{"auth.oidc.access_claim", "OIDC_ACCESS_CLAIM", &c.Auth.OIDC.AccessClaim},
+		{"auth.oidc.access_map", "OIDC_ACCESS_MAP", &c.Auth.OIDC.AccessMap},
+		{"auth.oidc.subject_claim", "OIDC_SUBJECT_CLAIM", &c.Auth.OIDC.SubjectClaim},
+		{"auth.oidc.tenant", "OIDC_TENANT", &c.Auth.OIDC.Tenant},
+		{"auth.allow_signup", "ALLOW_SIGNUP", &c.Auth.AllowSignup},
+		{"auth.access_token_ttl", "ACCESS_TOKEN_TTL", &c.Auth.AccessTokenTTL},
+		{"auth.refresh_token_ttl", "REFRESH_TOKEN_TTL", &c.Auth.RefreshTokenTTL},
+		{"auth.passwords.min_length", "PASSWORD_MIN_LENGTH", &c.Auth.Passwords.MinLength},
+		{"auth.passwords.min_classes", "PASSWORD_MIN_CLASSES", &c.Auth.Passwords.MinClasses},
+		{"auth.passwords.reset_ttl", "PASSWORD_RESET_TTL", &c.Auth.Passwords.ResetTTL},
+		{"auth.lockout.account_threshold", "LOGIN_LOCKOUT_ACCOUNT_THRESHOLD", &c.Auth.Lockout.AccountThreshold},
+		{"auth.lockout.ip_threshold", "LOGIN_LOCKOUT_IP_THRESHOLD", &c.Auth.Lockout.IPThreshold},
+		{"auth.lockout.base_delay", "LOGIN_LOCKOUT_BASE_DELAY", &c.Auth.Lockout.BaseDelay},
+		{"auth.lockout.max_delay", "LOGIN_LOCKOUT_MAX_DELAY", &c.Auth.Lockout.MaxDelay},
+		{"auth.notifier_file", "NOTIFIER_FILE", &c.Auth.NotifierFile},
+
+		{"provisioning.sessions", "PROVISION_UNKNOWN_SESSIONS", &c.Provisioning.Sessions},
+		{"provisioning.questions", "PROVISION_UNKNOWN_QUESTIONS", &c.Provisioning.Questions},
+		{"provisioning.students", "PROVISION_UNKNOWN_STUDENTS", &c.Provisioning.Students},
+		{"provisioning.teachers", "PROVISION_UNKNOWN_TEACHERS", &c.Provisioning.Teachers},
+
+		{"rate_limits.auth", "RATE_LIMIT_AUTH", &c.RateLimits.Auth},
+		{"rate_limits.events", "RATE_LIMIT_EVENTS", &c.RateLimits.Events},
+		{"rate_limits.reports", "RATE_LIMIT_REPORTS", &c.RateLimits.Reports},
+		{"rate_limits.query", "RATE_LIMIT_QUERY", &c.RateLimits.Query},
+		{"rate_limits.default", "RATE_LIMIT_DEFAULT", &c.RateLimits.Default},
+
+		{"reports.default_page_size", "REPORTS_DEFAULT_PAGE_SIZE", &c.Reports.DefaultPageSize},
+		{"reports.max_page_size", "REPORTS_MAX_PAGE_SIZE", &c.Reports.MaxPageSize},
+		{"reports.query_max_rows", "QUERY_MAX_ROWS", &c.Reports.QueryMaxRows},
+		{"reports.query_timeout", "QUERY_TIMEOUT", &c.Reports.QueryTimeout},
+
+		{"tracing.exporter", "TRACING_EXPORTER", &c.Tracing.Exporter},
+		{"tracing.sample_ratio", "TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio},
+
+		{"logging.level", "LOG_LEVEL", &c.Logging.Level},
+		{"logging.format", "LOG_FORMAT", &c.Logging.Format},
+		{"logging.redact_fields", "LOG_REDACT_FIELDS", &c.Logging.RedactFields},
+	}
+}
+
+// applyEnv overrides each field whose environment variable is set and not empty. Lists
+// are comma-separated; "none" empties a list.
+func (c *Config) applyEnv() []error {
+	var errs []error
+	for _, s := range c.settings() {
+		value := strings.TrimSpace(os.Getenv(s.env))
+		if value == "" {
+			continue
+		}
+		if err := parseValue(s.value, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+	}
+	return errs
+}
+
+func parseValue(dst interface{}, value string) error {
+	switch dst := dst.(type) {
+	case *string:
+		*dst = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("must be a whole number, got %q", value)
+		}
+		*dst = n
+	case *float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", value)
+		}
+		*dst = f
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", value)
+		}
+		*dst = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("must be a duration such as 15m or 720h, got %q", value)
+		}
+		*dst = d
+	case *[]string:
+		*dst = splitList(value)
+	case *map[string][]string:
+		// e.g. "ingestor.write=WRITE,ingestor.read=READ,district-admins=ADMIN"
+		scopes := map[string][]string{}
+		for _, pair := range splitList(value) {
+			external, scope, ok := strings.Cut(pair, "=")
+			external, scope = strings.TrimSpace(external), strings.TrimSpace(scope)
+			if !ok || external == "" {
+				return fmt.Errorf("entries must look like value=ADMIN|WRITE|READ, got %q", pair)
+			}
+			scopes[external] = append(scopes[external], scope)
+		}
+		*dst = scopes
+	case *map[string]string:
+		// e.g. "district-admins=tenant,principals=school"
+		levels := map[string]string{}
+		for _, pair := range splitList(value) {
+			external, level, ok := strings.Cut(pair, "=")
+			external, level = strings.TrimSpace(external), strings.TrimSpace(level)
+			if !ok || external == "" {
+				return fmt.Errorf("entries must look like value=level, got %q", pair)
+			}
+			levels[external] = level
+		}
+		*dst = levels
+	case *RateLimit:
+		return dst.UnmarshalText([]byte(value))
+	case *slog.Level:
+		if err := dst.UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("must be debug, info, warn or error, got %q", value)
+		}
+	default:
+		panic(fmt.Sprintf("config: no parser for %T", dst))
+	}
+	return nil
+}
+
+func splitList(value string) []string {
+	if value == "none" {
+		return nil
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
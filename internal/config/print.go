@@ -0,0 +1,51 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mask replaces secrets in printed configurations
+const mask = "********"
+
+// dsnPassword matches the password of a key=value connection string
+var dsnPassword = regexp.MustCompile(`(password=)('[^']*'|\S+)`)
+
+// queryPassword matches a password passed as a URL query parameter
+var queryPassword = regexp.MustCompile(`([?&]password=)[^&]*`)
+
+// Masked returns a copy of the configuration with its secrets masked: the JWT secret
+// and the database password
+func (c *Config) Masked() *Config {
+	masked := *c
+	if masked.Auth.JWTSecret != "" {
+		masked.Auth.JWTSecret = mask
+	}
+	masked.Database.URL = maskDatabaseURL(c.Database.URL)
+	return &masked
+}
+
+func maskDatabaseURL(dsn string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		// the mask is spliced in, as url.URL would escape it
+		if _, ok := u.User.Password(); ok {
+			dsn = strings.Replace(dsn, u.User.String()+"@", url.User(u.User.Username()).String()+":"+mask+"@", 1)
+		}
+		return queryPassword.ReplaceAllString(dsn, "${1}"+mask)
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}"+mask)
+}
+
+// Print writes the configuration as YAML, in the layout of the file, with secrets masked
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Masked()); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
@@ -0,0 +1,178 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxPageSize mirrors repository.MaxPageSize, which this package cannot import
+const maxPageSize = 1000
+
+// validator collects every problem with a configuration, naming fields by both their
+// file path and environment variable
+type validator struct {
+	names map[string]string
+	errs  []error
+}
+
+func (v *validator) fail(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", v.names[path], fmt.Sprintf(format, args...)))
+}
+
+func (v *validator) required(path, value string) {
+	if value == "" {
+		v.fail(path, "is required")
+	}
+}
+
+func (v *validator) between(path string, value, min, max int) {
+	if value < min || value > max {
+		v.fail(path, "must be between %d and %d, got %d", min, max, value)
+	}
+}
+
+func (v *validator) positive(path string, value time.Duration) {
+	if value <= 0 {
+		v.fail(path, "must be a positive duration such as 15m or 720h, got %s", value)
+	}
+}
+
+func (v *validator) oneOf(path, value string, allowed ...string) {
+	if !slices.Contains(allowed, value) {
+		v.fail(path, "must be one of %s, got %q", strings.Join(allowed, ", "), value)
+	}
+}
+
+func (c *Config) newValidator() *validator {
+	v := &validator{names: map[string]string{}}
+	for _, s := range c.settings() {
+		v.names[s.path] = fmt.Sprintf("%s (%s)", s.path, s.env)
+	}
+	return v
+}
+
+func (c *Config) validate() []error {
+	v := c.newValidator()
+
+	v.required("http.port", c.HTTP.Port)
+	v.required("http.health_port", c.HTTP.HealthPort)
+	v.positive("http.read_timeout", c.HTTP.ReadTimeout)
+	v.positive("http.write_timeout", c.HTTP.WriteTimeout)
+	v.positive("http.idle_timeout", c.HTTP.IdleTimeout)
+	v.positive("http.shutdown_timeout", c.HTTP.ShutdownTimeout)
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			v.fail("http.trusted_proxies", "entries must be IPs or CIDRs, got %q", proxy)
+		}
+	}
+
+	c.checkDatabase(v)
+
+	if len(c.Kafka.Brokers) == 0 {
+		v.fail("kafka.brokers", "must list at least one broker")
+	}
+	v.required("kafka.topic", c.Kafka.Topic)
+	v.oneOf("kafka.producer.required_acks", c.Kafka.Producer.RequiredAcks, AcksNone, AcksLeader, AcksAll)
+	v.between("kafka.producer.max_retries", c.Kafka.Producer.MaxRetries, 0, 100)
+	v.positive("kafka.producer.flush_frequency", c.Kafka.Producer.FlushFrequency)
+	v.between("kafka.producer.flush_messages", c.Kafka.Producer.FlushMessages, 1, 100000)
+	v.oneOf("kafka.producer.compression", c.Kafka.Producer.Compression, compressionCodecs...)
+	v.required("kafka.consumer.group_id", c.Kafka.Consumer.GroupID)
+	if len(c.Kafka.Consumer.Topics) == 0 {
+		v.fail("kafka.consumer.topics", "must list at least one topic")
+	}
+	v.oneOf("kafka.consumer.initial_offset", c.Kafka.Consumer.InitialOffset, OffsetOldest, OffsetNewest)
+	v.positive("kafka.consumer.session_timeout", c.Kafka.Consumer.SessionTimeout)
+	v.positive("kafka.consumer.max_processing_time", c.Kafka.Consumer.MaxProcessingTime)
+
+	if c.Auth.JWTSecret == "" && c.Auth.JWTKeysDir == "" {
+		v.fail("auth.jwt_secret", "is required unless %s is set", v.names["auth.jwt_keys_dir"])
+	}
+	c.validateOIDC(v)
+	v.positive("auth.access_token_ttl", c.Auth.AccessTokenTTL)
+	v.positive("auth.refresh_token_ttl", c.Auth.RefreshTokenTTL)
+	v.between("auth.passwords.min_length", c.Auth.Passwords.MinLength, 6, 72)
+	v.between("auth.passwords.min_classes", c.Auth.Passwords.MinClasses, 1, 4)
+	v.positive("auth.passwords.reset_ttl", c.Auth.Passwords.ResetTTL)
+	v.between("auth.lockout.account_threshold", c.Auth.Lockout.AccountThreshold, 1, 1000)
+	v.between("auth.lockout.ip_threshold", c.Auth.Lockout.IPThreshold, 1, 100000)
+	v.positive("auth.lockout.base_delay", c.Auth.Lockout.BaseDelay)
+	v.positive("auth.lockout.max_delay", c.Auth.Lockout.MaxDelay)
+
+	v.oneOf("provisioning.sessions", c.Provisioning.Sessions, ProvisionReject, ProvisionAutoCreate)
+	v.oneOf("provisioning.questions", c.Provisioning.Questions, ProvisionReject, ProvisionAutoCreate)
+	v.oneOf("provisioning.students", c.Provisioning.Students, ProvisionReject, ProvisionAutoCreate)
+	v.oneOf("provisioning.teachers", c.Provisioning.Teachers, ProvisionReject, ProvisionAutoCreate)
+
+	v.between("reports.max_page_size", c.Reports.MaxPageSize, 1, maxPageSize)
+	v.between("reports.default_page_size", c.Reports.DefaultPageSize, 1, c.Reports.MaxPageSize)
+	v.between("reports.query_max_rows", c.Reports.QueryMaxRows, 1, 1000000)
+	v.positive("reports.query_timeout", c.Reports.QueryTimeout)
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, TracingOff, TracingOTLP, TracingStdout)
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.fail("tracing.sample_ratio", "must be a number between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+
+	v.oneOf("logging.format", c.Logging.Format, LogJSON, LogText)
+	return v.errs
+}
+
+// validateDatabase only checks the database section
+func (c *Config) validateDatabase() []error {
+	v := c.newValidator()
+	c.checkDatabase(v)
+	return v.errs
+}
+
+func (c *Config) checkDatabase(v *validator) {
+	v.required("database.url", c.Database.URL)
+	v.between("database.max_open_conns", c.Database.MaxOpenConns, 1, 1000)
+	v.between("database.max_idle_conns", c.Database.MaxIdleConns, 0, c.Database.MaxOpenConns)
+	v.positive("database.conn_max_lifetime", c.Database.ConnMaxLifetime)
+	v.positive("database.conn_max_idle_time", c.Database.ConnMaxIdleTime)
+}
+
+func (c *Config) validateOIDC(v *validator) {
+	oidc := c.Auth.OIDC
+	if !oidc.Enabled() {
+		return
+	}
+	v.required("auth.oidc.audience", oidc.Audience)
+	v.required("auth.oidc.tenant", oidc.Tenant)
+	if (oidc.JWKSURL == "") == (oidc.JWKSFile == "") {
+		v.fail("auth.oidc.jwks_url", "exactly one of it and %s is required", v.names["auth.oidc.jwks_file"])
+	}
+	v.required("auth.oidc.scope_claim", oidc.ScopeClaim)
+	if len(oidc.Algorithms) == 0 {
+		v.fail("auth.oidc.algorithms", "must list at least one algorithm")
+	}
+	for _, alg := range oidc.Algorithms {
+		if !oidcAlgorithms[alg] {
+			v.fail("auth.oidc.algorithms", "may only list RS, PS and ES algorithms, got %q", alg)
+		}
+	}
+	for external, scopes := range oidc.ScopeMap {
+		for _, scope := range scopes {
+			if scope != "ADMIN" && scope != "WRITE" && scope != "READ" {
+				v.fail("auth.oidc.scope_map", "%q must map to ADMIN, WRITE or READ, got %q", external, scope)
+			}
+		}
+	}
+	if (oidc.AccessClaim == "") != (len(oidc.AccessMap) == 0) {
+		v.fail("auth.oidc.access_claim", "must be set together with %s", v.names["auth.oidc.access_map"])
+	}
+	needsSubject := false
+	for external, level := range oidc.AccessMap {
+		if !oidcAccessLevels[level] {
+			v.fail("auth.oidc.access_map", "%q must map to tenant, school, teacher, student or none, got %q", external, level)
+		}
+		needsSubject = needsSubject || level == "school" || level == "teacher" || level == "student"
+	}
+	if needsSubject {
+		v.required("auth.oidc.subject_claim", oidc.SubjectClaim)
+	}
+}
@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/migrate"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/migrations"
+	"gorm.io/gorm"
+)
+
+// Migrator runs the migrations compiled into the binary against db
+func Migrator(db *gorm.DB) (*migrate.Migrator, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(sqlDB, migrations.Postgres())
+}
+
+// CheckSchema fails unless the database is migrated at least as far as the models expect
+func CheckSchema(migrator *migrate.Migrator) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return migrator.Check(ctx, models.SchemaVersion)
+}
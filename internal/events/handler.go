@@ -1,38 +1,157 @@
 package events
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
 	"github.com/rohanreddymelachervu/ingestor/internal/kafka"
+	"github.com/rohanreddymelachervu/ingestor/internal/logging"
+	"github.com/rohanreddymelachervu/ingestor/internal/metrics"
 	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Handler struct {
 	service   *Service
 	kafkaMode bool
 	producer  *kafka.Producer
+	audit     auth.Auditor
 }
 
 // NewHandler creates a handler with direct database processing (default mode)
-func NewHandler(s *Service) *Handler {
+func NewHandler(s *Service, auditor auth.Auditor) *Handler {
 	return &Handler{
 		service:   s,
 		kafkaMode: false,
+		audit:     auditor,
 	}
 }
 
 // NewHandlerWithKafka creates a handler with Kafka producer for event publishing
-func NewHandlerWithKafka(s *Service, producer *kafka.Producer) *Handler {
+func NewHandlerWithKafka(s *Service, producer *kafka.Producer, auditor auth.Auditor) *Handler {
 	return &Handler{
 		service:   s,
 		kafkaMode: true,
 		producer:  producer,
+		audit:     auditor,
 	}
 }
 
+// recordIngestion adds an ingestion request to the audit log; it only failed when nothing was accepted
+func (h *Handler) recordIngestion(c *gin.Context, mode string, total, processed int, eventID string) {
+	entry := auth.AuditEntry(c, "events.ingest")
+	if processed == 0 && total > 0 {
+		entry.Outcome = models.AuditFailure
+	}
+	if eventID != "" {
+		entry.TargetType = "event"
+		entry.TargetID = eventID
+	}
+	entry.Details = models.JSONMap{
+		"mode":            mode,
+		"total_events":    total,
+		"processed_count": processed,
+		"failed_count":    total - processed,
+	}
+	h.audit.Record(entry)
+}
+
+// publishes reports whether events go to Kafka; without a producer they are processed directly
+func (h *Handler) publishes() bool {
+	return h.kafkaMode && h.producer != nil
+}
+
+// mode names how events are ingested, for responses and the audit log
+func (h *Handler) mode() string {
+	if h.publishes() {
+		return "kafka"
+	}
+	return "direct"
+}
+
+// tenantService returns the service scoped to the caller's tenant
+func (h *Handler) tenantService(c *gin.Context) *Service {
+	return h.service.ForTenant(auth.TenantID(c))
+}
+
+// eventTypes are the event types counted by name; others are counted as unknown to keep
+// the number of metric series bounded
+var eventTypes = map[string]bool{
+	"QUESTION_PUBLISHED": true, "ANSWER_SUBMITTED": true, "SESSION_STARTED": true,
+	"STUDENT_JOINED": true, "STUDENT_LEFT": true, "QUESTION_SKIPPED": true,
+}
+
+var tracer = otel.Tracer("github.com/rohanreddymelachervu/ingestor/internal/events")
+
+// ingest authorizes and records one event in a span of its own. In Kafka mode it is validated
+// and published, falling back to direct processing when publishing fails.
+func (h *Handler) ingest(ctx context.Context, service *Service, event models.EventPayload, ingestedBy string, classroomIDs []uuid.UUID) (err error) {
+	eventType := event.EventType
+	if !eventTypes[eventType] {
+		eventType = "unknown"
+	}
+	ctx, span := tracer.Start(ctx, "events.ingest", trace.WithAttributes(
+		attribute.String("event.id", event.EventID),
+		attribute.String("event.type", eventType),
+	))
+	ctx = logging.With(ctx,
+		slog.String("event_id", event.EventID),
+		slog.String("event_type", eventType),
+		slog.String("session_id", event.SessionID),
+	)
+	service = service.WithContext(ctx)
+
+	outcome := "processed"
+	defer func() {
+		switch {
+		case err == nil:
+			slog.DebugContext(ctx, "event ingested", "outcome", outcome)
+		case statusForError(err) == http.StatusInternalServerError:
+			outcome = metrics.ErrorClass(err)
+			slog.ErrorContext(ctx, "event ingestion failed", "outcome", outcome, "error", err)
+		default:
+			outcome = metrics.ErrorClass(err)
+			slog.InfoContext(ctx, "event rejected", "outcome", outcome, "error", err)
+		}
+		if err != nil {
+			span.SetStatus(codes.Error, outcome)
+		}
+		span.SetAttributes(attribute.String("event.outcome", outcome))
+		span.End()
+		metrics.EventsIngested.WithLabelValues(eventType, outcome).Inc()
+	}()
+
+	if classroomIDs != nil {
+		if err := service.AuthorizeEvent(event, classroomIDs); err != nil {
+			return err
+		}
+	}
+
+	if h.publishes() {
+		// Reject malformed payloads and unknown references before they reach the topic, as
+		// ProcessEvent would
+		if err := service.ValidateEvent(event); err != nil {
+			return err
+		}
+		err := h.producer.PublishEvent(ctx, service.TenantID, ingestedBy, event.EventID, event.EventType, event.SessionID, event)
+		if err == nil {
+			outcome = "queued"
+			return nil
+		}
+		slog.WarnContext(ctx, "failed to publish event to Kafka, processing it directly", "error", err)
+	}
+	return service.ProcessEvent(event, ingestedBy)
+}
+
 func (h *Handler) CreateEvent(c *gin.Context) {
 	var event models.EventPayload
 	if err := c.ShouldBindJSON(&event); err != nil {
@@ -40,42 +159,26 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 		return
 	}
 
-	userID, _ := c.Get("userID")
+	mode := h.mode()
+	processed := 0
+	defer func() { h.recordIngestion(c, mode, 1, processed, event.EventID) }()
 
-	if h.kafkaMode && h.producer != nil {
-		// Kafka mode: publish to topic
-		err := h.producer.PublishEvent(event.EventID, event.EventType, event.SessionID, event)
-		if err != nil {
-			log.Printf("Failed to publish to Kafka, falling back to direct processing: %v", err)
-			// Fallback to direct processing if Kafka fails
-			err = h.service.ProcessEvent(event, userID)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-				return
-			}
-		}
-
-		c.JSON(http.StatusCreated, gin.H{
-			"message":   "Event queued successfully",
-			"event_id":  event.EventID,
-			"timestamp": event.Timestamp,
-			"mode":      "kafka",
-		})
-	} else {
-		// Direct mode: process immediately
-		err := h.service.ProcessEvent(event, userID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
+	if err := h.ingest(c.Request.Context(), h.tenantService(c), event, auth.Caller(c), auth.ClassroomIDs(c)); err != nil {
+		respondError(c, err)
+		return
+	}
+	processed = 1
 
-		c.JSON(http.StatusCreated, gin.H{
-			"message":   "Event processed successfully",
-			"event_id":  event.EventID,
-			"timestamp": event.Timestamp,
-			"mode":      "direct",
-		})
+	message := "Event processed successfully"
+	if mode == "kafka" {
+		message = "Event queued successfully"
 	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   message,
+		"event_id":  event.EventID,
+		"timestamp": event.Timestamp,
+		"mode":      mode,
+	})
 }
 
 func (h *Handler) CreateBatchEvents(c *gin.Context) {
@@ -86,44 +189,26 @@ func (h *Handler) CreateBatchEvents(c *gin.Context) {
 	}
 
 	userID, _ := c.Get("userID")
+	ingestedBy := auth.Caller(c)
+	service := h.tenantService(c)
 	processedCount := 0
 	errors := []string{}
+	classroomIDs := auth.ClassroomIDs(c)
 
 	for _, event := range events {
-		if h.kafkaMode && h.producer != nil {
-			// Kafka mode: publish each event
-			err := h.producer.PublishEvent(event.EventID, event.EventType, event.SessionID, event)
-			if err != nil {
-				log.Printf("Failed to publish event %s to Kafka: %v", event.EventID, err)
-				// Try direct processing as fallback
-				err = h.service.ProcessEvent(event, userID)
-				if err != nil {
-					errors = append(errors, fmt.Sprintf("Event %s: %s", event.EventID, err.Error()))
-				} else {
-					processedCount++
-				}
-			} else {
-				processedCount++
-			}
-		} else {
-			// Direct mode: process immediately
-			err := h.service.ProcessEvent(event, userID)
-			if err != nil {
-				errors = append(errors, fmt.Sprintf("Event %s: %s", event.EventID, err.Error()))
-			} else {
-				processedCount++
-			}
+		if err := h.ingest(c.Request.Context(), service, event, ingestedBy, classroomIDs); err != nil {
+			errors = append(errors, fmt.Sprintf("Event %s: %s", event.EventID, errorMessage(err)))
+			continue
 		}
+		processedCount++
 	}
 
-	mode := "direct"
-	if h.kafkaMode {
-		mode = "kafka"
-	}
+	mode := h.mode()
 
 	response := gin.H{
 		"message":         "Batch events processed",
 		"user_id":         userID,
+		"ingested_by":     ingestedBy,
 		"processed_count": processedCount,
 		"total_events":    len(events),
 		"mode":            mode,
@@ -133,5 +218,52 @@ func (h *Handler) CreateBatchEvents(c *gin.Context) {
 		response["errors"] = errors
 	}
 
+	h.recordIngestion(c, mode, len(events), processedCount, "")
 	c.JSON(http.StatusCreated, response)
 }
+
+// respondError writes the error response for a failed event. Validation and missing-reference
+// errors are safe to echo back; anything else is replaced with a generic message.
+func respondError(c *gin.Context, err error) {
+	var missingErr *MissingReferenceError
+	if errors.As(err, &missingErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error": err.Error(),
+			"missing_reference": gin.H{
+				"entity": missingErr.Entity,
+				"id":     missingErr.ID,
+			},
+		})
+		return
+	}
+	c.JSON(statusForError(err), gin.H{"error": errorMessage(err)})
+}
+
+// statusForError maps service errors to HTTP status codes
+func statusForError(err error) int {
+	var validationErr *ValidationError
+	var missingErr *MissingReferenceError
+	var forbiddenErr *ForbiddenError
+	var conflictErr *ConflictError
+	switch {
+	case errors.As(err, &validationErr):
+		return http.StatusBadRequest
+	case errors.As(err, &forbiddenErr):
+		return http.StatusForbidden
+	case errors.As(err, &conflictErr):
+		return http.StatusConflict
+	case errors.As(err, &missingErr):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// errorMessage returns a client-safe message, hiding internal database errors; ingest
+// has logged them
+func errorMessage(err error) string {
+	if statusForError(err) != http.StatusInternalServerError {
+		return err.Error()
+	}
+	return "failed to process event"
+}
@@ -1,65 +1,218 @@
 package events
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/grading"
 	"github.com/rohanreddymelachervu/ingestor/internal/models"
 	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+	"gorm.io/gorm"
 )
 
 type Service struct {
-	EventRepo     repository.EventRepository
-	QuizRepo      repository.QuizRepository
-	SessionRepo   repository.SessionRepository
-	ClassroomRepo repository.ClassroomRepository
+	EventRepo        repository.EventRepository
+	QuizRepo         repository.QuizRepository
+	SessionRepo      repository.SessionRepository
+	ClassroomRepo    repository.ClassroomRepository
+	TeacherRepo      repository.TeacherRepository
+	ProvisioningRepo repository.ProvisioningRepository
+	TenantRepo       repository.TenantRepository
+	// TenantID is the tenant events are recorded for; set by ForTenant
+	TenantID uuid.UUID
+	// Provisioning decides whether unknown sessions, questions, students and teachers
+	// are auto-created or rejected. The zero value rejects everything.
+	Provisioning config.ProvisioningConfig
 }
 
 func NewService(eventRepo repository.EventRepository, quizRepo repository.QuizRepository,
-	sessionRepo repository.SessionRepository, classroomRepo repository.ClassroomRepository) *Service {
+	sessionRepo repository.SessionRepository, classroomRepo repository.ClassroomRepository,
+	teacherRepo repository.TeacherRepository, provisioningRepo repository.ProvisioningRepository,
+	tenantRepo repository.TenantRepository, provisioning config.ProvisioningConfig) *Service {
 	return &Service{
-		EventRepo:     eventRepo,
-		QuizRepo:      quizRepo,
-		SessionRepo:   sessionRepo,
-		ClassroomRepo: classroomRepo,
+		EventRepo:        eventRepo,
+		QuizRepo:         quizRepo,
+		SessionRepo:      sessionRepo,
+		ClassroomRepo:    classroomRepo,
+		TeacherRepo:      teacherRepo,
+		ProvisioningRepo: provisioningRepo,
+		TenantRepo:       tenantRepo,
+		Provisioning:     provisioning,
 	}
 }
 
-func (s *Service) ProcessEvent(event models.EventPayload, userID interface{}) error {
+// ForTenant returns a copy of the service that reads and writes only the tenant's data
+func (s *Service) ForTenant(tenantID uuid.UUID) *Service {
+	scoped := *s
+	scoped.TenantID = tenantID
+	scoped.EventRepo = s.EventRepo.ForTenant(tenantID)
+	scoped.QuizRepo = s.QuizRepo.ForTenant(tenantID)
+	scoped.SessionRepo = s.SessionRepo.ForTenant(tenantID)
+	scoped.ClassroomRepo = s.ClassroomRepo.ForTenant(tenantID)
+	scoped.TeacherRepo = s.TeacherRepo.ForTenant(tenantID)
+	scoped.ProvisioningRepo = s.ProvisioningRepo.ForTenant(tenantID)
+	return &scoped
+}
+
+// WithContext returns a copy of the service whose queries run under ctx, so they are traced
+// as part of the request or Kafka message that carried the event
+func (s *Service) WithContext(ctx context.Context) *Service {
+	scoped := *s
+	scoped.EventRepo = s.EventRepo.WithContext(ctx)
+	scoped.QuizRepo = s.QuizRepo.WithContext(ctx)
+	scoped.SessionRepo = s.SessionRepo.WithContext(ctx)
+	scoped.ClassroomRepo = s.ClassroomRepo.WithContext(ctx)
+	scoped.TeacherRepo = s.TeacherRepo.WithContext(ctx)
+	scoped.ProvisioningRepo = s.ProvisioningRepo.WithContext(ctx)
+	return &scoped
+}
+
+// ValidationError marks an event rejected because its payload is malformed
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+func (e *ValidationError) ErrorClass() string {
+	return "validation"
+}
+
+// MissingReferenceError marks an event that points at an entity which does not exist
+// and is not allowed to be auto-created
+type MissingReferenceError struct {
+	Entity string
+	ID     string
+}
+
+func (e *MissingReferenceError) Error() string {
+	return fmt.Sprintf("unknown %s %s", e.Entity, e.ID)
+}
+
+func (e *MissingReferenceError) ErrorClass() string {
+	return "missing_reference"
+}
+
+// ForbiddenError marks an event outside the classrooms the caller may record events for
+type ForbiddenError struct {
+	Reason string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Reason
+}
+
+func (e *ForbiddenError) ErrorClass() string {
+	return "forbidden"
+}
+
+// ConflictError marks an event that contradicts data already recorded, such as a second
+// SESSION_STARTED naming another classroom or quiz
+type ConflictError struct {
+	Reason string
+}
+
+func (e *ConflictError) Error() string {
+	return e.Reason
+}
+
+func (e *ConflictError) ErrorClass() string {
+	return "conflict"
+}
+
+// AuthorizeEvent checks that an event of a classroom-restricted device key belongs to one
+// of its classrooms. Events for an existing session must also match the session's classroom.
+func (s *Service) AuthorizeEvent(event models.EventPayload, classroomIDs []uuid.UUID) error {
+	classroomID, err := parseID("classroom_id", event.ClassroomID)
+	if err != nil {
+		return err
+	}
+	if !containsID(classroomIDs, classroomID) {
+		return &ForbiddenError{Reason: fmt.Sprintf("classroom %s is outside the classrooms of this api key", classroomID)}
+	}
+
+	sessionID, err := parseID("session_id", event.SessionID)
+	if err != nil {
+		return err
+	}
+	session, err := s.SessionRepo.GetSessionByID(sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load session: %v", err)
+	}
+	if !containsID(classroomIDs, session.ClassroomID) {
+		return &ForbiddenError{Reason: fmt.Sprintf("session %s belongs to a classroom outside the classrooms of this api key", sessionID)}
+	}
+	return nil
+}
+
+func containsID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessEvent records the event. ingestedBy names who sent it, as returned by auth.Caller,
+// and is stored with the event; it is empty for events published before it was recorded.
+func (s *Service) ProcessEvent(event models.EventPayload, ingestedBy string) error {
+	var source *string
+	if ingestedBy != "" {
+		source = &ingestedBy
+	}
 	switch event.EventType {
 	case "QUESTION_PUBLISHED":
-		return s.processQuestionPublishedEvent(event, userID)
+		return s.processQuestionPublishedEvent(event, source)
 	case "ANSWER_SUBMITTED":
-		return s.processAnswerSubmittedEvent(event, userID)
+		return s.processAnswerSubmittedEvent(event, source)
 	case "SESSION_STARTED":
-		return s.processSessionStartedEvent(event, userID)
+		return s.processSessionStartedEvent(event, source)
+	case "STUDENT_JOINED", "STUDENT_LEFT":
+		return s.processPresenceEvent(event, source)
+	case "QUESTION_SKIPPED":
+		return s.processQuestionSkippedEvent(event, source)
 	default:
-		return fmt.Errorf("unknown event type: %s", event.EventType)
+		return &ValidationError{Reason: fmt.Sprintf("unknown event type: %s", event.EventType)}
 	}
 }
 
-func (s *Service) processQuestionPublishedEvent(event models.EventPayload, userID interface{}) error {
+func (s *Service) processQuestionPublishedEvent(event models.EventPayload, ingestedBy *string) error {
 	// Parse UUIDs
-	eventID, err := uuid.Parse(event.EventID)
+	eventID, err := parseID("event_id", event.EventID)
 	if err != nil {
-		return fmt.Errorf("invalid event_id: %v", err)
+		return err
 	}
-	sessionID, err := uuid.Parse(event.SessionID)
+	sessionID, err := parseID("session_id", event.SessionID)
 	if err != nil {
-		return fmt.Errorf("invalid session_id: %v", err)
+		return err
 	}
-	questionID, err := uuid.Parse(event.QuestionID)
+	questionID, err := parseID("question_id", event.QuestionID)
 	if err != nil {
-		return fmt.Errorf("invalid question_id: %v", err)
+		return err
 	}
 
-	var teacherID *uuid.UUID
-	if event.TeacherID != nil {
-		parsed, err := uuid.Parse(*event.TeacherID)
-		if err != nil {
-			return fmt.Errorf("invalid teacher_id: %v", err)
-		}
-		teacherID = &parsed
+	if err := s.ensureSession(sessionID, eventID, event); err != nil {
+		return err
+	}
+	if err := s.ensureQuestion(questionID, eventID, event); err != nil {
+		return err
+	}
+	teacherID, err := s.eventTeacher(event, eventID)
+	if err != nil {
+		return err
 	}
 
 	// Create question published event
@@ -69,84 +222,596 @@ func (s *Service) processQuestionPublishedEvent(event models.EventPayload, userI
 		QuestionID:  questionID,
 		TeacherID:   teacherID,
 		PublishedAt: event.Timestamp,
+		IngestedBy:  ingestedBy,
 	}
 
 	if event.TimerSec != nil {
 		questionEvent.TimerDurationSec = *event.TimerSec
 	}
 
-	return s.EventRepo.SaveQuestionPublishedEvent(questionEvent)
+	if err := translateDBError(s.EventRepo.SaveQuestionPublishedEvent(questionEvent)); err != nil {
+		return err
+	}
+
+	// Sessions started without a teacher are attributed to the first one who publishes
+	if teacherID != nil {
+		return s.SessionRepo.AttributeTeacher(sessionID, *teacherID)
+	}
+	return nil
 }
 
-func (s *Service) processAnswerSubmittedEvent(event models.EventPayload, userID interface{}) error {
+func (s *Service) processAnswerSubmittedEvent(event models.EventPayload, ingestedBy *string) error {
 	// Parse UUIDs
-	eventID, err := uuid.Parse(event.EventID)
+	eventID, err := parseID("event_id", event.EventID)
 	if err != nil {
-		return fmt.Errorf("invalid event_id: %v", err)
+		return err
 	}
-	sessionID, err := uuid.Parse(event.SessionID)
+	sessionID, err := parseID("session_id", event.SessionID)
 	if err != nil {
-		return fmt.Errorf("invalid session_id: %v", err)
+		return err
 	}
-	questionID, err := uuid.Parse(event.QuestionID)
+	questionID, err := parseID("question_id", event.QuestionID)
 	if err != nil {
-		return fmt.Errorf("invalid question_id: %v", err)
+		return err
 	}
 
-	if event.StudentID == nil || event.Answer == nil {
-		return fmt.Errorf("student_id and answer are required for ANSWER_SUBMITTED events")
+	if event.StudentID == nil || len(event.Answer) == 0 {
+		return &ValidationError{Reason: "student_id and answer are required for ANSWER_SUBMITTED events"}
 	}
 
-	studentID, err := uuid.Parse(*event.StudentID)
+	studentID, err := parseID("student_id", *event.StudentID)
 	if err != nil {
-		return fmt.Errorf("invalid student_id: %v", err)
+		return err
 	}
 
-	// Timer validation: check if answer is submitted within allowed time
-	err = s.EventRepo.ValidateAnswerTiming(sessionID, questionID, event.Timestamp)
+	if err := s.ensureSession(sessionID, eventID, event); err != nil {
+		return err
+	}
+	if err := s.ensureQuestion(questionID, eventID, event); err != nil {
+		return err
+	}
+	if err := s.ensureStudent(studentID, eventID); err != nil {
+		return err
+	}
+
+	// Validate the typed answer payload and score it before touching timing rules
+	result, question, err := s.gradeAnswer(questionID, event)
 	if err != nil {
-		return fmt.Errorf("answer submitted after deadline: %v", err)
+		return err
 	}
 
-	// Determine if answer is correct (A and C = correct, B and D = incorrect)
-	isCorrect := (*event.Answer == "A" || *event.Answer == "C")
+	// Timer validation: late answers are rejected unless the tenant's policy accepts them
+	isLate := false
+	err = s.EventRepo.ValidateAnswerTiming(sessionID, questionID, event.Timestamp)
+	var late *repository.LateAnswerError
+	if errors.As(err, &late) {
+		if err := s.checkLateAnswer(late); err != nil {
+			return err
+		}
+		isLate = true
+	} else if err != nil {
+		return &ValidationError{Reason: fmt.Sprintf("answer submitted after deadline: %v", err)}
+	}
 
 	// Create answer submitted event
 	answerEvent := &models.AnswerSubmittedEvent{
-		EventID:     eventID,
-		SessionID:   sessionID,
-		QuestionID:  questionID,
-		StudentID:   studentID,
-		Answer:      *event.Answer,
-		IsCorrect:   isCorrect,
-		SubmittedAt: event.Timestamp,
+		EventID:       eventID,
+		SessionID:     sessionID,
+		QuestionID:    questionID,
+		StudentID:     studentID,
+		Answer:        result.Canonical,
+		AnswerPayload: event.Answer,
+		IsCorrect:     result.IsCorrect,
+		Score:         result.Score,
+		SubmittedAt:   event.Timestamp,
+		IsLate:        isLate,
+		IngestedBy:    ingestedBy,
+	}
+	if question != nil {
+		answerEvent.QuestionVersion = &question.Version
+	}
+
+	return translateDBError(s.EventRepo.SaveAnswerSubmittedEvent(answerEvent))
+}
+
+// checkLateAnswer applies the tenant's late-answer policy to an answer past its deadline
+func (s *Service) checkLateAnswer(late *repository.LateAnswerError) error {
+	tenant, err := s.TenantRepo.GetTenantByID(s.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant settings: %v", err)
+	}
+	switch tenant.LateAnswerPolicy {
+	case models.LateAnswerAccept:
+		return nil
+	case models.LateAnswerGrace:
+		grace := time.Duration(tenant.LateAnswerGraceSec) * time.Second
+		if !late.SubmittedAt.After(late.Deadline.Add(grace)) {
+			return nil
+		}
+	}
+	return &ValidationError{Reason: fmt.Sprintf("answer submitted after deadline: %v", late)}
+}
+
+// ValidateEvent checks an event payload and its references without persisting anything.
+// Used in Kafka mode so that malformed answers and unknown references are rejected at the
+// HTTP boundary instead of in the consumer.
+func (s *Service) ValidateEvent(event models.EventPayload) error {
+	if err := s.validatePayload(event); err != nil {
+		return err
+	}
+	return s.checkReferences(event)
+}
+
+// checkReferences runs the existence checks of ProcessEvent without writing anything.
+// Sessions are left to the consumer, as one started by an event still on the topic does
+// not exist yet.
+func (s *Service) checkReferences(event models.EventPayload) error {
+	switch event.EventType {
+	case "SESSION_STARTED", "QUESTION_PUBLISHED":
+		if event.TeacherID != nil && *event.TeacherID != "" {
+			teacherID, err := parseID("teacher_id", *event.TeacherID)
+			if err != nil {
+				return err
+			}
+			if _, err := s.checkTeacher(teacherID); err != nil {
+				return err
+			}
+		}
+	}
+	if event.EventType == "SESSION_STARTED" {
+		if _, err := s.ensureQuiz(event.QuizID); err != nil {
+			return err
+		}
+		_, err := s.ensureClassroom(event.ClassroomID)
+		return err
+	}
+
+	switch event.EventType {
+	case "QUESTION_PUBLISHED", "ANSWER_SUBMITTED", "QUESTION_SKIPPED":
+		questionID, err := parseID("question_id", event.QuestionID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkQuestion(questionID, event); err != nil {
+			return err
+		}
+	}
+	if event.EventType != "QUESTION_PUBLISHED" {
+		studentID, err := parseID("student_id", *event.StudentID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.checkStudent(studentID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePayload checks the shape of an event payload and grades its answer
+func (s *Service) validatePayload(event models.EventPayload) error {
+	switch event.EventType {
+	case "QUESTION_PUBLISHED", "SESSION_STARTED":
+		return nil
+	case "STUDENT_JOINED", "STUDENT_LEFT":
+		return requireStudent(event)
+	case "QUESTION_SKIPPED":
+		if _, err := parseID("question_id", event.QuestionID); err != nil {
+			return err
+		}
+		return requireStudent(event)
+	case "ANSWER_SUBMITTED":
+	default:
+		return &ValidationError{Reason: fmt.Sprintf("unknown event type: %s", event.EventType)}
+	}
+
+	questionID, err := parseID("question_id", event.QuestionID)
+	if err != nil {
+		return err
+	}
+	if event.StudentID == nil || len(event.Answer) == 0 {
+		return &ValidationError{Reason: "student_id and answer are required for ANSWER_SUBMITTED events"}
+	}
+	_, _, err = s.gradeAnswer(questionID, event)
+	return err
+}
+
+// gradeAnswer scores the answer against the current version of the question.
+// Unregistered questions are graded as legacy single-choice questions and return a nil question.
+func (s *Service) gradeAnswer(questionID uuid.UUID, event models.EventPayload) (*grading.Result, *models.Question, error) {
+	questionType := grading.TypeSingleChoice
+	var answerKey []byte
+
+	question, err := s.QuizRepo.GetQuestionByID(questionID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("failed to load question: %v", err)
+		}
+		question = nil
+	} else {
+		questionType = question.QuestionType
+		answerKey = question.AnswerKey
 	}
 
-	return s.EventRepo.SaveAnswerSubmittedEvent(answerEvent)
+	result, err := grading.Grade(questionType, answerKey, event.Answer)
+	if err == nil && question != nil {
+		err = grading.ValidateOptions(questionType, event.Answer, question.Options.Keys())
+	}
+	if err != nil {
+		var invalid *grading.InvalidAnswerError
+		if errors.As(err, &invalid) {
+			return nil, nil, &ValidationError{Reason: invalid.Error()}
+		}
+		return nil, nil, err
+	}
+	return result, question, nil
 }
 
-func (s *Service) processSessionStartedEvent(event models.EventPayload, userID interface{}) error {
+func (s *Service) processSessionStartedEvent(event models.EventPayload, ingestedBy *string) error {
 	// Parse UUIDs
-	sessionID, err := uuid.Parse(event.SessionID)
+	eventID, err := parseID("event_id", event.EventID)
 	if err != nil {
-		return fmt.Errorf("invalid session_id: %v", err)
+		return err
 	}
-	quizID, err := uuid.Parse(event.QuizID)
+	sessionID, err := parseID("session_id", event.SessionID)
 	if err != nil {
-		return fmt.Errorf("invalid quiz_id: %v", err)
+		return err
 	}
-	classroomID, err := uuid.Parse(event.ClassroomID)
+	quizID, err := parseID("quiz_id", event.QuizID)
 	if err != nil {
-		return fmt.Errorf("invalid classroom_id: %v", err)
+		return err
+	}
+	classroomID, err := parseID("classroom_id", event.ClassroomID)
+	if err != nil {
+		return err
+	}
+
+	teacherID, err := s.eventTeacher(event, eventID)
+	if err != nil {
+		return err
+	}
+	if teacherID == nil {
+		if teacherID, err = s.primaryTeacher(classroomID); err != nil {
+			return err
+		}
+	}
+
+	// A session auto-created by an earlier event gets its real data now. A started session
+	// is left as it is: a repeat is a no-op and a contradicting start is rejected, so that
+	// retries or another device cannot move its history to another classroom or quiz.
+	existing, err := s.SessionRepo.GetSessionByID(sessionID)
+	if err == nil {
+		placeholder, err := s.ProvisioningRepo.IsPlaceholder("session", sessionID)
+		if err != nil {
+			return fmt.Errorf("failed to load session: %v", err)
+		}
+		if !placeholder {
+			if existing.QuizID != quizID || existing.ClassroomID != classroomID {
+				return &ConflictError{Reason: fmt.Sprintf("session %s already started with another quiz or classroom", sessionID)}
+			}
+			return nil
+		}
+		existing.QuizID = quizID
+		existing.ClassroomID = classroomID
+		existing.StartedAt = event.Timestamp
+		existing.IngestedBy = ingestedBy
+		if teacherID != nil {
+			existing.TeacherID = teacherID
+		}
+		if err := translateDBError(s.SessionRepo.UpdateSession(existing)); err != nil {
+			return err
+		}
+		return s.ProvisioningRepo.MarkReconciled("session", sessionID)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to load session: %v", err)
 	}
 
-	// Create or update session
 	session := &models.QuizSession{
 		SessionID:   sessionID,
 		QuizID:      quizID,
 		ClassroomID: classroomID,
+		TeacherID:   teacherID,
 		StartedAt:   event.Timestamp,
+		IngestedBy:  ingestedBy,
 	}
 
-	return s.SessionRepo.CreateSession(session)
+	return translateDBError(s.SessionRepo.CreateSession(session))
+}
+
+// eventTeacher resolves the optional teacher_id of the event, making sure the teacher exists
+func (s *Service) eventTeacher(event models.EventPayload, eventID uuid.UUID) (*uuid.UUID, error) {
+	if event.TeacherID == nil || *event.TeacherID == "" {
+		return nil, nil
+	}
+	teacherID, err := parseID("teacher_id", *event.TeacherID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureTeacher(teacherID, eventID); err != nil {
+		return nil, err
+	}
+	return &teacherID, nil
+}
+
+// primaryTeacher returns the classroom's primary teacher, or nil when none is assigned
+func (s *Service) primaryTeacher(classroomID uuid.UUID) (*uuid.UUID, error) {
+	teacher, err := s.TeacherRepo.GetPrimaryTeacher(classroomID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load classroom teacher: %v", err)
+	}
+	return &teacher.TeacherID, nil
+}
+
+func (s *Service) processPresenceEvent(event models.EventPayload, ingestedBy *string) error {
+	eventID, err := parseID("event_id", event.EventID)
+	if err != nil {
+		return err
+	}
+	sessionID, err := parseID("session_id", event.SessionID)
+	if err != nil {
+		return err
+	}
+	if err := requireStudent(event); err != nil {
+		return err
+	}
+	studentID, err := parseID("student_id", *event.StudentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureSession(sessionID, eventID, event); err != nil {
+		return err
+	}
+	if err := s.ensureStudent(studentID, eventID); err != nil {
+		return err
+	}
+
+	presenceEvent := &models.SessionPresenceEvent{
+		EventID:    eventID,
+		SessionID:  sessionID,
+		StudentID:  studentID,
+		EventType:  event.EventType,
+		OccurredAt: event.Timestamp,
+		IngestedBy: ingestedBy,
+	}
+
+	return translateDBError(s.EventRepo.SavePresenceEvent(presenceEvent))
+}
+
+func (s *Service) processQuestionSkippedEvent(event models.EventPayload, ingestedBy *string) error {
+	eventID, err := parseID("event_id", event.EventID)
+	if err != nil {
+		return err
+	}
+	sessionID, err := parseID("session_id", event.SessionID)
+	if err != nil {
+		return err
+	}
+	questionID, err := parseID("question_id", event.QuestionID)
+	if err != nil {
+		return err
+	}
+	if err := requireStudent(event); err != nil {
+		return err
+	}
+	studentID, err := parseID("student_id", *event.StudentID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.ensureSession(sessionID, eventID, event); err != nil {
+		return err
+	}
+	if err := s.ensureQuestion(questionID, eventID, event); err != nil {
+		return err
+	}
+	if err := s.ensureStudent(studentID, eventID); err != nil {
+		return err
+	}
+
+	skippedEvent := &models.QuestionSkippedEvent{
+		EventID:    eventID,
+		SessionID:  sessionID,
+		QuestionID: questionID,
+		StudentID:  studentID,
+		SkippedAt:  event.Timestamp,
+		IngestedBy: ingestedBy,
+	}
+
+	return translateDBError(s.EventRepo.SaveQuestionSkippedEvent(skippedEvent))
+}
+
+// requireStudent checks that a student-level event names its student
+func requireStudent(event models.EventPayload) error {
+	if event.StudentID == nil || *event.StudentID == "" {
+		return &ValidationError{Reason: fmt.Sprintf("student_id is required for %s events", event.EventType)}
+	}
+	return nil
+}
+
+// ensureSession makes sure the referenced session exists. When auto-creation is enabled a
+// placeholder is created from the event's quiz and classroom, both of which must already exist.
+func (s *Service) ensureSession(sessionID, eventID uuid.UUID, event models.EventPayload) error {
+	placeholder, err := s.checkSession(sessionID, event)
+	if err != nil || placeholder == nil {
+		return err
+	}
+	return translateDBError(s.ProvisioningRepo.ProvisionSession(placeholder, eventID))
+}
+
+// checkSession returns nil if the session exists, or else the placeholder ensureSession
+// would create, without writing anything
+func (s *Service) checkSession(sessionID uuid.UUID, event models.EventPayload) (*models.QuizSession, error) {
+	_, err := s.SessionRepo.GetSessionByID(sessionID)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load session: %v", err)
+	}
+	if s.Provisioning.Sessions != config.ProvisionAutoCreate {
+		return nil, &MissingReferenceError{Entity: "session", ID: sessionID.String()}
+	}
+
+	quizID, err := s.ensureQuiz(event.QuizID)
+	if err != nil {
+		return nil, err
+	}
+	classroomID, err := s.ensureClassroom(event.ClassroomID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.QuizSession{
+		SessionID:   sessionID,
+		QuizID:      quizID,
+		ClassroomID: classroomID,
+		StartedAt:   event.Timestamp,
+	}, nil
+}
+
+// ensureQuestion makes sure the referenced question exists. Placeholders are attached to
+// the event's quiz and graded with the legacy single-choice rule until an author fills them in.
+func (s *Service) ensureQuestion(questionID, eventID uuid.UUID, event models.EventPayload) error {
+	placeholder, err := s.checkQuestion(questionID, event)
+	if err != nil || placeholder == nil {
+		return err
+	}
+	return translateDBError(s.ProvisioningRepo.ProvisionQuestion(placeholder, eventID))
+}
+
+// checkQuestion returns nil if the question exists, or else the placeholder ensureQuestion
+// would create
+func (s *Service) checkQuestion(questionID uuid.UUID, event models.EventPayload) (*models.Question, error) {
+	_, err := s.QuizRepo.GetQuestionByID(questionID)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load question: %v", err)
+	}
+	if s.Provisioning.Questions != config.ProvisionAutoCreate {
+		return nil, &MissingReferenceError{Entity: "question", ID: questionID.String()}
+	}
+
+	quizID, err := s.ensureQuiz(event.QuizID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Question{QuestionID: questionID, QuizID: quizID}, nil
+}
+
+// ensureStudent makes sure the referenced student exists, creating a nameless stub when allowed
+func (s *Service) ensureStudent(studentID, eventID uuid.UUID) error {
+	placeholder, err := s.checkStudent(studentID)
+	if err != nil || placeholder == nil {
+		return err
+	}
+	return translateDBError(s.ProvisioningRepo.ProvisionStudent(placeholder, eventID))
+}
+
+// checkStudent returns nil if the student exists, or else the stub ensureStudent would create
+func (s *Service) checkStudent(studentID uuid.UUID) (*models.Student, error) {
+	_, err := s.ClassroomRepo.GetStudentByID(studentID)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load student: %v", err)
+	}
+	if s.Provisioning.Students != config.ProvisionAutoCreate {
+		return nil, &MissingReferenceError{Entity: "student", ID: studentID.String()}
+	}
+	return &models.Student{StudentID: studentID}, nil
+}
+
+// ensureTeacher makes sure the referenced teacher exists, creating a nameless stub when allowed
+func (s *Service) ensureTeacher(teacherID, eventID uuid.UUID) error {
+	placeholder, err := s.checkTeacher(teacherID)
+	if err != nil || placeholder == nil {
+		return err
+	}
+	return translateDBError(s.ProvisioningRepo.ProvisionTeacher(placeholder, eventID))
+}
+
+// checkTeacher returns nil if the teacher exists, or else the stub ensureTeacher would create
+func (s *Service) checkTeacher(teacherID uuid.UUID) (*models.Teacher, error) {
+	_, err := s.TeacherRepo.GetTeacherByID(teacherID)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to load teacher: %v", err)
+	}
+	if s.Provisioning.Teachers != config.ProvisionAutoCreate {
+		return nil, &MissingReferenceError{Entity: "teacher", ID: teacherID.String()}
+	}
+	return &models.Teacher{TeacherID: teacherID}, nil
+}
+
+// ensureClassroom resolves a classroom the event names. Classrooms are never auto-created.
+func (s *Service) ensureClassroom(rawClassroomID string) (uuid.UUID, error) {
+	classroomID, err := parseID("classroom_id", rawClassroomID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := s.ClassroomRepo.GetClassroomByID(classroomID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, &MissingReferenceError{Entity: "classroom", ID: classroomID.String()}
+		}
+		return uuid.Nil, fmt.Errorf("failed to load classroom: %v", err)
+	}
+	return classroomID, nil
+}
+
+// ensureQuiz resolves the event's quiz. Quizzes are never auto-created.
+func (s *Service) ensureQuiz(rawQuizID string) (uuid.UUID, error) {
+	quizID, err := parseID("quiz_id", rawQuizID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if _, err := s.QuizRepo.GetQuizByID(quizID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, &MissingReferenceError{Entity: "quiz", ID: quizID.String()}
+		}
+		return uuid.Nil, fmt.Errorf("failed to load quiz: %v", err)
+	}
+	return quizID, nil
+}
+
+// parseID parses a UUID field from the payload
+func parseID(field, value string) (uuid.UUID, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return uuid.Nil, &ValidationError{Reason: fmt.Sprintf("invalid %s: %v", field, err)}
+	}
+	return id, nil
+}
+
+// fkDetail matches the Postgres foreign-key violation detail, e.g.
+// Key (student_id)=(...) is not present in table "students", or for the
+// tenant-scoped keys Key (tenant_id, student_id)=(..., ...).
+var fkDetail = regexp.MustCompile(`Key \(([\w, ]+)\)=\(([^)]*)\)`)
+
+// translateDBError turns foreign-key violations that slip past the existence checks
+// (e.g. a row deleted concurrently) into MissingReferenceError
+func translateDBError(err error) error {
+	var pgErr *pgconn.PgError
+	if err == nil || !errors.As(err, &pgErr) || pgErr.Code != "23503" {
+		return err
+	}
+	match := fkDetail.FindStringSubmatch(pgErr.Detail)
+	if match == nil {
+		return &MissingReferenceError{Entity: pgErr.TableName, ID: "unknown"}
+	}
+	// the referenced entity is the last column of the key
+	columns := strings.Split(match[1], ", ")
+	values := strings.Split(match[2], ", ")
+	return &MissingReferenceError{
+		Entity: strings.TrimSuffix(columns[len(columns)-1], "_id"),
+		ID:     values[len(values)-1],
+	}
 }
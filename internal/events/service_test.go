@@ -0,0 +1,165 @@
+package events
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+	"gorm.io/gorm"
+)
+
+// The fakes implement the lookups ValidateEvent makes; any write panics on the nil
+// embedded interface, which is how the tests see that validation writes nothing.
+
+type fakeQuizRepo struct {
+	repository.QuizRepository
+	quizzes, questions map[uuid.UUID]bool
+}
+
+func (r *fakeQuizRepo) GetQuizByID(id uuid.UUID) (*models.Quiz, error) {
+	if !r.quizzes[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.Quiz{QuizID: id}, nil
+}
+
+func (r *fakeQuizRepo) GetQuestionByID(id uuid.UUID) (*models.Question, error) {
+	if !r.questions[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.Question{QuestionID: id, QuestionType: "single_choice", AnswerKey: []byte(`{"correct":"A"}`)}, nil
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+}
+
+func (r *fakeSessionRepo) GetSessionByID(uuid.UUID) (*models.QuizSession, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+type fakeClassroomRepo struct {
+	repository.ClassroomRepository
+	classrooms, students map[uuid.UUID]bool
+}
+
+func (r *fakeClassroomRepo) GetClassroomByID(id uuid.UUID) (*models.Classroom, error) {
+	if !r.classrooms[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.Classroom{ClassroomID: id}, nil
+}
+
+func (r *fakeClassroomRepo) GetStudentByID(id uuid.UUID) (*models.Student, error) {
+	if !r.students[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.Student{StudentID: id}, nil
+}
+
+type fakeTeacherRepo struct {
+	repository.TeacherRepository
+	teachers map[uuid.UUID]bool
+}
+
+func (r *fakeTeacherRepo) GetTeacherByID(id uuid.UUID) (*models.Teacher, error) {
+	if !r.teachers[id] {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &models.Teacher{TeacherID: id}, nil
+}
+
+func TestValidateEventChecksReferences(t *testing.T) {
+	quiz, classroom, question, student, teacher := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	unknown := uuid.New().String()
+	known := func(ids ...uuid.UUID) map[uuid.UUID]bool {
+		m := map[uuid.UUID]bool{}
+		for _, id := range ids {
+			m[id] = true
+		}
+		return m
+	}
+	newService := func(provisioning config.ProvisioningConfig) *Service {
+		return &Service{
+			QuizRepo:      &fakeQuizRepo{quizzes: known(quiz), questions: known(question)},
+			SessionRepo:   &fakeSessionRepo{},
+			ClassroomRepo: &fakeClassroomRepo{classrooms: known(classroom), students: known(student)},
+			TeacherRepo:   &fakeTeacherRepo{teachers: known(teacher)},
+			Provisioning:  provisioning,
+		}
+	}
+	event := func(eventType string, edit func(*models.EventPayload)) models.EventPayload {
+		studentID, teacherID := student.String(), teacher.String()
+		e := models.EventPayload{
+			EventID:     uuid.NewString(),
+			EventType:   eventType,
+			Timestamp:   time.Now(),
+			SessionID:   uuid.NewString(), // never exists: sessions are checked by the consumer
+			QuizID:      quiz.String(),
+			ClassroomID: classroom.String(),
+			QuestionID:  question.String(),
+			StudentID:   &studentID,
+			TeacherID:   &teacherID,
+			Answer:      []byte(`"A"`),
+		}
+		if edit != nil {
+			edit(&e)
+		}
+		return e
+	}
+	reject := config.ProvisioningConfig{}
+	autoCreate := config.ProvisioningConfig{
+		Sessions: config.ProvisionAutoCreate, Questions: config.ProvisionAutoCreate,
+		Students: config.ProvisionAutoCreate, Teachers: config.ProvisionAutoCreate,
+	}
+
+	cases := []struct {
+		name         string
+		provisioning config.ProvisioningConfig
+		event        models.EventPayload
+		missing      string // the entity reported missing, or "" if the event is valid
+	}{
+		{"session started", reject, event("SESSION_STARTED", nil), ""},
+		{"session started unknown quiz", reject, event("SESSION_STARTED", func(e *models.EventPayload) { e.QuizID = unknown }), "quiz"},
+		{"session started unknown classroom", reject, event("SESSION_STARTED", func(e *models.EventPayload) { e.ClassroomID = unknown }), "classroom"},
+		{"session started unknown teacher", reject, event("SESSION_STARTED", func(e *models.EventPayload) { e.TeacherID = &unknown }), "teacher"},
+		{"answer", reject, event("ANSWER_SUBMITTED", nil), ""},
+		{"answer unknown question", reject, event("ANSWER_SUBMITTED", func(e *models.EventPayload) { e.QuestionID = unknown }), "question"},
+		{"answer unknown student", reject, event("ANSWER_SUBMITTED", func(e *models.EventPayload) { e.StudentID = &unknown }), "student"},
+		{"skip unknown question", reject, event("QUESTION_SKIPPED", func(e *models.EventPayload) { e.QuestionID = unknown }), "question"},
+		{"join unknown student", reject, event("STUDENT_JOINED", func(e *models.EventPayload) { e.StudentID = &unknown }), "student"},
+		{"join ignores the question", reject, event("STUDENT_JOINED", func(e *models.EventPayload) { e.QuestionID = unknown }), ""},
+		{"publish unknown teacher", reject, event("QUESTION_PUBLISHED", func(e *models.EventPayload) { e.TeacherID = &unknown }), "teacher"},
+		{"publish unknown question", reject, event("QUESTION_PUBLISHED", func(e *models.EventPayload) { e.QuestionID = unknown }), "question"},
+		{"auto-created student", autoCreate, event("STUDENT_JOINED", func(e *models.EventPayload) { e.StudentID = &unknown }), ""},
+		{"auto-created teacher", autoCreate, event("QUESTION_PUBLISHED", func(e *models.EventPayload) { e.TeacherID = &unknown }), ""},
+		{"auto-created question", autoCreate, event("QUESTION_PUBLISHED", func(e *models.EventPayload) { e.QuestionID = unknown }), ""},
+		{"auto-created question needs its quiz", autoCreate, event("QUESTION_PUBLISHED", func(e *models.EventPayload) {
+			e.QuestionID = unknown
+			e.QuizID = uuid.NewString()
+		}), "quiz"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := newService(tc.provisioning).ValidateEvent(tc.event)
+			if tc.missing == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var missing *MissingReferenceError
+			if !errors.As(err, &missing) || missing.Entity != tc.missing {
+				t.Fatalf("got %v, want a missing %s", err, tc.missing)
+			}
+			if statusForError(err) != http.StatusUnprocessableEntity {
+				t.Errorf("status %d, want 422", statusForError(err))
+			}
+		})
+	}
+}
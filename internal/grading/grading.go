@@ -0,0 +1,465 @@
+package grading
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Supported question types
+const (
+	TypeSingleChoice = "single_choice"
+	TypeMultiSelect  = "multi_select"
+	TypeTrueFalse    = "true_false"
+	TypeNumeric      = "numeric"
+	TypeShortText    = "short_text"
+	TypeOrdering     = "ordering"
+)
+
+// ValidTypes lists every question type accepted by the system
+var ValidTypes = []string{
+	TypeSingleChoice,
+	TypeMultiSelect,
+	TypeTrueFalse,
+	TypeNumeric,
+	TypeShortText,
+	TypeOrdering,
+}
+
+// Result is the outcome of grading a single answer
+type Result struct {
+	Score     float64 // partial credit in the range [0, 1]
+	IsCorrect bool    // true only for full credit
+	Canonical string  // normalized text form stored in answer_submitted_events.answer
+}
+
+// InvalidAnswerError is returned when an answer payload does not match the question type
+type InvalidAnswerError struct {
+	QuestionType string
+	Reason       string
+}
+
+func (e *InvalidAnswerError) Error() string {
+	return fmt.Sprintf("invalid answer for %s question: %s", e.QuestionType, e.Reason)
+}
+
+// Answer key shapes, one per question type
+type singleChoiceKey struct {
+	Correct string `json:"correct"`
+}
+
+type multiSelectKey struct {
+	Correct       []string `json:"correct"`
+	PartialCredit bool     `json:"partial_credit"`
+}
+
+type trueFalseKey struct {
+	Correct *bool `json:"correct"`
+}
+
+type numericKey struct {
+	Value     *float64 `json:"value"`
+	Tolerance float64  `json:"tolerance"`
+}
+
+type shortTextKey struct {
+	Accepted      []string `json:"accepted"`
+	CaseSensitive bool     `json:"case_sensitive"`
+}
+
+type orderingKey struct {
+	Correct       []string `json:"correct"`
+	PartialCredit bool     `json:"partial_credit"`
+}
+
+// IsValidType reports whether the given question type is supported
+func IsValidType(questionType string) bool {
+	for _, t := range ValidTypes {
+		if t == questionType {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateAnswerKey checks that an answer key is well formed for the question type
+func ValidateAnswerKey(questionType string, key json.RawMessage) error {
+	if !IsValidType(questionType) {
+		return fmt.Errorf("unknown question type: %s", questionType)
+	}
+	if len(key) == 0 || string(key) == "null" {
+		// Single-choice questions may omit the key and fall back to legacy grading
+		if questionType == TypeSingleChoice {
+			return nil
+		}
+		return fmt.Errorf("answer_key is required for %s questions", questionType)
+	}
+
+	switch questionType {
+	case TypeSingleChoice:
+		var k singleChoiceKey
+		if err := json.Unmarshal(key, &k); err != nil || k.Correct == "" {
+			return fmt.Errorf("single_choice answer_key must be {\"correct\": \"<option>\"}")
+		}
+	case TypeMultiSelect:
+		var k multiSelectKey
+		if err := json.Unmarshal(key, &k); err != nil || len(k.Correct) == 0 {
+			return fmt.Errorf("multi_select answer_key must list at least one correct option")
+		}
+	case TypeTrueFalse:
+		var k trueFalseKey
+		if err := json.Unmarshal(key, &k); err != nil || k.Correct == nil {
+			return fmt.Errorf("true_false answer_key must be {\"correct\": true|false}")
+		}
+	case TypeNumeric:
+		var k numericKey
+		if err := json.Unmarshal(key, &k); err != nil || k.Value == nil {
+			return fmt.Errorf("numeric answer_key must include a value")
+		}
+		if k.Tolerance < 0 {
+			return fmt.Errorf("numeric tolerance cannot be negative")
+		}
+	case TypeShortText:
+		var k shortTextKey
+		if err := json.Unmarshal(key, &k); err != nil || len(k.Accepted) == 0 {
+			return fmt.Errorf("short_text answer_key must list at least one accepted answer")
+		}
+	case TypeOrdering:
+		var k orderingKey
+		if err := json.Unmarshal(key, &k); err != nil || len(k.Correct) < 2 {
+			return fmt.Errorf("ordering answer_key must list at least two items")
+		}
+	}
+	return nil
+}
+
+// ValidateOptions checks that a choice answer only references the registered option keys,
+// comparing keys the way the grader does. Question types without options are always accepted.
+func ValidateOptions(questionType string, answer json.RawMessage, options []string) error {
+	if len(options) == 0 {
+		return nil
+	}
+	var selected []string
+	switch questionType {
+	case TypeSingleChoice:
+		var choice string
+		if err := json.Unmarshal(answer, &choice); err != nil {
+			return &InvalidAnswerError{QuestionType: questionType, Reason: "expected a single option string"}
+		}
+		selected = []string{OptionKey(choice)}
+	case TypeMultiSelect:
+		if err := json.Unmarshal(answer, &selected); err != nil {
+			return &InvalidAnswerError{QuestionType: questionType, Reason: "expected an array of option strings"}
+		}
+		selected = optionKeys(selected)
+	default:
+		return nil
+	}
+
+	known := toSet(optionKeys(options))
+	for _, option := range selected {
+		if !known[option] {
+			return &InvalidAnswerError{QuestionType: questionType, Reason: fmt.Sprintf("unknown option %q", option)}
+		}
+	}
+	return nil
+}
+
+// ValidateKeyOptions checks that the correct options in an answer key exist among the option keys
+func ValidateKeyOptions(questionType string, key json.RawMessage, options []string) error {
+	if len(options) == 0 || len(key) == 0 || string(key) == "null" {
+		return nil
+	}
+	var correct []string
+	switch questionType {
+	case TypeSingleChoice:
+		var k singleChoiceKey
+		if err := json.Unmarshal(key, &k); err != nil {
+			return err
+		}
+		correct = []string{OptionKey(k.Correct)}
+	case TypeMultiSelect:
+		var k multiSelectKey
+		if err := json.Unmarshal(key, &k); err != nil {
+			return err
+		}
+		correct = optionKeys(k.Correct)
+	default:
+		return nil
+	}
+
+	known := toSet(optionKeys(options))
+	for _, option := range correct {
+		if !known[option] {
+			return fmt.Errorf("answer_key references unknown option %q", option)
+		}
+	}
+	return nil
+}
+
+// Grade validates an answer payload against the question type and scores it.
+// A nil or empty key on a single-choice question uses the legacy rule (A and C are correct).
+func Grade(questionType string, key json.RawMessage, answer json.RawMessage) (*Result, error) {
+	if questionType == "" {
+		questionType = TypeSingleChoice
+	}
+	if len(answer) == 0 || string(answer) == "null" {
+		return nil, &InvalidAnswerError{QuestionType: questionType, Reason: "answer is required"}
+	}
+
+	switch questionType {
+	case TypeSingleChoice:
+		return gradeSingleChoice(key, answer)
+	case TypeMultiSelect:
+		return gradeMultiSelect(key, answer)
+	case TypeTrueFalse:
+		return gradeTrueFalse(key, answer)
+	case TypeNumeric:
+		return gradeNumeric(key, answer)
+	case TypeShortText:
+		return gradeShortText(key, answer)
+	case TypeOrdering:
+		return gradeOrdering(key, answer)
+	default:
+		return nil, fmt.Errorf("unknown question type: %s", questionType)
+	}
+}
+
+func gradeSingleChoice(key, answer json.RawMessage) (*Result, error) {
+	var choice string
+	if err := json.Unmarshal(answer, &choice); err != nil || strings.TrimSpace(choice) == "" {
+		return nil, &InvalidAnswerError{QuestionType: TypeSingleChoice, Reason: "expected a single option string"}
+	}
+	choice = OptionKey(choice)
+
+	var correct bool
+	if len(key) == 0 || string(key) == "null" {
+		// Legacy rule kept for questions registered before typed answer keys existed
+		correct = choice == "A" || choice == "C"
+	} else {
+		var k singleChoiceKey
+		if err := json.Unmarshal(key, &k); err != nil {
+			return nil, fmt.Errorf("malformed single_choice answer key: %v", err)
+		}
+		correct = choice == OptionKey(k.Correct)
+	}
+
+	return fullOrNothing(correct, choice), nil
+}
+
+func gradeMultiSelect(key, answer json.RawMessage) (*Result, error) {
+	var selected []string
+	if err := json.Unmarshal(answer, &selected); err != nil {
+		return nil, &InvalidAnswerError{QuestionType: TypeMultiSelect, Reason: "expected an array of option strings"}
+	}
+	selected = optionKeys(selected)
+	if len(selected) == 0 {
+		return nil, &InvalidAnswerError{QuestionType: TypeMultiSelect, Reason: "at least one option must be selected"}
+	}
+
+	var k multiSelectKey
+	if err := json.Unmarshal(key, &k); err != nil || len(k.Correct) == 0 {
+		return nil, fmt.Errorf("malformed multi_select answer key")
+	}
+	correctSet := toSet(optionKeys(k.Correct))
+
+	hits, misses := 0, 0
+	for _, option := range selected {
+		if correctSet[option] {
+			hits++
+		} else {
+			misses++
+		}
+	}
+
+	sorted := append([]string(nil), selected...)
+	sort.Strings(sorted)
+	canonical := strings.Join(sorted, ",")
+
+	if hits == len(correctSet) && misses == 0 {
+		return &Result{Score: 1, IsCorrect: true, Canonical: canonical}, nil
+	}
+	if !k.PartialCredit {
+		return &Result{Score: 0, IsCorrect: false, Canonical: canonical}, nil
+	}
+
+	// Each wrong selection cancels out one correct selection
+	score := math.Max(0, float64(hits-misses)/float64(len(correctSet)))
+	return &Result{Score: roundScore(score), IsCorrect: false, Canonical: canonical}, nil
+}
+
+func gradeTrueFalse(key, answer json.RawMessage) (*Result, error) {
+	var value bool
+	if err := json.Unmarshal(answer, &value); err != nil {
+		return nil, &InvalidAnswerError{QuestionType: TypeTrueFalse, Reason: "expected true or false"}
+	}
+
+	var k trueFalseKey
+	if err := json.Unmarshal(key, &k); err != nil || k.Correct == nil {
+		return nil, fmt.Errorf("malformed true_false answer key")
+	}
+
+	return fullOrNothing(value == *k.Correct, strconv.FormatBool(value)), nil
+}
+
+func gradeNumeric(key, answer json.RawMessage) (*Result, error) {
+	var value float64
+	if err := json.Unmarshal(answer, &value); err != nil {
+		return nil, &InvalidAnswerError{QuestionType: TypeNumeric, Reason: "expected a number"}
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return nil, &InvalidAnswerError{QuestionType: TypeNumeric, Reason: "number must be finite"}
+	}
+
+	var k numericKey
+	if err := json.Unmarshal(key, &k); err != nil || k.Value == nil {
+		return nil, fmt.Errorf("malformed numeric answer key")
+	}
+
+	correct := math.Abs(value-*k.Value) <= k.Tolerance
+	return fullOrNothing(correct, strconv.FormatFloat(value, 'f', -1, 64)), nil
+}
+
+func gradeShortText(key, answer json.RawMessage) (*Result, error) {
+	var text string
+	if err := json.Unmarshal(answer, &text); err != nil {
+		return nil, &InvalidAnswerError{QuestionType: TypeShortText, Reason: "expected a text string"}
+	}
+
+	var k shortTextKey
+	if err := json.Unmarshal(key, &k); err != nil || len(k.Accepted) == 0 {
+		return nil, fmt.Errorf("malformed short_text answer key")
+	}
+
+	normalized := NormalizeText(text, k.CaseSensitive)
+	if normalized == "" {
+		return nil, &InvalidAnswerError{QuestionType: TypeShortText, Reason: "answer text is empty"}
+	}
+
+	correct := false
+	for _, accepted := range k.Accepted {
+		if NormalizeText(accepted, k.CaseSensitive) == normalized {
+			correct = true
+			break
+		}
+	}
+
+	return fullOrNothing(correct, normalized), nil
+}
+
+func gradeOrdering(key, answer json.RawMessage) (*Result, error) {
+	var order []string
+	if err := json.Unmarshal(answer, &order); err != nil {
+		return nil, &InvalidAnswerError{QuestionType: TypeOrdering, Reason: "expected an array of item identifiers"}
+	}
+
+	var k orderingKey
+	if err := json.Unmarshal(key, &k); err != nil || len(k.Correct) == 0 {
+		return nil, fmt.Errorf("malformed ordering answer key")
+	}
+
+	// The submission must be a permutation of the expected items
+	if len(order) != len(k.Correct) || len(dedupe(order)) != len(order) {
+		return nil, &InvalidAnswerError{QuestionType: TypeOrdering, Reason: "answer must contain every item exactly once"}
+	}
+	expected := toSet(k.Correct)
+	for _, item := range order {
+		if !expected[item] {
+			return nil, &InvalidAnswerError{QuestionType: TypeOrdering, Reason: fmt.Sprintf("unknown item %q", item)}
+		}
+	}
+
+	inPlace := 0
+	for i := range order {
+		if order[i] == k.Correct[i] {
+			inPlace++
+		}
+	}
+
+	canonical := strings.Join(order, ">")
+	if inPlace == len(order) {
+		return &Result{Score: 1, IsCorrect: true, Canonical: canonical}, nil
+	}
+	if !k.PartialCredit {
+		return &Result{Score: 0, IsCorrect: false, Canonical: canonical}, nil
+	}
+	return &Result{Score: roundScore(float64(inPlace) / float64(len(order))), IsCorrect: false, Canonical: canonical}, nil
+}
+
+// NormalizeText trims, collapses whitespace and strips punctuation so that
+// "  Photo-synthesis! " and "photosynthesis" compare equal.
+func NormalizeText(text string, caseSensitive bool) string {
+	var b strings.Builder
+	lastSpace := false
+	for _, r := range strings.TrimSpace(text) {
+		switch {
+		case unicode.IsSpace(r):
+			if !lastSpace {
+				b.WriteRune(' ')
+			}
+			lastSpace = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			// dropped
+		default:
+			if !caseSensitive {
+				r = unicode.ToLower(r)
+			}
+			b.WriteRune(r)
+			lastSpace = false
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Helper functions
+func fullOrNothing(correct bool, canonical string) *Result {
+	if correct {
+		return &Result{Score: 1, IsCorrect: true, Canonical: canonical}
+	}
+	return &Result{Score: 0, IsCorrect: false, Canonical: canonical}
+}
+
+// OptionKey normalizes a choice option so that " a" and "A" name the same option; answers
+// are graded, stored and reported by it
+func OptionKey(option string) string {
+	return strings.ToUpper(strings.TrimSpace(option))
+}
+
+// optionKeys normalizes options, dropping empty and repeated ones
+func optionKeys(options []string) []string {
+	normalized := make([]string, len(options))
+	for i, option := range options {
+		normalized[i] = OptionKey(option)
+	}
+	return dedupe(normalized)
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var out []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v == "" || seen[v] {
+			continue
+		}
+		seen[v] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+func roundScore(score float64) float64 {
+	return math.Round(score*10000) / 10000
+}
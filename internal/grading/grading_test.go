@@ -0,0 +1,216 @@
+package grading
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type gradeCase struct {
+	name      string
+	key       string
+	answer    string
+	score     float64
+	correct   bool
+	canonical string
+	invalid   bool // the answer is rejected with an InvalidAnswerError
+}
+
+func runGradeCases(t *testing.T, questionType string, cases []gradeCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var key, answer json.RawMessage
+			if tc.key != "" {
+				key = json.RawMessage(tc.key)
+			}
+			if tc.answer != "" {
+				answer = json.RawMessage(tc.answer)
+			}
+
+			result, err := Grade(questionType, key, answer)
+			if tc.invalid {
+				var invalid *InvalidAnswerError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("expected an InvalidAnswerError, got result %+v, error %v", result, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Score != tc.score || result.IsCorrect != tc.correct || result.Canonical != tc.canonical {
+				t.Errorf("got score %v, correct %v, canonical %q; want %v, %v, %q",
+					result.Score, result.IsCorrect, result.Canonical, tc.score, tc.correct, tc.canonical)
+			}
+		})
+	}
+}
+
+func TestGradeSingleChoice(t *testing.T) {
+	runGradeCases(t, TypeSingleChoice, []gradeCase{
+		{name: "correct", key: `{"correct":"B"}`, answer: `"B"`, score: 1, correct: true, canonical: "B"},
+		{name: "wrong", key: `{"correct":"B"}`, answer: `"C"`, canonical: "C"},
+		{name: "case and whitespace", key: `{"correct":"B"}`, answer: `"  b "`, score: 1, correct: true, canonical: "B"},
+		{name: "key case and whitespace", key: `{"correct":" b"}`, answer: `"B"`, score: 1, correct: true, canonical: "B"},
+		{name: "legacy key", answer: `"C"`, score: 1, correct: true, canonical: "C"},
+		{name: "legacy key wrong", answer: `"B"`, canonical: "B"},
+		{name: "wrong type", key: `{"correct":"B"}`, answer: `["B"]`, invalid: true},
+		{name: "number", key: `{"correct":"B"}`, answer: `2`, invalid: true},
+		{name: "empty string", key: `{"correct":"B"}`, answer: `"   "`, invalid: true},
+		{name: "missing", key: `{"correct":"B"}`, invalid: true},
+		{name: "null", key: `{"correct":"B"}`, answer: `null`, invalid: true},
+	})
+}
+
+func TestGradeMultiSelect(t *testing.T) {
+	key := `{"correct":["A","C"]}`
+	partial := `{"correct":["A","C"],"partial_credit":true}`
+	runGradeCases(t, TypeMultiSelect, []gradeCase{
+		{name: "correct", key: key, answer: `["C","A"]`, score: 1, correct: true, canonical: "A,C"},
+		{name: "missing one", key: key, answer: `["A"]`, canonical: "A"},
+		{name: "partial credit", key: partial, answer: `["A"]`, score: 0.5, canonical: "A"},
+		{name: "wrong cancels right", key: partial, answer: `["A","B"]`, canonical: "A,B"},
+		{name: "extra option", key: key, answer: `["A","B","C"]`, canonical: "A,B,C"},
+		{name: "duplicate keys", key: key, answer: `["A","A","C"]`, score: 1, correct: true, canonical: "A,C"},
+		{name: "duplicates differing in case", key: partial, answer: `["a"," A","A"]`, score: 0.5, canonical: "A"},
+		{name: "case and whitespace", key: key, answer: `[" c","a "]`, score: 1, correct: true, canonical: "A,C"},
+		{name: "duplicate key entries", key: `{"correct":["A","a","C"]}`, answer: `["A","C"]`, score: 1, correct: true, canonical: "A,C"},
+		{name: "wrong type", key: key, answer: `"A"`, invalid: true},
+		{name: "empty array", key: key, answer: `[]`, invalid: true},
+		{name: "only blanks", key: key, answer: `[""," "]`, invalid: true},
+		{name: "missing", key: key, invalid: true},
+	})
+}
+
+func TestGradeTrueFalse(t *testing.T) {
+	key := `{"correct":false}`
+	runGradeCases(t, TypeTrueFalse, []gradeCase{
+		{name: "correct", key: key, answer: `false`, score: 1, correct: true, canonical: "false"},
+		{name: "wrong", key: key, answer: `true`, canonical: "true"},
+		{name: "wrong type", key: key, answer: `"false"`, invalid: true},
+		{name: "missing", key: key, invalid: true},
+	})
+}
+
+func TestGradeNumeric(t *testing.T) {
+	key := `{"value":3.14,"tolerance":0.01}`
+	runGradeCases(t, TypeNumeric, []gradeCase{
+		{name: "exact", key: key, answer: `3.14`, score: 1, correct: true, canonical: "3.14"},
+		{name: "within tolerance", key: key, answer: `3.145`, score: 1, correct: true, canonical: "3.145"},
+		{name: "outside tolerance", key: key, answer: `3.2`, canonical: "3.2"},
+		{name: "exact without tolerance", key: `{"value":42}`, answer: `42`, score: 1, correct: true, canonical: "42"},
+		{name: "wrong type", key: key, answer: `"3.14"`, invalid: true},
+		{name: "missing", key: key, invalid: true},
+	})
+}
+
+func TestGradeShortText(t *testing.T) {
+	key := `{"accepted":["Photosynthesis","light reaction"]}`
+	runGradeCases(t, TypeShortText, []gradeCase{
+		{name: "exact", key: key, answer: `"Photosynthesis"`, score: 1, correct: true, canonical: "photosynthesis"},
+		{name: "case whitespace and punctuation", key: key, answer: `"  LIGHT   reaction! "`, score: 1, correct: true, canonical: "light reaction"},
+		{name: "wrong", key: key, answer: `"respiration"`, canonical: "respiration"},
+		{name: "case sensitive", key: `{"accepted":["Paris"],"case_sensitive":true}`, answer: `"paris"`, canonical: "paris"},
+		{name: "case sensitive whitespace", key: `{"accepted":["Paris"],"case_sensitive":true}`, answer: `" Paris "`, score: 1, correct: true, canonical: "Paris"},
+		{name: "wrong type", key: key, answer: `42`, invalid: true},
+		{name: "only punctuation", key: key, answer: `" ?! "`, invalid: true},
+		{name: "missing", key: key, invalid: true},
+	})
+}
+
+func TestGradeOrdering(t *testing.T) {
+	key := `{"correct":["a","b","c","d"]}`
+	partial := `{"correct":["a","b","c","d"],"partial_credit":true}`
+	runGradeCases(t, TypeOrdering, []gradeCase{
+		{name: "correct", key: key, answer: `["a","b","c","d"]`, score: 1, correct: true, canonical: "a>b>c>d"},
+		{name: "swapped", key: key, answer: `["a","b","d","c"]`, canonical: "a>b>d>c"},
+		{name: "partial credit", key: partial, answer: `["a","b","d","c"]`, score: 0.5, canonical: "a>b>d>c"},
+		{name: "duplicate item", key: key, answer: `["a","a","c","d"]`, invalid: true},
+		{name: "unknown item", key: key, answer: `["a","b","c","e"]`, invalid: true},
+		{name: "too short", key: key, answer: `["a","b","c"]`, invalid: true},
+		{name: "wrong type", key: key, answer: `"a,b,c,d"`, invalid: true},
+		{name: "missing", key: key, invalid: true},
+	})
+}
+
+func TestValidateOptions(t *testing.T) {
+	options := []string{"A", "B", "C"}
+	cases := []struct {
+		name         string
+		questionType string
+		answer       string
+		valid        bool
+	}{
+		{"single choice", TypeSingleChoice, `"B"`, true},
+		{"single choice case and whitespace", TypeSingleChoice, `" b "`, true},
+		{"single choice unknown", TypeSingleChoice, `"D"`, false},
+		{"single choice wrong type", TypeSingleChoice, `["B"]`, false},
+		{"multi select", TypeMultiSelect, `["A","C"]`, true},
+		{"multi select case and whitespace", TypeMultiSelect, `[" a","c "]`, true},
+		{"multi select duplicates", TypeMultiSelect, `["A","a","A"]`, true},
+		{"multi select unknown", TypeMultiSelect, `["A","D"]`, false},
+		{"multi select wrong type", TypeMultiSelect, `"A"`, false},
+		{"no options for type", TypeNumeric, `42`, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateOptions(tc.questionType, json.RawMessage(tc.answer), options)
+			if tc.valid && err != nil {
+				t.Errorf("expected valid, got %v", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+// Every answer that passes option validation must grade without an InvalidAnswerError
+func TestValidateOptionsMatchesGrader(t *testing.T) {
+	options := []string{"A", "B", "C"}
+	cases := []struct {
+		questionType string
+		key          string
+		answer       string
+	}{
+		{TypeSingleChoice, `{"correct":"a"}`, `" A"`},
+		{TypeMultiSelect, `{"correct":["a","c"]}`, `["A"," c","C"]`},
+	}
+	for _, tc := range cases {
+		if err := ValidateKeyOptions(tc.questionType, json.RawMessage(tc.key), options); err != nil {
+			t.Errorf("%s key %s: %v", tc.questionType, tc.key, err)
+		}
+		if err := ValidateOptions(tc.questionType, json.RawMessage(tc.answer), options); err != nil {
+			t.Errorf("%s answer %s: %v", tc.questionType, tc.answer, err)
+		}
+		result, err := Grade(tc.questionType, json.RawMessage(tc.key), json.RawMessage(tc.answer))
+		if err != nil || !result.IsCorrect {
+			t.Errorf("%s answer %s: got %+v, %v; want full credit", tc.questionType, tc.answer, result, err)
+		}
+	}
+}
+
+func TestValidateAnswerKey(t *testing.T) {
+	cases := []struct {
+		questionType string
+		key          string
+		valid        bool
+	}{
+		{TypeSingleChoice, ``, true},
+		{TypeSingleChoice, `{"correct":""}`, false},
+		{TypeMultiSelect, `{"correct":[]}`, false},
+		{TypeTrueFalse, `{"correct":true}`, true},
+		{TypeTrueFalse, `{}`, false},
+		{TypeNumeric, `{"value":1,"tolerance":-1}`, false},
+		{TypeShortText, `{"accepted":["x"]}`, true},
+		{TypeOrdering, `{"correct":["a"]}`, false},
+		{"essay", `{}`, false},
+	}
+	for _, tc := range cases {
+		err := ValidateAnswerKey(tc.questionType, json.RawMessage(tc.key))
+		if tc.valid != (err == nil) {
+			t.Errorf("%s key %q: got error %v, want valid %v", tc.questionType, tc.key, err, tc.valid)
+		}
+	}
+}
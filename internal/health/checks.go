@@ -0,0 +1,48 @@
+package health
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Postgres checks that the database answers a ping
+func Postgres(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+// Migrations checks that the schema, as recorded by golang-migrate, is clean and at
+// least at the version the code expects
+func Migrations(db *gorm.DB, required uint) Check {
+	return func(ctx context.Context) error {
+		var state struct {
+			Version uint
+			Dirty   bool
+		}
+		err := db.WithContext(ctx).Raw("SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&state).Error
+		if err != nil {
+			return fmt.Errorf("failed to read migration version: %w", err)
+		}
+		if state.Dirty {
+			return fmt.Errorf("migration %d failed halfway and needs fixing", state.Version)
+		}
+		if state.Version < required {
+			return fmt.Errorf("schema is at migration %d, need %d", state.Version, required)
+		}
+		return nil
+	}
+}
+
+// Pinger adapts a dependency with a Ping method, such as the Kafka producer or consumer
+func Pinger(ping func() error) Check {
+	return func(context.Context) error {
+		return ping()
+	}
+}
@@ -0,0 +1,53 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	monitor *Monitor
+}
+
+func NewHandler(m *Monitor) *Handler {
+	return &Handler{monitor: m}
+}
+
+// Liveness handles GET /healthz: the process is up and serving, whatever its dependencies
+func (h *Handler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readiness handles GET /readyz with 503 while any dependency fails its check
+func (h *Handler) Readiness(c *gin.Context) {
+	statuses, healthy := h.monitor.Run(c.Request.Context())
+	checks := make(map[string]string, len(statuses))
+	for _, status := range statuses {
+		checks[status.Name] = "ok"
+		if !status.Healthy {
+			checks[status.Name] = status.Error
+		}
+	}
+
+	if !healthy {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
+
+// Status handles GET /status with each dependency's latency and last error, for admins
+func (h *Handler) Status(c *gin.Context) {
+	statuses, healthy := h.monitor.Run(c.Request.Context())
+	status := "ready"
+	if !healthy {
+		status = "not ready"
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":         status,
+		"uptime_seconds": int(h.monitor.Uptime().Seconds()),
+		"dependencies":   statuses,
+	})
+}
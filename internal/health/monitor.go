@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Check reports whether a dependency is usable
+type Check func(ctx context.Context) error
+
+// Monitor runs the checks of a process's dependencies and remembers each one's last error
+type Monitor struct {
+	mu           sync.Mutex
+	dependencies []*dependency
+	// timeout bounds each check, including checks that ignore their context
+	timeout time.Duration
+	started time.Time
+}
+
+type dependency struct {
+	name   string
+	check  Check
+	status DependencyStatus
+}
+
+// DependencyStatus is the outcome of a dependency's latest check
+type DependencyStatus struct {
+	Name      string    `json:"name"`
+	Healthy   bool      `json:"healthy"`
+	LatencyMS float64   `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+	Error     string    `json:"error,omitempty"`
+	// LastError is kept after the dependency recovers
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+func NewMonitor(timeout time.Duration) *Monitor {
+	return &Monitor{timeout: timeout, started: time.Now()}
+}
+
+// Add registers a dependency; all dependencies must pass for the process to be ready
+func (m *Monitor) Add(name string, check Check) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.dependencies = append(m.dependencies, &dependency{name: name, check: check, status: DependencyStatus{Name: name}})
+}
+
+// Run checks all dependencies concurrently and reports whether they are all healthy
+func (m *Monitor) Run(ctx context.Context) ([]DependencyStatus, bool) {
+	m.mu.Lock()
+	dependencies := append([]*dependency(nil), m.dependencies...)
+	m.mu.Unlock()
+
+	errs := make([]error, len(dependencies))
+	latencies := make([]time.Duration, len(dependencies))
+	var wg sync.WaitGroup
+	for i, dep := range dependencies {
+		wg.Add(1)
+		go func(i int, dep *dependency) {
+			defer wg.Done()
+			start := time.Now()
+			errs[i] = m.runCheck(ctx, dep.check)
+			latencies[i] = time.Since(start)
+		}(i, dep)
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	healthy := true
+	statuses := make([]DependencyStatus, len(dependencies))
+	for i, dep := range dependencies {
+		dep.status.Healthy = errs[i] == nil
+		dep.status.LatencyMS = float64(latencies[i].Microseconds()) / 1000
+		dep.status.CheckedAt = now
+		dep.status.Error = ""
+		if errs[i] != nil {
+			healthy = false
+			checkedAt := now
+			dep.status.Error = errs[i].Error()
+			dep.status.LastError = dep.status.Error
+			dep.status.LastErrorAt = &checkedAt
+		}
+		statuses[i] = dep.status
+	}
+	return statuses, healthy
+}
+
+// Uptime is how long ago the monitor was created, at process start
+func (m *Monitor) Uptime() time.Duration {
+	return time.Since(m.started)
+}
+
+// runCheck gives up after the timeout even when the check does not watch its context
+func (m *Monitor) runCheck(ctx context.Context, check Check) error {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- check(ctx) }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return errors.New("check timed out after " + m.timeout.String())
+		}
+		return ctx.Err()
+	}
+}
@@ -4,67 +4,97 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/logging"
+	"github.com/rohanreddymelachervu/ingestor/internal/metrics"
 	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Consumer struct {
+	client        sarama.Client
 	consumerGroup sarama.ConsumerGroup
 	topics        []string
-	eventService  EventProcessor
+	processorFor  ProcessorResolver
 	ready         chan bool
+	// inSession is set while the consumer is a member of the group, between Setup and Cleanup
+	inSession atomic.Bool
 }
 
 // EventProcessor interface to avoid circular imports
 type EventProcessor interface {
-	ProcessEvent(event models.EventPayload, userID interface{}) error
+	ProcessEvent(event models.EventPayload, ingestedBy string) error
 }
 
+// ProcessorResolver returns the processor that records events for a tenant; its database
+// calls should run under ctx, which carries the span of the message
+type ProcessorResolver func(ctx context.Context, tenantID uuid.UUID) EventProcessor
+
 type ConsumerGroupHandler struct {
-	eventService EventProcessor
+	processorFor ProcessorResolver
 	ready        chan bool
 	once         sync.Once
+	inSession    *atomic.Bool
 }
 
-func NewConsumer(brokers []string, groupID string, topics []string, eventService EventProcessor) (*Consumer, error) {
-	config := sarama.NewConfig()
+// NewConsumer creates a member of the configured consumer group
+func NewConsumer(cfg config.KafkaConfig, processorFor ProcessorResolver) (*Consumer, error) {
+	saramaConfig := sarama.NewConfig()
 
 	// Consumer settings for reliability
-	config.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
-	config.Consumer.Offsets.Initial = sarama.OffsetOldest
-	config.Consumer.Group.Session.Timeout = 10 * time.Second
-	config.Consumer.Group.Heartbeat.Interval = 3 * time.Second
-	config.Consumer.MaxProcessingTime = 1 * time.Minute
+	saramaConfig.Consumer.Group.Rebalance.Strategy = sarama.BalanceStrategyRoundRobin
+	saramaConfig.Consumer.Offsets.Initial = sarama.OffsetOldest
+	if cfg.Consumer.InitialOffset == config.OffsetNewest {
+		saramaConfig.Consumer.Offsets.Initial = sarama.OffsetNewest
+	}
+	// heartbeats must come well within the session timeout; sarama asks for a third
+	saramaConfig.Consumer.Group.Session.Timeout = cfg.Consumer.SessionTimeout
+	saramaConfig.Consumer.Group.Heartbeat.Interval = cfg.Consumer.SessionTimeout / 3
+	saramaConfig.Consumer.MaxProcessingTime = cfg.Consumer.MaxProcessingTime
 
 	// Enable auto-commit for simplicity
-	config.Consumer.Offsets.AutoCommit.Enable = true
-	config.Consumer.Offsets.AutoCommit.Interval = 1 * time.Second
+	saramaConfig.Consumer.Offsets.AutoCommit.Enable = true
+	saramaConfig.Consumer.Offsets.AutoCommit.Interval = 1 * time.Second
 
-	// Create consumer group
-	consumerGroup, err := sarama.NewConsumerGroup(brokers, groupID, config)
+	// Create consumer group; its client is kept so that Ping can check the connection
+	client, err := sarama.NewClient(cfg.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create consumer group: %w", err)
+	}
+	consumerGroup, err := sarama.NewConsumerGroupFromClient(cfg.Consumer.GroupID, client)
 	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to create consumer group: %w", err)
 	}
 
 	return &Consumer{
+		client:        client,
 		consumerGroup: consumerGroup,
-		topics:        topics,
-		eventService:  eventService,
+		topics:        cfg.Consumer.Topics,
+		processorFor:  processorFor,
 		ready:         make(chan bool),
 	}, nil
 }
 
 func (c *Consumer) Start(ctx context.Context) error {
 	handler := &ConsumerGroupHandler{
-		eventService: c.eventService,
+		processorFor: c.processorFor,
 		ready:        c.ready,
+		inSession:    &c.inSession,
 	}
 
 	wg := &sync.WaitGroup{}
@@ -82,7 +112,7 @@ func (c *Consumer) Start(ctx context.Context) error {
 			// server-side rebalance happens, the consumer session will need to be
 			// recreated to get the new claims
 			if err := c.consumerGroup.Consume(ctx, c.topics, handler); err != nil {
-				log.Printf("Error from consumer: %v", err)
+				slog.Error("consumer group failed", "error", err)
 				return
 			}
 		}
@@ -90,7 +120,7 @@ func (c *Consumer) Start(ctx context.Context) error {
 
 	// Wait till consumer is ready
 	<-c.ready
-	log.Println("Kafka consumer up and running....")
+	slog.Info("Kafka consumer running", "topics", c.topics)
 
 	// Listen for termination signals
 	sigterm := make(chan os.Signal, 1)
@@ -98,17 +128,34 @@ func (c *Consumer) Start(ctx context.Context) error {
 
 	select {
 	case <-ctx.Done():
-		log.Println("Context cancelled, terminating consumer")
+		slog.Info("context cancelled, stopping consumer")
 	case <-sigterm:
-		log.Println("Termination signal received, terminating consumer")
+		slog.Info("termination signal received, stopping consumer")
 	}
 
 	wg.Wait()
-	return c.consumerGroup.Close()
+	if err := c.consumerGroup.Close(); err != nil {
+		c.client.Close()
+		return err
+	}
+	return c.client.Close()
+}
+
+// Ping checks that a broker answers with the metadata of the topics and that the
+// consumer is a member of its group
+func (c *Consumer) Ping() error {
+	if err := c.client.RefreshMetadata(c.topics...); err != nil {
+		return err
+	}
+	if !c.inSession.Load() {
+		return fmt.Errorf("not a member of the consumer group")
+	}
+	return nil
 }
 
 // Setup is run at the beginning of a new session, before ConsumeClaim
 func (h *ConsumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
+	h.inSession.Store(true)
 	// Mark the consumer as ready (only once)
 	h.once.Do(func() {
 		close(h.ready)
@@ -118,6 +165,7 @@ func (h *ConsumerGroupHandler) Setup(sarama.ConsumerGroupSession) error {
 
 // Cleanup is run at the end of a session, once all ConsumeClaim goroutines have exited
 func (h *ConsumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	h.inSession.Store(false)
 	return nil
 }
 
@@ -132,13 +180,11 @@ func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 				return nil
 			}
 
-			log.Printf("Message claimed: value = %s, timestamp = %v, topic = %s, partition = %d, offset = %d",
-				string(message.Value), message.Timestamp, message.Topic, message.Partition, message.Offset)
-
-			if err := h.processMessage(message); err != nil {
-				log.Printf("Error processing message: %v", err)
-				// Continue processing other messages even if one fails
-			}
+			// Failures are logged and counted; the following messages are still processed
+			h.handleMessage(session.Context(), message)
+			// the high water mark is the offset of the next message to be produced
+			metrics.ConsumerLag.WithLabelValues(message.Topic, strconv.Itoa(int(message.Partition))).
+				Set(float64(claim.HighWaterMarkOffset() - message.Offset - 1))
 
 			// Mark message as processed
 			session.MarkMessage(message, "")
@@ -149,13 +195,50 @@ func (h *ConsumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession,
 	}
 }
 
-func (h *ConsumerGroupHandler) processMessage(message *sarama.ConsumerMessage) error {
-	// Parse the Kafka message
+// handleMessage processes a message in a span continuing the trace of the request that published it.
+// Its log records carry the event's IDs once the message is decoded; the payload is only
+// logged at debug level, with the redacted fields removed.
+func (h *ConsumerGroupHandler) handleMessage(ctx context.Context, message *sarama.ConsumerMessage) {
+	ctx = otel.GetTextMapPropagator().Extract(ctx, consumerHeaders{message})
+	ctx, span := tracer.Start(ctx, "kafka.process "+message.Topic, trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", message.Topic),
+			attribute.Int("messaging.kafka.partition", int(message.Partition)),
+			attribute.Int64("messaging.kafka.offset", message.Offset),
+		))
+	defer span.End()
+	ctx = logging.With(ctx,
+		slog.String("topic", message.Topic),
+		slog.Int("partition", int(message.Partition)),
+		slog.Int64("offset", message.Offset),
+	)
+	slog.DebugContext(ctx, "message claimed", "message", logging.JSON(message.Value))
+
 	var eventMessage EventMessage
-	if err := json.Unmarshal(message.Value, &eventMessage); err != nil {
-		return fmt.Errorf("failed to unmarshal event message: %w", err)
+	err := json.Unmarshal(message.Value, &eventMessage)
+	if err != nil {
+		err = &decodeError{fmt.Errorf("failed to unmarshal event message: %w", err)}
+	} else {
+		ctx = logging.With(ctx,
+			slog.String("event_id", eventMessage.EventID),
+			slog.String("event_type", eventMessage.EventType),
+			slog.String("session_id", eventMessage.SessionID),
+		)
+		err = h.processMessage(ctx, message, eventMessage)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to process message", "outcome", metrics.ErrorClass(err), "error", err)
+		metrics.ConsumerMessages.WithLabelValues(metrics.ErrorClass(err)).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, metrics.ErrorClass(err))
+		return
 	}
+	slog.DebugContext(ctx, "event processed")
+	metrics.ConsumerMessages.WithLabelValues("processed").Inc()
+}
 
+func (h *ConsumerGroupHandler) processMessage(ctx context.Context, message *sarama.ConsumerMessage, eventMessage EventMessage) error {
 	// Convert to original EventPayload format
 	payloadBytes, err := json.Marshal(eventMessage.Payload)
 	if err != nil {
@@ -164,14 +247,45 @@ func (h *ConsumerGroupHandler) processMessage(message *sarama.ConsumerMessage) e
 
 	var eventPayload models.EventPayload
 	if err := json.Unmarshal(payloadBytes, &eventPayload); err != nil {
-		return fmt.Errorf("failed to unmarshal event payload: %w", err)
+		return &decodeError{fmt.Errorf("failed to unmarshal event payload: %w", err)}
+	}
+
+	tenantID := models.DefaultTenantID
+	if eventMessage.TenantID != "" {
+		tenantID, err = uuid.Parse(eventMessage.TenantID)
+		if err != nil {
+			return &decodeError{fmt.Errorf("invalid tenant_id %q: %w", eventMessage.TenantID, err)}
+		}
 	}
 
 	// Process using existing business logic
-	if err := h.eventService.ProcessEvent(eventPayload, nil); err != nil {
+	if err := h.processorFor(ctx, tenantID).ProcessEvent(eventPayload, ingestedBy(message, eventMessage)); err != nil {
 		return fmt.Errorf("failed to process event: %w", err)
 	}
-
-	log.Printf("Successfully processed event: %s (type: %s)", eventMessage.EventID, eventMessage.EventType)
 	return nil
 }
+
+// decodeError marks a message that cannot be read as an event
+type decodeError struct {
+	err error
+}
+
+func (e *decodeError) Error() string {
+	return e.err.Error()
+}
+
+func (e *decodeError) Unwrap() error {
+	return e.err
+}
+
+func (e *decodeError) ErrorClass() string {
+	return "decode"
+}
+
+// ingestedBy returns who sent the event, from the message body or else its ingested_by header
+func ingestedBy(message *sarama.ConsumerMessage, eventMessage EventMessage) string {
+	if eventMessage.IngestedBy != "" {
+		return eventMessage.IngestedBy
+	}
+	return consumerHeaders{message}.Get("ingested_by")
+}
@@ -0,0 +1,62 @@
+package kafka
+
+import "github.com/IBM/sarama"
+
+// producerHeaders lets the trace context propagator write into an outgoing message's headers
+type producerHeaders struct {
+	message *sarama.ProducerMessage
+}
+
+func (h producerHeaders) Get(key string) string {
+	for _, header := range h.message.Headers {
+		if string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+func (h producerHeaders) Set(key, value string) {
+	for i, header := range h.message.Headers {
+		if string(header.Key) == key {
+			h.message.Headers[i].Value = []byte(value)
+			return
+		}
+	}
+	h.message.Headers = append(h.message.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+func (h producerHeaders) Keys() []string {
+	keys := make([]string, len(h.message.Headers))
+	for i, header := range h.message.Headers {
+		keys[i] = string(header.Key)
+	}
+	return keys
+}
+
+// consumerHeaders lets the trace context propagator read a consumed message's headers
+type consumerHeaders struct {
+	message *sarama.ConsumerMessage
+}
+
+func (h consumerHeaders) Get(key string) string {
+	for _, header := range h.message.Headers {
+		if header != nil && string(header.Key) == key {
+			return string(header.Value)
+		}
+	}
+	return ""
+}
+
+// Set is never called when extracting
+func (h consumerHeaders) Set(string, string) {}
+
+func (h consumerHeaders) Keys() []string {
+	keys := make([]string, 0, len(h.message.Headers))
+	for _, header := range h.message.Headers {
+		if header != nil {
+			keys = append(keys, string(header.Key))
+		}
+	}
+	return keys
+}
@@ -1,64 +1,99 @@
 package kafka
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/IBM/sarama"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Producer struct {
+	client       sarama.Client
 	syncProducer sarama.SyncProducer
 	topicName    string
 }
 
 type EventMessage struct {
-	EventID   string      `json:"event_id"`
-	EventType string      `json:"event_type"`
-	SessionID string      `json:"session_id"`
-	Timestamp time.Time   `json:"timestamp"`
-	Payload   interface{} `json:"payload"`
+	// TenantID is empty for messages published before tenancy; they belong to the default tenant
+	TenantID string `json:"tenant_id,omitempty"`
+	// IngestedBy names who sent the event, e.g. user:42 or key:<api_key_id>; empty for
+	// messages published before it was recorded
+	IngestedBy string      `json:"ingested_by,omitempty"`
+	EventID    string      `json:"event_id"`
+	EventType  string      `json:"event_type"`
+	SessionID  string      `json:"session_id"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Payload    interface{} `json:"payload"`
 }
 
-func NewProducer(brokers []string, topicName string) (*Producer, error) {
-	config := sarama.NewConfig()
+// requiredAcks maps the configured acknowledgement levels to sarama's
+var requiredAcks = map[string]sarama.RequiredAcks{
+	config.AcksNone:   sarama.NoResponse,
+	config.AcksLeader: sarama.WaitForLocal,
+	config.AcksAll:    sarama.WaitForAll,
+}
 
-	// Producer settings for reliability
-	config.Producer.RequiredAcks = sarama.WaitForAll // Wait for all replicas
-	config.Producer.Retry.Max = 5                    // Retry failed sends
-	config.Producer.Return.Successes = true
-	config.Producer.Return.Errors = true
+// NewProducer creates a producer publishing to the configured topic
+func NewProducer(cfg config.KafkaConfig) (*Producer, error) {
+	saramaConfig := sarama.NewConfig()
 
-	// Use session_id as partition key for ordering
-	config.Producer.Partitioner = sarama.NewHashPartitioner
+	// Producer settings for reliability; waiting for all replicas by default
+	saramaConfig.Producer.RequiredAcks = requiredAcks[cfg.Producer.RequiredAcks]
+	saramaConfig.Producer.Retry.Max = cfg.Producer.MaxRetries
+	saramaConfig.Producer.Return.Successes = true
+	saramaConfig.Producer.Return.Errors = true
 
-	// Compression for better throughput
-	config.Producer.Compression = sarama.CompressionSnappy
+	// Use session_id as partition key for ordering
+	saramaConfig.Producer.Partitioner = sarama.NewHashPartitioner
 
-	// Batching for better performance
-	config.Producer.Flush.Frequency = 100 * time.Millisecond
-	config.Producer.Flush.Messages = 100
+	// Compression and batching for better throughput
+	if err := saramaConfig.Producer.Compression.UnmarshalText([]byte(cfg.Producer.Compression)); err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+	saramaConfig.Producer.Flush.Frequency = cfg.Producer.FlushFrequency
+	saramaConfig.Producer.Flush.Messages = cfg.Producer.FlushMessages
 
-	producer, err := sarama.NewSyncProducer(brokers, config)
+	// The producer keeps its client so that Ping can check the connection
+	client, err := sarama.NewClient(cfg.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
 	if err != nil {
+		client.Close()
 		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
 	}
 
 	return &Producer{
+		client:       client,
 		syncProducer: producer,
-		topicName:    topicName,
+		topicName:    cfg.Topic,
 	}, nil
 }
 
-func (p *Producer) PublishEvent(eventID, eventType, sessionID string, payload interface{}) error {
+// tracer covers publishing and consuming; the trace context travels in record headers
+var tracer = otel.Tracer("github.com/rohanreddymelachervu/ingestor/internal/kafka")
+
+// PublishEvent sends the event to the topic, carrying the trace context of ctx in its headers
+func (p *Producer) PublishEvent(ctx context.Context, tenantID uuid.UUID, ingestedBy, eventID, eventType, sessionID string, payload interface{}) error {
 	message := EventMessage{
-		EventID:   eventID,
-		EventType: eventType,
-		SessionID: sessionID,
-		Timestamp: time.Now(),
-		Payload:   payload,
+		TenantID:   tenantID.String(),
+		IngestedBy: ingestedBy,
+		EventID:    eventID,
+		EventType:  eventType,
+		SessionID:  sessionID,
+		Timestamp:  time.Now(),
+		Payload:    payload,
 	}
 
 	messageBytes, err := json.Marshal(message)
@@ -75,23 +110,54 @@ func (p *Producer) PublishEvent(eventID, eventType, sessionID string, payload in
 				Key:   []byte("event_type"),
 				Value: []byte(eventType),
 			},
+			{
+				Key:   []byte("ingested_by"),
+				Value: []byte(ingestedBy),
+			},
 		},
 	}
 
+	ctx, span := tracer.Start(ctx, "kafka.publish "+p.topicName, trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", p.topicName),
+			attribute.String("event.id", eventID),
+			attribute.String("event.type", eventType),
+		))
+	defer span.End()
+	otel.GetTextMapPropagator().Inject(ctx, producerHeaders{kafkaMessage})
+
+	start := time.Now()
 	partition, offset, err := p.syncProducer.SendMessage(kafkaMessage)
+	metrics.KafkaPublishDuration.WithLabelValues(p.topicName).Observe(time.Since(start).Seconds())
 	if err != nil {
+		metrics.KafkaPublishFailures.WithLabelValues(p.topicName).Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "publish failed")
 		return fmt.Errorf("failed to send message to Kafka: %w", err)
 	}
+	span.SetAttributes(
+		attribute.Int("messaging.kafka.partition", int(partition)),
+		attribute.Int64("messaging.kafka.offset", offset),
+	)
 
-	log.Printf("Event published successfully - Topic: %s, Partition: %d, Offset: %d, EventID: %s",
-		p.topicName, partition, offset, eventID)
+	slog.DebugContext(ctx, "event published", "topic", p.topicName, "partition", partition, "offset", offset)
 
 	return nil
 }
 
+// Ping checks that a broker answers with the metadata of the topic
+func (p *Producer) Ping() error {
+	return p.client.RefreshMetadata(p.topicName)
+}
+
+// Close flushes pending messages and closes the producer and its client
 func (p *Producer) Close() error {
 	if p.syncProducer != nil {
-		return p.syncProducer.Close()
+		if err := p.syncProducer.Close(); err != nil {
+			p.client.Close()
+			return err
+		}
 	}
-	return nil
+	return p.client.Close()
 }
@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Redacted replaces the value of redacted fields
+const Redacted = "[REDACTED]"
+
+// redacted holds the configured redact fields; Setup sets it before anything logs
+var redacted = map[string]bool{}
+
+// Setup makes a structured logger writing to stdout the default for slog and for the
+// standard log package, whose lines become info records
+func Setup(cfg config.LoggingConfig) *slog.Logger {
+	logger := New(os.Stdout, cfg)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// New returns a logger that adds the attributes of the context to each record and
+// redacts the configured fields
+func New(w io.Writer, cfg config.LoggingConfig) *slog.Logger {
+	redacted = make(map[string]bool, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redacted[field] = true
+	}
+
+	opts := &slog.HandlerOptions{
+		Level: cfg.Level,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if redacted[a.Key] {
+				return slog.String(a.Key, Redacted)
+			}
+			return a
+		},
+	}
+	var handler slog.Handler = slog.NewJSONHandler(w, opts)
+	if cfg.Format == config.LogText {
+		handler = slog.NewTextHandler(w, opts)
+	}
+	return slog.New(contextHandler{handler})
+}
+
+// Fatal logs an error and exits, like log.Fatal
+func Fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
+}
+
+type attrsKey struct{}
+
+// With returns a context whose log records carry attrs, after those already on ctx.
+// Records must be logged with the context, e.g. through slog.InfoContext.
+func With(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	combined := make([]slog.Attr, 0, len(existing)+len(attrs))
+	combined = append(append(combined, existing...), attrs...)
+	return context.WithValue(ctx, attrsKey{}, combined)
+}
+
+// contextHandler adds the attributes stored by With and the current trace and span IDs
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		r.AddAttrs(attrs...)
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// JSON logs a JSON document with the values of redacted keys replaced at any depth;
+// documents that do not parse are logged by size only
+func JSON(raw []byte) slog.LogValuer {
+	return jsonValue(raw)
+}
+
+type jsonValue []byte
+
+func (v jsonValue) LogValue() slog.Value {
+	var document interface{}
+	if err := json.Unmarshal(v, &document); err != nil {
+		return slog.GroupValue(slog.Int("invalid_json_bytes", len(v)))
+	}
+	return slog.AnyValue(redact(document))
+}
+
+func redact(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			if redacted[key] {
+				value[key] = Redacted
+			} else {
+				value[key] = redact(nested)
+			}
+		}
+	case []interface{}:
+		for i, nested := range value {
+			value[i] = redact(nested)
+		}
+	}
+	return value
+}
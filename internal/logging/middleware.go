@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+)
+
+// RequestIDHeader carries the request ID in both directions; a client or proxy may set it
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware gives each request an ID, stores it in the request context for the log
+// records of the handlers, and logs the request once it completes. Requests to quiet
+// paths, such as probes, are only logged at debug level.
+func Middleware(quiet map[string]bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.NewString()
+		}
+		c.Header(RequestIDHeader, requestID)
+		ctx := With(c.Request.Context(), slog.String("request_id", requestID))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case quiet[c.Request.URL.Path]:
+			level = slog.LevelDebug
+		}
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("duration_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("response_bytes", max(c.Writer.Size(), 0)),
+			slog.String("caller", auth.Caller(c)),
+		}
+		if tenantID := auth.TenantID(c); tenantID != uuid.Nil {
+			attrs = append(attrs, slog.String("tenant_id", tenantID.String()))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+		slog.LogAttrs(ctx, level, "request", attrs...)
+	}
+}
+
+// validRequestID accepts IDs of up to 128 letters, digits and -_.: so that a client
+// cannot inject arbitrary text into the logs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-' || r == '_' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingestor_http_requests_total",
+		Help: "HTTP requests by route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ingestor_http_request_duration_seconds",
+		Help:    "HTTP request latency by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	// EventsIngested counts events received over HTTP by type and outcome: processed,
+	// queued (published to Kafka) or the class of the error that rejected them
+	EventsIngested = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingestor_events_ingested_total",
+		Help: "Events received over HTTP by event type and outcome.",
+	}, []string{"event_type", "outcome"})
+
+	KafkaPublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ingestor_kafka_publish_duration_seconds",
+		Help:    "Latency of publishing an event to Kafka.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"topic"})
+
+	KafkaPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingestor_kafka_publish_failures_total",
+		Help: "Events that could not be published to Kafka.",
+	}, []string{"topic"})
+
+	// ConsumerLag is how many messages of a partition are behind the one being processed
+	ConsumerLag = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ingestor_kafka_consumer_lag",
+		Help: "Messages not yet consumed, per topic partition.",
+	}, []string{"topic", "partition"})
+
+	ConsumerMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ingestor_kafka_consumer_messages_total",
+		Help: "Messages consumed by outcome: processed or the class of the error.",
+	}, []string{"outcome"})
+
+	QueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ingestor_generic_query_duration_seconds",
+		Help:    "Execution time of generic analytics queries.",
+		Buckets: []float64{.01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+	}, []string{"outcome"})
+)
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() gin.HandlerFunc {
+	return gin.WrapH(promhttp.Handler())
+}
+
+// Middleware counts and times requests by route template, so that IDs in paths do not
+// create a series each
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RegisterDBStats exports the connection pool statistics of the database
+func RegisterDBStats(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, "postgres"))
+}
+
+// ErrorClass labels an error by its ErrorClass method, e.g. validation or
+// missing_reference; errors without one are internal
+func ErrorClass(err error) string {
+	var classified interface{ ErrorClass() string }
+	if errors.As(err, &classified) {
+		return classified.ErrorClass()
+	}
+	return "internal"
+}
@@ -0,0 +1,283 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// lockKey is the Postgres advisory lock held while migrating, so that two deploys
+// starting at once apply each migration only once
+const lockKey int64 = 0x696e676573746f72 // "ingestor"
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one numbered step of migrations/postgres
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State is the version recorded in schema_migrations. The table is the one golang-migrate
+// keeps, so databases migrated by its CLI carry on from where they are. Dirty means a
+// migration failed halfway and the schema needs fixing by hand.
+type State struct {
+	Version uint
+	Dirty   bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New reads the migrations in files, which must pair an up and a down file for every
+// version
+func New(db *sql.DB, files fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+
+	byVersion := map[uint]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || version == 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(files, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		m := byVersion[uint(version)]
+		if m == nil {
+			m = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[m.Version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", m.Version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrator := &Migrator{db: db}
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %06d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+		migrator.migrations = append(migrator.migrations, *m)
+	}
+	sort.Slice(migrator.migrations, func(i, j int) bool {
+		return migrator.migrations[i].Version < migrator.migrations[j].Version
+	})
+	return migrator, nil
+}
+
+// Latest returns the version of the last migration, or 0 if there are none
+func (m *Migrator) Latest() uint {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status returns the recorded state and the migrations not yet applied
+func (m *Migrator) Status(ctx context.Context) (State, []Migration, error) {
+	var exists bool
+	if err := m.db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists); err != nil {
+		return State{}, nil, fmt.Errorf("failed to look for schema_migrations: %w", err)
+	}
+	if !exists {
+		return State{}, m.migrations, nil
+	}
+	state, err := readState(ctx, m.db)
+	if err != nil {
+		return State{}, nil, err
+	}
+	return state, m.pending(state), nil
+}
+
+// Check returns an error unless the schema is clean and at least at required, so that a
+// binary refuses to run against a database it does not match
+func (m *Migrator) Check(ctx context.Context, required uint) error {
+	state, _, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if state.Dirty {
+		return fmt.Errorf("migration %d failed halfway; fix the schema, then run migrate force %d", state.Version, state.Version)
+	}
+	if state.Version < required {
+		return fmt.Errorf("schema is at migration %d, need %d; run migrate up", state.Version, required)
+	}
+	return nil
+}
+
+// Up applies every pending migration in order and returns those it applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		state, err := cleanState(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.pending(state) {
+			if err := apply(ctx, conn, migration.Up, migration.Version); err != nil {
+				return fmt.Errorf("migration %06d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns those it
+// reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		state, err := cleanState(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for ; steps > 0 && state.Version > 0; steps-- {
+			i := m.index(state.Version)
+			if i < 0 {
+				return fmt.Errorf("schema is at migration %d, which this build does not know", state.Version)
+			}
+			var previous uint
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			migration := m.migrations[i]
+			if err := apply(ctx, conn, migration.Down, previous); err != nil {
+				return fmt.Errorf("reverting migration %06d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+			state.Version = previous
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force records version as applied and clean without running anything, once a failed
+// migration has been fixed by hand
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && m.index(version) < 0 {
+		return fmt.Errorf("no migration %d", version)
+	}
+	return m.locked(ctx, func(conn *sql.Conn) error {
+		return apply(ctx, conn, "", version)
+	})
+}
+
+func (m *Migrator) pending(state State) []Migration {
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if migration.Version > state.Version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+func (m *Migrator) index(version uint) int {
+	for i, migration := range m.migrations {
+		if migration.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// locked runs fn on a single connection holding the advisory lock, creating the
+// schema_migrations table first if needed. Waiting for the lock gives up with ctx.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	// unlocked even when ctx is done, as the lock lives as long as the pooled connection
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint NOT NULL PRIMARY KEY,
+		dirty boolean NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn(conn)
+}
+
+type queryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func readState(ctx context.Context, db queryer) (State, error) {
+	var state State
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&state.Version, &state.Dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return State{}, fmt.Errorf("failed to read migration version: %w", err)
+	}
+	return state, nil
+}
+
+func cleanState(ctx context.Context, conn *sql.Conn) (State, error) {
+	state, err := readState(ctx, conn)
+	if err != nil {
+		return State{}, err
+	}
+	if state.Dirty {
+		return State{}, fmt.Errorf("migration %d failed halfway; fix the schema, then run migrate force %d", state.Version, state.Version)
+	}
+	return state, nil
+}
+
+// apply runs statements and records version in one transaction, so a failed migration
+// leaves both the schema and its version as they were. Version 0 empties the table.
+func apply(ctx context.Context, conn *sql.Conn, statements string, version uint) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if statements != "" {
+		if _, err := tx.ExecContext(ctx, statements); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if version > 0 {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", version); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
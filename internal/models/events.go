@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // EventPayload represents an event in the analytics system
 type EventPayload struct {
@@ -10,11 +13,14 @@ type EventPayload struct {
 	SessionID   string    `json:"session_id" binding:"required"`
 	QuizID      string    `json:"quiz_id" binding:"required"`
 	ClassroomID string    `json:"classroom_id" binding:"required"`
-	QuestionID  string    `json:"question_id" binding:"required"`
-	TeacherID   *string   `json:"teacher_id,omitempty"`
-	TimerSec    *int      `json:"timer_sec,omitempty"`
-	// Additional fields for ANSWER_SUBMITTED events
-	StudentID      *string `json:"student_id,omitempty"`
-	Answer         *string `json:"answer,omitempty"`
-	ResponseTimeMs *int    `json:"response_time_ms,omitempty"`
+	// QuestionID is required for question events, not for STUDENT_JOINED / STUDENT_LEFT
+	QuestionID string  `json:"question_id"`
+	TeacherID  *string `json:"teacher_id,omitempty"`
+	TimerSec   *int    `json:"timer_sec,omitempty"`
+	// StudentID is required for ANSWER_SUBMITTED, QUESTION_SKIPPED, STUDENT_JOINED and STUDENT_LEFT
+	StudentID *string `json:"student_id,omitempty"`
+	// Answer holds a typed payload: "A" for single choice, ["A","C"] for multi-select,
+	// true/false, a number, free text, or an ordered array of item identifiers
+	Answer         json.RawMessage `json:"answer,omitempty"`
+	ResponseTimeMs *int            `json:"response_time_ms,omitempty"`
 }
@@ -1,69 +1,198 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
-	"gorm.io/gorm"
 )
 
-// Quiz represents a quiz entity - matches 000001_init_schema.up.sql
+// SchemaVersion is the latest migration in migrations/postgres the models match; readiness
+// checks fail until the database is migrated at least this far
+const SchemaVersion = 26
+
+// DefaultTenantID owns all data that existed before tenancy - matches 000017_tenants.up.sql
+var DefaultTenantID = uuid.MustParse("00000000-0000-0000-0000-000000000001")
+
+// Late-answer policies
+const (
+	LateAnswerReject = "reject" // answers after the deadline are rejected
+	LateAnswerGrace  = "grace"  // answers within the grace period are accepted and flagged late
+	LateAnswerAccept = "accept" // all late answers are accepted and flagged late
+)
+
+// Tenant is an organization (school or district) that owns all other data - matches 000017_tenants.up.sql
+type Tenant struct {
+	TenantID           uuid.UUID `gorm:"type:uuid;primary_key" json:"tenant_id"`
+	Name               string    `gorm:"not null" json:"name"`
+	Slug               string    `gorm:"not null;uniqueIndex" json:"slug"`
+	LateAnswerPolicy   string    `gorm:"not null;default:'reject'" json:"late_answer_policy"` // reject, grace, accept
+	LateAnswerGraceSec int       `gorm:"not null;default:0" json:"late_answer_grace_sec"`
+	RetentionDays      *int      `json:"retention_days"` // nil keeps data forever
+	CreatedAt          time.Time `gorm:"default:now()" json:"created_at"`
+	UpdatedAt          time.Time `gorm:"default:now()" json:"updated_at"`
+}
+
+func (Tenant) TableName() string {
+	return "tenants"
+}
+
+// Quiz represents a quiz entity - matches 000001, 000011 and 000017 migrations
 type Quiz struct {
-	QuizID      uuid.UUID `gorm:"type:uuid;primary_key" json:"quiz_id"`
-	Title       string    `gorm:"not null" json:"title"`
-	Description *string   `json:"description"`
+	QuizID      uuid.UUID  `gorm:"type:uuid;primary_key" json:"quiz_id"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	Title       string     `gorm:"not null" json:"title"`
+	Description *string    `json:"description"`
+	CreatedAt   time.Time  `gorm:"default:now()" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"default:now()" json:"updated_at"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
 }
 
 func (Quiz) TableName() string {
 	return "quizzes"
 }
 
-// Classroom represents a classroom entity - matches 000002_init_schema.up.sql
+// School groups a tenant's classrooms - matches 000018_report_access.up.sql
+type School struct {
+	SchoolID  uuid.UUID `gorm:"type:uuid;primary_key" json:"school_id"`
+	TenantID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_schools_tenant_sourced_id,priority:1" json:"-"`
+	Name      string    `gorm:"not null" json:"name"`
+	SourcedID *string   `gorm:"uniqueIndex:idx_schools_tenant_sourced_id,priority:2" json:"sourced_id,omitempty"` // OneRoster org sourcedId
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:now()" json:"updated_at"`
+}
+
+func (School) TableName() string {
+	return "schools"
+}
+
+// Classroom represents a classroom entity - matches 000002, 000013, 000014, 000017 and 000018 migrations
 type Classroom struct {
-	ClassroomID uuid.UUID `gorm:"type:uuid;primary_key" json:"classroom_id"`
-	Name        string    `gorm:"not null" json:"name"`
+	ClassroomID uuid.UUID  `gorm:"type:uuid;primary_key" json:"classroom_id"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_classrooms_tenant_sourced_id,priority:1" json:"-"`
+	SchoolID    *uuid.UUID `gorm:"type:uuid;index:idx_classrooms_school" json:"school_id"`
+	Name        string     `gorm:"not null" json:"name"`
+	SourcedID   *string    `gorm:"uniqueIndex:idx_classrooms_tenant_sourced_id,priority:2" json:"sourced_id,omitempty"` // OneRoster sourcedId
+	CreatedAt   time.Time  `gorm:"default:now()" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"default:now()" json:"updated_at"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
 }
 
 func (Classroom) TableName() string {
 	return "classrooms"
 }
 
-// Student represents a student entity - matches 000003_init_schema.up.sql
+// Student represents a student entity - matches 000003, 000013, 000014 and 000017 migrations
 type Student struct {
 	StudentID uuid.UUID `gorm:"type:uuid;primary_key" json:"student_id"`
-	Name      *string   `json:"name"` // nullable in migration
+	TenantID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_students_tenant_sourced_id,priority:1" json:"-"`
+	Name      *string   `json:"name"`                                                                              // nullable in migration
+	SourcedID *string   `gorm:"uniqueIndex:idx_students_tenant_sourced_id,priority:2" json:"sourced_id,omitempty"` // OneRoster sourcedId
+	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:now()" json:"updated_at"`
 }
 
 func (Student) TableName() string {
 	return "students"
 }
 
-// Question represents a question entity - matches 000006_init_schema.up.sql
+// Question represents a question entity - matches 000006, 000010, 000011 and 000017 migrations.
+// The row always holds the current content; every edit is also snapshotted in question_versions.
 type Question struct {
-	QuestionID uuid.UUID `gorm:"type:uuid;primary_key" json:"question_id"`
-	QuizID     uuid.UUID `gorm:"type:uuid;not null" json:"quiz_id"`
+	QuestionID   uuid.UUID       `gorm:"type:uuid;primary_key" json:"question_id"`
+	TenantID     uuid.UUID       `gorm:"type:uuid;not null" json:"-"`
+	QuizID       uuid.UUID       `gorm:"type:uuid;not null" json:"quiz_id"`
+	QuestionType string          `gorm:"not null;default:'single_choice'" json:"question_type"`
+	AnswerKey    json.RawMessage `gorm:"type:jsonb" json:"answer_key,omitempty"`
+	Text         *string         `json:"text"`
+	Options      QuestionOptions `gorm:"type:jsonb" json:"options,omitempty"`
+	Tags         StringList      `gorm:"type:jsonb" json:"tags,omitempty"`
+	Position     *int            `json:"position"`
+	Points       float64         `gorm:"not null;default:1" json:"points"`
+	Version      int             `gorm:"not null;default:1" json:"version"`
+	CreatedAt    time.Time       `gorm:"default:now()" json:"created_at"`
+	UpdatedAt    time.Time       `gorm:"default:now()" json:"updated_at"`
+	ArchivedAt   *time.Time      `json:"archived_at,omitempty"`
 }
 
 func (Question) TableName() string {
 	return "questions"
 }
 
-// QuizSession represents an active quiz session - matches 000005_init_schema.up.sql
+// QuestionVersion is an immutable snapshot of question content - matches 000011 and 000017 migrations
+type QuestionVersion struct {
+	QuestionID   uuid.UUID       `gorm:"type:uuid;primary_key" json:"question_id"`
+	Version      int             `gorm:"primary_key" json:"version"`
+	TenantID     uuid.UUID       `gorm:"type:uuid;not null" json:"-"`
+	QuestionType string          `gorm:"not null" json:"question_type"`
+	AnswerKey    json.RawMessage `gorm:"type:jsonb" json:"answer_key,omitempty"`
+	Text         *string         `json:"text"`
+	Options      QuestionOptions `gorm:"type:jsonb" json:"options,omitempty"`
+	Tags         StringList      `gorm:"type:jsonb" json:"tags,omitempty"`
+	Points       float64         `gorm:"not null" json:"points"`
+	CreatedAt    time.Time       `gorm:"default:now()" json:"created_at"`
+}
+
+func (QuestionVersion) TableName() string {
+	return "question_versions"
+}
+
+// QuizSession represents an active quiz session - matches 000005, 000016, 000017 and 000024 migrations
 type QuizSession struct {
 	SessionID   uuid.UUID  `gorm:"type:uuid;primary_key" json:"session_id"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
 	QuizID      uuid.UUID  `gorm:"type:uuid;not null" json:"quiz_id"`
 	ClassroomID uuid.UUID  `gorm:"type:uuid;not null" json:"classroom_id"`
+	TeacherID   *uuid.UUID `gorm:"type:uuid" json:"teacher_id"` // teacher who ran the session, nullable
 	StartedAt   time.Time  `gorm:"not null" json:"started_at"`
 	EndedAt     *time.Time `json:"ended_at"`
+	// IngestedBy is who sent the SESSION_STARTED event, e.g. user:42 or key:<api_key_id>
+	IngestedBy *string `json:"ingested_by,omitempty"`
 }
 
 func (QuizSession) TableName() string {
 	return "quiz_sessions"
 }
 
-// ClassroomStudent represents the many-to-many relationship - matches 000004_init_schema.up.sql
+// Teacher represents a teacher entity - matches 000016 and 000017 migrations
+type Teacher struct {
+	TeacherID  uuid.UUID  `gorm:"type:uuid;primary_key" json:"teacher_id"`
+	TenantID   uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_teachers_tenant_email,priority:1;uniqueIndex:idx_teachers_tenant_sourced_id,priority:1" json:"-"`
+	Name       *string    `json:"name"` // nullable for teachers only known from events
+	Email      *string    `gorm:"uniqueIndex:idx_teachers_tenant_email,priority:2" json:"email,omitempty"`
+	SourcedID  *string    `gorm:"uniqueIndex:idx_teachers_tenant_sourced_id,priority:2" json:"sourced_id,omitempty"` // OneRoster sourcedId
+	CreatedAt  time.Time  `gorm:"default:now()" json:"created_at"`
+	UpdatedAt  time.Time  `gorm:"default:now()" json:"updated_at"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+}
+
+func (Teacher) TableName() string {
+	return "teachers"
+}
+
+// Classroom teacher roles
+const (
+	TeacherRolePrimary   = "primary"
+	TeacherRoleCoTeacher = "co_teacher"
+)
+
+// ClassroomTeacher assigns a teacher to a classroom - matches 000016 and 000017 migrations
+type ClassroomTeacher struct {
+	ClassroomID uuid.UUID `gorm:"type:uuid;primary_key" json:"classroom_id"`
+	TeacherID   uuid.UUID `gorm:"type:uuid;primary_key" json:"teacher_id"`
+	TenantID    uuid.UUID `gorm:"type:uuid;not null" json:"-"`
+	Role        string    `gorm:"not null;default:'primary'" json:"role"` // primary, co_teacher
+	AssignedAt  time.Time `gorm:"default:now()" json:"assigned_at"`
+}
+
+func (ClassroomTeacher) TableName() string {
+	return "classroom_teachers"
+}
+
+// ClassroomStudent represents the many-to-many relationship - matches 000004 and 000017 migrations
 type ClassroomStudent struct {
 	ClassroomID uuid.UUID `gorm:"type:uuid;primary_key" json:"classroom_id"`
+	TenantID    uuid.UUID `gorm:"type:uuid;not null" json:"-"`
 	StudentID   uuid.UUID `gorm:"type:uuid;primary_key" json:"student_id"`
 }
 
@@ -71,61 +200,147 @@ func (ClassroomStudent) TableName() string {
 	return "classroom_students"
 }
 
-// QuestionPublishedEvent represents teacher publishing a question - matches 000007_init_schema.up.sql
+// ClassroomEnrollment is one enrollment interval - matches 000013 and 000017 migrations.
+// An open interval (nil UnenrolledAt) mirrors a row in classroom_students.
+type ClassroomEnrollment struct {
+	EnrollmentID uint       `gorm:"primaryKey" json:"enrollment_id"`
+	TenantID     uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	ClassroomID  uuid.UUID  `gorm:"type:uuid;not null" json:"classroom_id"`
+	StudentID    uuid.UUID  `gorm:"type:uuid;not null" json:"student_id"`
+	EnrolledAt   time.Time  `gorm:"not null" json:"enrolled_at"`
+	UnenrolledAt *time.Time `json:"unenrolled_at"`
+}
+
+func (ClassroomEnrollment) TableName() string {
+	return "classroom_enrollments"
+}
+
+// QuestionPublishedEvent represents teacher publishing a question - matches 000007, 000017 and 000024 migrations
 type QuestionPublishedEvent struct {
 	EventID          uuid.UUID  `gorm:"type:uuid;primary_key" json:"event_id"`
+	TenantID         uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
 	SessionID        uuid.UUID  `gorm:"type:uuid;not null" json:"session_id"`
 	QuestionID       uuid.UUID  `gorm:"type:uuid;not null" json:"question_id"`
 	TeacherID        *uuid.UUID `gorm:"type:uuid" json:"teacher_id"` // nullable
 	PublishedAt      time.Time  `gorm:"not null" json:"published_at"`
 	TimerDurationSec int        `gorm:"not null" json:"timer_duration_sec"`
+	IngestedBy       *string    `json:"ingested_by,omitempty"`
 }
 
 func (QuestionPublishedEvent) TableName() string {
 	return "question_published_events"
 }
 
-// AnswerSubmittedEvent represents student submitting an answer - matches 000008_init_schema.up.sql
+// AnswerSubmittedEvent represents student submitting an answer - matches 000008, 000010, 000017 and 000024 migrations
 type AnswerSubmittedEvent struct {
-	EventID     uuid.UUID `gorm:"type:uuid;primary_key" json:"event_id"`
-	SessionID   uuid.UUID `gorm:"type:uuid;not null" json:"session_id"`
-	QuestionID  uuid.UUID `gorm:"type:uuid;not null" json:"question_id"`
-	StudentID   uuid.UUID `gorm:"type:uuid;not null" json:"student_id"`
-	Answer      string    `gorm:"not null" json:"answer"`
-	IsCorrect   bool      `gorm:"not null" json:"is_correct"`
-	SubmittedAt time.Time `gorm:"not null" json:"submitted_at"`
+	EventID       uuid.UUID       `gorm:"type:uuid;primary_key" json:"event_id"`
+	TenantID      uuid.UUID       `gorm:"type:uuid;not null" json:"-"`
+	SessionID     uuid.UUID       `gorm:"type:uuid;not null" json:"session_id"`
+	QuestionID    uuid.UUID       `gorm:"type:uuid;not null" json:"question_id"`
+	StudentID     uuid.UUID       `gorm:"type:uuid;not null" json:"student_id"`
+	Answer        string          `gorm:"not null" json:"answer"`
+	AnswerPayload json.RawMessage `gorm:"type:jsonb" json:"answer_payload,omitempty"`
+	IsCorrect     bool            `gorm:"not null" json:"is_correct"`
+	Score         float64         `gorm:"not null;default:0" json:"score"`
+	// QuestionVersion is the content version the answer was graded against
+	QuestionVersion *int      `json:"question_version"`
+	SubmittedAt     time.Time `gorm:"not null" json:"submitted_at"`
+	// IsLate marks answers accepted after the deadline by the tenant's late-answer policy
+	IsLate     bool    `gorm:"not null;default:false" json:"is_late"`
+	IngestedBy *string `json:"ingested_by,omitempty"`
 }
 
 func (AnswerSubmittedEvent) TableName() string {
 	return "answer_submitted_events"
 }
 
-// User represents authentication users - matches 000009_init_schema.up.sql
+// SessionPresenceEvent records a student joining or leaving a session - matches 000015, 000017 and 000024 migrations
+type SessionPresenceEvent struct {
+	EventID    uuid.UUID `gorm:"type:uuid;primary_key" json:"event_id"`
+	TenantID   uuid.UUID `gorm:"type:uuid;not null" json:"-"`
+	SessionID  uuid.UUID `gorm:"type:uuid;not null" json:"session_id"`
+	StudentID  uuid.UUID `gorm:"type:uuid;not null" json:"student_id"`
+	EventType  string    `gorm:"not null" json:"event_type"` // STUDENT_JOINED, STUDENT_LEFT
+	OccurredAt time.Time `gorm:"not null" json:"occurred_at"`
+	IngestedBy *string   `json:"ingested_by,omitempty"`
+}
+
+func (SessionPresenceEvent) TableName() string {
+	return "session_presence_events"
+}
+
+// QuestionSkippedEvent represents a student explicitly skipping a question - matches 000015, 000017 and 000024 migrations
+type QuestionSkippedEvent struct {
+	EventID    uuid.UUID `gorm:"type:uuid;primary_key" json:"event_id"`
+	TenantID   uuid.UUID `gorm:"type:uuid;not null" json:"-"`
+	SessionID  uuid.UUID `gorm:"type:uuid;not null" json:"session_id"`
+	QuestionID uuid.UUID `gorm:"type:uuid;not null" json:"question_id"`
+	StudentID  uuid.UUID `gorm:"type:uuid;not null" json:"student_id"`
+	SkippedAt  time.Time `gorm:"not null" json:"skipped_at"`
+	IngestedBy *string   `json:"ingested_by,omitempty"`
+}
+
+func (QuestionSkippedEvent) TableName() string {
+	return "question_skipped_events"
+}
+
+// ProvisionedEntity records a placeholder auto-created from an event - matches 000012, 000016 and 000017 migrations
+type ProvisionedEntity struct {
+	EntityType    string     `gorm:"primary_key" json:"entity_type"` // session, question, student, teacher
+	TenantID      uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	EntityID      uuid.UUID  `gorm:"type:uuid;primary_key" json:"entity_id"`
+	SourceEventID uuid.UUID  `gorm:"type:uuid;not null" json:"source_event_id"`
+	ProvisionedAt time.Time  `gorm:"default:now()" json:"provisioned_at"`
+	ReconciledAt  *time.Time `json:"reconciled_at"`
+}
+
+func (ProvisionedEntity) TableName() string {
+	return "provisioned_entities"
+}
+
+// User represents authentication users - matches 000009, 000017, 000018 and 000019 migrations
 type User struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"size:100;not null" json:"name"`
-	Email     string    `gorm:"size:100;uniqueIndex;not null" json:"email"`
-	Password  string    `gorm:"not null" json:"-"`
-	Role      string    `gorm:"size:20;not null;default:'writer'" json:"role"`
-	CreatedAt time.Time `gorm:"default:now()" json:"created_at"`
-	UpdatedAt time.Time `gorm:"default:now()" json:"updated_at"`
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TenantID    uuid.UUID  `gorm:"type:uuid;not null" json:"-"`
+	Name        string     `gorm:"size:100;not null" json:"name"`
+	Email       string     `gorm:"size:100;uniqueIndex;not null" json:"email"`
+	Password    string     `gorm:"not null" json:"-"`
+	Role        string     `gorm:"size:20;not null;default:'writer'" json:"role"` // admin, writer, reader
+	AccessLevel string     `gorm:"not null;default:'tenant'" json:"access_level"` // tenant, school, teacher, student, none
+	SchoolID    *uuid.UUID `gorm:"type:uuid" json:"school_id,omitempty"`
+	TeacherID   *uuid.UUID `gorm:"type:uuid" json:"teacher_id,omitempty"`
+	StudentID   *uuid.UUID `gorm:"type:uuid" json:"student_id,omitempty"`
+	DisabledAt  *time.Time `json:"disabled_at,omitempty"` // disabled users cannot log in
+	CreatedAt   time.Time  `gorm:"default:now()" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"default:now()" json:"updated_at"`
 }
 
 func (User) TableName() string {
 	return "users"
 }
 
-// AutoMigrate runs all migrations for the models
-func AutoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
-		&Quiz{},
-		&Classroom{},
-		&Student{},
-		&Question{},
-		&QuizSession{},
-		&ClassroomStudent{},
-		&QuestionPublishedEvent{},
-		&AnswerSubmittedEvent{},
-		&User{},
-	)
+// Audit outcomes
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// AuditEntry records who did what - matches 000023_audit_log.up.sql. The table is
+// append-only. Failed logins for unknown emails belong to no tenant.
+type AuditEntry struct {
+	EntryID       int64      `gorm:"primaryKey" json:"entry_id"`
+	TenantID      *uuid.UUID `gorm:"type:uuid" json:"-"`
+	OccurredAt    time.Time  `gorm:"not null;default:now()" json:"occurred_at"`
+	Action        string     `gorm:"not null" json:"action"`  // e.g. auth.login, user.role_change, events.ingest
+	Outcome       string     `gorm:"not null" json:"outcome"` // success or failure
+	ActorUserID   *uint      `json:"actor_user_id,omitempty"`
+	ActorAPIKeyID *uuid.UUID `gorm:"column:actor_api_key_id;type:uuid" json:"actor_api_key_id,omitempty"`
+	SourceIP      string     `json:"source_ip,omitempty"`
+	TargetType    string     `json:"target_type,omitempty"` // e.g. user, api_key, tenant
+	TargetID      string     `json:"target_id,omitempty"`
+	Details       JSONMap    `gorm:"type:jsonb" json:"details,omitempty"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_log"
 }
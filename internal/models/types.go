@@ -0,0 +1,99 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// QuestionOption is a selectable choice shown to students
+type QuestionOption struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+}
+
+// QuestionOptions is stored as a JSONB array
+type QuestionOptions []QuestionOption
+
+func (o QuestionOptions) Value() (driver.Value, error) {
+	if o == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (o *QuestionOptions) Scan(value interface{}) error {
+	return scanJSON(value, o)
+}
+
+// Label returns the display label for an option key, or an empty string
+func (o QuestionOptions) Label(key string) string {
+	for _, opt := range o {
+		if opt.Key == key {
+			return opt.Label
+		}
+	}
+	return ""
+}
+
+// Keys returns the option keys in display order
+func (o QuestionOptions) Keys() []string {
+	keys := make([]string, 0, len(o))
+	for _, opt := range o {
+		keys = append(keys, opt.Key)
+	}
+	return keys
+}
+
+// StringList is stored as a JSONB array of strings
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+// JSONMap is stored as a JSONB object
+type JSONMap map[string]interface{}
+
+func (m JSONMap) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (m *JSONMap) Scan(value interface{}) error {
+	return scanJSON(value, m)
+}
+
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("unsupported JSON column type %T", value)
+	}
+}
@@ -0,0 +1,79 @@
+package oneroster
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+)
+
+type Handler struct {
+	service *Service
+	audit   auth.Auditor
+}
+
+func NewHandler(s *Service, auditor auth.Auditor) *Handler {
+	return &Handler{service: s, audit: auditor}
+}
+
+// tenantService returns the service scoped to the caller's tenant
+func (h *Handler) tenantService(c *gin.Context) *Service {
+	return h.service.ForTenant(auth.TenantID(c))
+}
+
+// ImportRoster handles POST /api/admin/rosters/import. The CSV files are sent as a
+// multipart form with one part per file, named orgs.csv, classes.csv, users.csv and
+// enrollments.csv (the .csv suffix is optional). Pass dry_run=true to only see the diff.
+func (h *Handler) ImportRoster(c *gin.Context) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expected multipart form with OneRoster CSV files"})
+		return
+	}
+
+	files := make(map[string]io.Reader, len(Files))
+	for _, name := range Files {
+		headers := form.File[name]
+		if len(headers) == 0 {
+			headers = form.File[strings.TrimSuffix(name, ".csv")]
+		}
+		if len(headers) == 0 {
+			continue
+		}
+		file, err := headers[0].Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read " + name})
+			return
+		}
+		defer file.Close()
+		files[name] = file
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+
+	report, err := h.tenantService(c).Import(files, dryRun)
+	if err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if report.Applied != nil {
+		entry := auth.AuditEntry(c, "roster.import")
+		entry.Details = models.JSONMap{
+			"skipped_rows": report.SkippedRows,
+			"applied":      report.Applied,
+		}
+		h.audit.Record(entry)
+	}
+
+	c.JSON(http.StatusOK, report)
+}
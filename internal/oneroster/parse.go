@@ -0,0 +1,226 @@
+package oneroster
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// OneRoster 1.1 CSV files understood by the importer
+const (
+	FileOrgs        = "orgs.csv"
+	FileClasses     = "classes.csv"
+	FileUsers       = "users.csv"
+	FileEnrollments = "enrollments.csv"
+)
+
+// Files lists the CSV files in the order they are parsed
+var Files = []string{FileOrgs, FileClasses, FileUsers, FileEnrollments}
+
+const statusToBeDeleted = "tobedeleted"
+
+// RowError describes one CSV row that could not be imported
+type RowError struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	SourcedID string `json:"sourced_id,omitempty"`
+	Message   string `json:"message"`
+}
+
+type Org struct {
+	SourcedID string
+	Name      string
+	Type      string // school, district, ...
+	Line      int
+}
+
+type Class struct {
+	SourcedID       string
+	Title           string
+	SchoolSourcedID string
+	Deleted         bool
+	Line            int
+}
+
+type User struct {
+	SourcedID string
+	Name      string
+	Deleted   bool
+	Line      int
+}
+
+type Enrollment struct {
+	SourcedID      string
+	ClassSourcedID string
+	UserSourcedID  string
+	BeginDate      *time.Time
+	EndDate        *time.Time
+	Deleted        bool
+	Line           int
+}
+
+// Roster is the parsed content of a OneRoster CSV export. Only student users and
+// student enrollments are kept; teachers and other roles are counted as skipped.
+type Roster struct {
+	Orgs        []Org
+	Classes     []Class
+	Users       []User
+	Enrollments []Enrollment
+	Skipped     int
+}
+
+// Parse reads the OneRoster CSV files keyed by file name. Every file is required.
+// Malformed rows are reported and left out of the roster; the rest is still returned.
+func Parse(files map[string]io.Reader) (*Roster, []RowError, error) {
+	for _, name := range Files {
+		if files[name] == nil {
+			return nil, nil, fmt.Errorf("%s is required", name)
+		}
+	}
+
+	roster := &Roster{}
+	var rowErrors []RowError
+	seen := map[string]map[string]bool{}
+
+	for _, name := range Files {
+		seen[name] = map[string]bool{}
+		err := readCSV(name, files[name], func(line int, row csvRow) {
+			sourcedID := row.get("sourcedId")
+			fail := func(format string, args ...interface{}) {
+				rowErrors = append(rowErrors, RowError{File: name, Line: line, SourcedID: sourcedID, Message: fmt.Sprintf(format, args...)})
+			}
+			if sourcedID == "" {
+				fail("sourcedId is required")
+				return
+			}
+			if seen[name][sourcedID] {
+				fail("duplicate sourcedId")
+				return
+			}
+			seen[name][sourcedID] = true
+			deleted := strings.EqualFold(row.get("status"), statusToBeDeleted)
+
+			switch name {
+			case FileOrgs:
+				roster.Orgs = append(roster.Orgs, Org{SourcedID: sourcedID, Name: row.get("name"), Type: strings.ToLower(row.get("type")), Line: line})
+
+			case FileClasses:
+				title := row.get("title")
+				if title == "" && !deleted {
+					fail("title is required")
+					return
+				}
+				roster.Classes = append(roster.Classes, Class{
+					SourcedID:       sourcedID,
+					Title:           title,
+					SchoolSourcedID: row.get("schoolSourcedId"),
+					Deleted:         deleted,
+					Line:            line,
+				})
+
+			case FileUsers:
+				if !strings.EqualFold(row.get("role"), "student") {
+					roster.Skipped++
+					return
+				}
+				fullName := strings.TrimSpace(row.get("givenName") + " " + row.get("familyName"))
+				roster.Users = append(roster.Users, User{SourcedID: sourcedID, Name: fullName, Deleted: deleted, Line: line})
+
+			case FileEnrollments:
+				if !strings.EqualFold(row.get("role"), "student") {
+					roster.Skipped++
+					return
+				}
+				enrollment := Enrollment{
+					SourcedID:      sourcedID,
+					ClassSourcedID: row.get("classSourcedId"),
+					UserSourcedID:  row.get("userSourcedId"),
+					Deleted:        deleted,
+					Line:           line,
+				}
+				if enrollment.ClassSourcedID == "" || enrollment.UserSourcedID == "" {
+					fail("classSourcedId and userSourcedId are required")
+					return
+				}
+				var err error
+				if enrollment.BeginDate, err = parseDate(row.get("beginDate")); err != nil {
+					fail("invalid beginDate: %v", err)
+					return
+				}
+				if enrollment.EndDate, err = parseDate(row.get("endDate")); err != nil {
+					fail("invalid endDate: %v", err)
+					return
+				}
+				if enrollment.BeginDate != nil && enrollment.EndDate != nil && !enrollment.EndDate.After(*enrollment.BeginDate) {
+					fail("endDate must be after beginDate")
+					return
+				}
+				roster.Enrollments = append(roster.Enrollments, enrollment)
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return roster, rowErrors, nil
+}
+
+// csvRow gives access to a record by header name
+type csvRow struct {
+	header map[string]int
+	record []string
+}
+
+func (r csvRow) get(column string) string {
+	i, ok := r.header[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+// readCSV calls fn for every data row with the 1-based line the row starts on
+func readCSV(name string, src io.Reader, fn func(line int, row csvRow)) error {
+	reader := csv.NewReader(src)
+	reader.FieldsPerRecord = -1
+
+	headerRecord, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("%s: failed to read header: %v", name, err)
+	}
+	header := make(map[string]int, len(headerRecord))
+	for i, column := range headerRecord {
+		header[strings.TrimPrefix(strings.TrimSpace(column), "\ufeff")] = i
+	}
+	if _, ok := header["sourcedId"]; !ok {
+		return fmt.Errorf("%s: missing sourcedId column", name)
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		line, _ := reader.FieldPos(0)
+		fn(line, csvRow{header: header, record: record})
+	}
+}
+
+// parseDate accepts OneRoster dates (YYYY-MM-DD) and full RFC3339 timestamps
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not a date", value)
+}
@@ -0,0 +1,198 @@
+package oneroster
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	orgsCSV        = "sourcedId,name,type\nschool-1,North High,school\ndistrict-1,District,district\n"
+	classesCSV     = "sourcedId,title,schoolSourcedId,status\nclass-1,Algebra,school-1,active\n"
+	usersCSV       = "sourcedId,givenName,familyName,role\nuser-1,Ada,Lovelace,student\nteacher-1,Alan,Turing,teacher\n"
+	enrollmentsCSV = "sourcedId,classSourcedId,userSourcedId,role\nenr-1,class-1,user-1,student\nenr-2,class-1,teacher-1,teacher\n"
+)
+
+// export returns the four files of a valid export with some of them replaced
+func export(replace map[string]string) map[string]io.Reader {
+	content := map[string]string{
+		FileOrgs:        orgsCSV,
+		FileClasses:     classesCSV,
+		FileUsers:       usersCSV,
+		FileEnrollments: enrollmentsCSV,
+	}
+	files := map[string]io.Reader{}
+	for name, csv := range content {
+		if replacement, ok := replace[name]; ok {
+			csv = replacement
+		}
+		if csv != "" {
+			files[name] = strings.NewReader(csv)
+		}
+	}
+	return files
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		name    string
+		replace map[string]string
+		fails   string     // the error Parse returns, if it rejects the export as a whole
+		errors  []RowError // the rows reported, without their messages
+		users   []string   // sourcedIds of the student users kept
+		skipped int
+	}{
+		{
+			name:    "valid",
+			users:   []string{"user-1"},
+			skipped: 2,
+		},
+		{
+			name:    "missing file",
+			replace: map[string]string{FileUsers: ""},
+			fails:   "users.csv is required",
+		},
+		{
+			name:    "unterminated quote",
+			replace: map[string]string{FileUsers: "sourcedId,givenName,role\nuser-1,\"Ada,student\n"},
+			fails:   "users.csv:",
+		},
+		{
+			name:    "no sourcedId column",
+			replace: map[string]string{FileClasses: "id,title\nclass-1,Algebra\n"},
+			fails:   "classes.csv: missing sourcedId column",
+		},
+		{
+			name:    "unknown columns and byte order mark",
+			replace: map[string]string{FileUsers: "\ufeffsourcedId,nickname,givenName,familyName,role,grades\nuser-1,Addy,Ada,Lovelace,student,09\n"},
+			users:   []string{"user-1"},
+			skipped: 1,
+		},
+		{
+			name:    "short row",
+			replace: map[string]string{FileUsers: "sourcedId,role,givenName\nuser-1,student\n"},
+			users:   []string{"user-1"},
+			skipped: 1,
+		},
+		{
+			name:    "duplicate sourcedId",
+			replace: map[string]string{FileUsers: "sourcedId,role\nuser-1,student\nuser-2,student\nuser-1,student\n"},
+			errors:  []RowError{{File: FileUsers, Line: 4, SourcedID: "user-1"}},
+			users:   []string{"user-1", "user-2"},
+			skipped: 1,
+		},
+		{
+			name:    "same sourcedId in two files",
+			replace: map[string]string{FileUsers: "sourcedId,role\nclass-1,student\n"},
+			users:   []string{"class-1"},
+			skipped: 1,
+		},
+		{
+			name:    "missing sourcedId",
+			replace: map[string]string{FileUsers: "sourcedId,role\n,student\nuser-1,student\n"},
+			errors:  []RowError{{File: FileUsers, Line: 2}},
+			users:   []string{"user-1"},
+			skipped: 1,
+		},
+		{
+			name:    "class without title",
+			replace: map[string]string{FileClasses: "sourcedId,title,status\nclass-1,,active\nclass-2,,tobedeleted\n"},
+			errors:  []RowError{{File: FileClasses, Line: 2, SourcedID: "class-1"}},
+			users:   []string{"user-1"},
+			skipped: 2,
+		},
+		{
+			name: "bad enrollment dates",
+			replace: map[string]string{FileEnrollments: "sourcedId,classSourcedId,userSourcedId,role,beginDate,endDate\n" +
+				"enr-1,class-1,user-1,student,yesterday,\n" +
+				"enr-2,class-1,user-1,student,2024-09-01,2024-08-01\n" +
+				"enr-3,,user-1,student,,\n" +
+				"enr-4,class-1,user-1,student,2024-09-01,2025-06-30T00:00:00Z\n"},
+			errors: []RowError{
+				{File: FileEnrollments, Line: 2, SourcedID: "enr-1"},
+				{File: FileEnrollments, Line: 3, SourcedID: "enr-2"},
+				{File: FileEnrollments, Line: 4, SourcedID: "enr-3"},
+			},
+			users:   []string{"user-1"},
+			skipped: 1,
+		},
+		{
+			name:    "quoted field spanning lines",
+			replace: map[string]string{FileUsers: "sourcedId,givenName,role\nuser-1,\"Ada\nMarie\",student\nuser-1,Ada,student\n"},
+			errors:  []RowError{{File: FileUsers, Line: 4, SourcedID: "user-1"}},
+			users:   []string{"user-1"},
+			skipped: 1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			roster, rowErrors, err := Parse(export(tc.replace))
+			if tc.fails != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.fails) {
+					t.Fatalf("got error %v, want %q", err, tc.fails)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for i := range rowErrors {
+				if rowErrors[i].Message == "" {
+					t.Errorf("row error %+v has no message", rowErrors[i])
+				}
+				rowErrors[i].Message = ""
+			}
+			if len(rowErrors) != 0 || len(tc.errors) != 0 {
+				if !reflect.DeepEqual(rowErrors, tc.errors) {
+					t.Errorf("row errors %+v, want %+v", rowErrors, tc.errors)
+				}
+			}
+
+			var users []string
+			for _, user := range roster.Users {
+				users = append(users, user.SourcedID)
+			}
+			if !reflect.DeepEqual(users, tc.users) {
+				t.Errorf("users %v, want %v", users, tc.users)
+			}
+			if roster.Skipped != tc.skipped {
+				t.Errorf("skipped %d, want %d", roster.Skipped, tc.skipped)
+			}
+		})
+	}
+}
+
+func TestParseKeepsFields(t *testing.T) {
+	roster, rowErrors, err := Parse(export(map[string]string{
+		FileClasses: "sourcedId,title,schoolSourcedId,status\nclass-1, Algebra ,school-1,active\nclass-2,,school-1,TOBEDELETED\n",
+		FileEnrollments: "sourcedId,classSourcedId,userSourcedId,role,beginDate,endDate,status\n" +
+			"enr-1,class-1,user-1,Student,2024-09-01,2025-06-30,tobedeleted\n",
+	}))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("got errors %v, %v", err, rowErrors)
+	}
+
+	if len(roster.Orgs) != 2 || roster.Orgs[0].Type != orgTypeSchool || roster.Orgs[1].Type != "district" {
+		t.Errorf("orgs %+v", roster.Orgs)
+	}
+	want := []Class{
+		{SourcedID: "class-1", Title: "Algebra", SchoolSourcedID: "school-1", Line: 2},
+		{SourcedID: "class-2", SchoolSourcedID: "school-1", Deleted: true, Line: 3},
+	}
+	if !reflect.DeepEqual(roster.Classes, want) {
+		t.Errorf("classes %+v, want %+v", roster.Classes, want)
+	}
+	if len(roster.Users) != 1 || roster.Users[0].Name != "Ada Lovelace" {
+		t.Errorf("users %+v", roster.Users)
+	}
+	if len(roster.Enrollments) != 1 {
+		t.Fatalf("enrollments %+v", roster.Enrollments)
+	}
+	enrollment := roster.Enrollments[0]
+	if !enrollment.Deleted || enrollment.BeginDate == nil || enrollment.EndDate == nil ||
+		enrollment.BeginDate.Format("2006-01-02") != "2024-09-01" || enrollment.EndDate.Format("2006-01-02") != "2025-06-30" {
+		t.Errorf("enrollment %+v", enrollment)
+	}
+}
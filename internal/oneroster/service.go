@@ -0,0 +1,423 @@
+package oneroster
+
+import (
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+)
+
+// orgTypeSchool is the orgs.csv type imported as a school
+const orgTypeSchool = "school"
+
+// namespace derives stable UUIDs from sourcedIds so re-imports land on the same rows
+var namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://www.imsglobal.org/oneroster"))
+
+type Service struct {
+	ClassroomRepo repository.ClassroomRepository
+	// TenantID is the tenant rosters are imported into; set by ForTenant
+	TenantID uuid.UUID
+}
+
+func NewService(classroomRepo repository.ClassroomRepository) *Service {
+	return &Service{ClassroomRepo: classroomRepo}
+}
+
+// ForTenant returns a copy of the service that imports into the tenant's roster
+func (s *Service) ForTenant(tenantID uuid.UUID) *Service {
+	return &Service{ClassroomRepo: s.ClassroomRepo.ForTenant(tenantID), TenantID: tenantID}
+}
+
+// entityID derives the ID of a new entity. IDs are primary keys shared by all tenants,
+// so the tenant is part of the namespace: two districts may use the same sourcedId.
+func (s *Service) entityID(kind, sourcedID string) uuid.UUID {
+	return uuid.NewSHA1(uuid.NewSHA1(namespace, s.TenantID[:]), []byte(kind+":"+sourcedID))
+}
+
+// ValidationError marks an import rejected as a whole, e.g. a missing file or header
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+// EntityChange is a school, classroom or student the import creates, updates or archives
+type EntityChange struct {
+	SourcedID    string     `json:"sourced_id"`
+	ID           uuid.UUID  `json:"id"`
+	Name         string     `json:"name"`
+	PreviousName *string    `json:"previous_name,omitempty"`
+	SchoolID     *uuid.UUID `json:"school_id,omitempty"` // classrooms only
+}
+
+// EnrollmentChange is an enrollment the import opens or closes
+type EnrollmentChange struct {
+	SourcedID      string    `json:"sourced_id"`
+	ClassSourcedID string    `json:"class_sourced_id"`
+	UserSourcedID  string    `json:"user_sourced_id"`
+	ClassroomID    uuid.UUID `json:"classroom_id"`
+	StudentID      uuid.UUID `json:"student_id"`
+	EffectiveAt    time.Time `json:"effective_at"`
+}
+
+type EntityDiff struct {
+	Create    []EntityChange `json:"create"`
+	Update    []EntityChange `json:"update"`
+	Archive   []EntityChange `json:"archive,omitempty"`
+	Unchanged int            `json:"unchanged"`
+}
+
+type EnrollmentDiff struct {
+	Enroll    []EnrollmentChange `json:"enroll"`
+	Unenroll  []EnrollmentChange `json:"unenroll"`
+	Unchanged int                `json:"unchanged"`
+}
+
+// ImportReport is the diff between the CSV export and the database, plus rows that were rejected.
+// Applied is only set when the import was not a dry run.
+type ImportReport struct {
+	DryRun      bool                     `json:"dry_run"`
+	Schools     EntityDiff               `json:"schools"`
+	Classrooms  EntityDiff               `json:"classrooms"`
+	Students    EntityDiff               `json:"students"`
+	Enrollments EnrollmentDiff           `json:"enrollments"`
+	SkippedRows int                      `json:"skipped_rows"`
+	Errors      []RowError               `json:"errors"`
+	Applied     *repository.RosterResult `json:"applied,omitempty"`
+}
+
+// Import parses a OneRoster CSV export and upserts school orgs, classes, students and student
+// enrollments keyed by sourcedId. Invalid rows are reported and skipped; the valid rows are applied in a
+// single transaction unless dryRun is set.
+func (s *Service) Import(files map[string]io.Reader, dryRun bool) (*ImportReport, error) {
+	roster, rowErrors, err := Parse(files)
+	if err != nil {
+		return nil, &ValidationError{Reason: err.Error()}
+	}
+
+	report := &ImportReport{DryRun: dryRun, SkippedRows: roster.Skipped, Errors: rowErrors}
+	if report.Errors == nil {
+		report.Errors = []RowError{}
+	}
+	fail := func(file string, line int, sourcedID, message string) {
+		report.Errors = append(report.Errors, RowError{File: file, Line: line, SourcedID: sourcedID, Message: message})
+	}
+
+	existingClassrooms, existingStudents, err := s.loadExisting(roster)
+	if err != nil {
+		return nil, err
+	}
+
+	existingSchools, err := s.loadExistingSchools(roster)
+	if err != nil {
+		return nil, err
+	}
+
+	var batch repository.RosterBatch
+	now := time.Now()
+
+	// Orgs of type school become schools; districts and other orgs are only referenced
+	orgs := make(map[string]bool, len(roster.Orgs))
+	schools := map[string]uuid.UUID{}
+	for _, org := range roster.Orgs {
+		orgs[org.SourcedID] = true
+		if org.Type != orgTypeSchool {
+			continue
+		}
+		name := org.Name
+		if name == "" {
+			name = org.SourcedID
+		}
+		existing, found := existingSchools[org.SourcedID]
+		change := EntityChange{SourcedID: org.SourcedID, ID: s.entityID("org", org.SourcedID), Name: name}
+		switch {
+		case !found:
+			report.Schools.Create = append(report.Schools.Create, change)
+		case existing.Name != name:
+			change.ID = existing.SchoolID
+			previous := existing.Name
+			change.PreviousName = &previous
+			report.Schools.Update = append(report.Schools.Update, change)
+		default:
+			schools[org.SourcedID] = existing.SchoolID
+			report.Schools.Unchanged++
+			continue
+		}
+		schools[org.SourcedID] = change.ID
+		sourcedID := org.SourcedID
+		batch.Schools = append(batch.Schools, models.School{SchoolID: change.ID, Name: name, SourcedID: &sourcedID})
+	}
+
+	// Classes become classrooms; tobedeleted classes are archived
+	classrooms := map[string]uuid.UUID{}
+	archived := map[string]bool{}
+	for _, class := range roster.Classes {
+		if class.SchoolSourcedID != "" && !orgs[class.SchoolSourcedID] {
+			fail(FileClasses, class.Line, class.SourcedID, "unknown schoolSourcedId "+class.SchoolSourcedID)
+			continue
+		}
+		existing, found := existingClassrooms[class.SourcedID]
+		if class.Deleted {
+			archived[class.SourcedID] = true
+			if found && existing.ArchivedAt == nil {
+				report.Classrooms.Archive = append(report.Classrooms.Archive, EntityChange{SourcedID: class.SourcedID, ID: existing.ClassroomID, Name: existing.Name})
+				batch.ArchiveClassrooms = append(batch.ArchiveClassrooms, existing.ClassroomID)
+			}
+			continue
+		}
+
+		// classes of a district or other non-school org keep the school they have
+		var schoolID *uuid.UUID
+		if id, ok := schools[class.SchoolSourcedID]; ok {
+			schoolID = &id
+		}
+		schoolChanged := schoolID != nil && (existing.SchoolID == nil || *existing.SchoolID != *schoolID)
+
+		change := EntityChange{SourcedID: class.SourcedID, ID: s.entityID("class", class.SourcedID), Name: class.Title, SchoolID: schoolID}
+		switch {
+		case !found:
+			report.Classrooms.Create = append(report.Classrooms.Create, change)
+		case existing.Name != class.Title || existing.ArchivedAt != nil || schoolChanged:
+			change.ID = existing.ClassroomID
+			previous := existing.Name
+			change.PreviousName = &previous
+			report.Classrooms.Update = append(report.Classrooms.Update, change)
+		default:
+			classrooms[class.SourcedID] = existing.ClassroomID
+			report.Classrooms.Unchanged++
+			continue
+		}
+		classrooms[class.SourcedID] = change.ID
+		sourcedID := class.SourcedID
+		batch.Classrooms = append(batch.Classrooms, models.Classroom{ClassroomID: change.ID, Name: class.Title, SchoolID: schoolID, SourcedID: &sourcedID})
+	}
+
+	// Student users become students; students are never deleted, only unenrolled
+	students := map[string]uuid.UUID{}
+	for _, user := range roster.Users {
+		if user.Deleted {
+			continue
+		}
+		existing, found := existingStudents[user.SourcedID]
+		change := EntityChange{SourcedID: user.SourcedID, ID: s.entityID("user", user.SourcedID), Name: user.Name}
+		switch {
+		case !found:
+			report.Students.Create = append(report.Students.Create, change)
+		case (existing.Name == nil && user.Name != "") || (existing.Name != nil && *existing.Name != user.Name):
+			change.ID = existing.StudentID
+			change.PreviousName = existing.Name
+			report.Students.Update = append(report.Students.Update, change)
+		default:
+			students[user.SourcedID] = existing.StudentID
+			report.Students.Unchanged++
+			continue
+		}
+		students[user.SourcedID] = change.ID
+		sourcedID := user.SourcedID
+		student := models.Student{StudentID: change.ID, SourcedID: &sourcedID}
+		if user.Name != "" {
+			name := user.Name
+			student.Name = &name
+		}
+		batch.Students = append(batch.Students, student)
+	}
+
+	// Enrollments may also point at classes and students imported earlier
+	for sourcedID, classroom := range existingClassrooms {
+		if _, ok := classrooms[sourcedID]; !ok && !archived[sourcedID] {
+			classrooms[sourcedID] = classroom.ClassroomID
+		}
+	}
+	for sourcedID, student := range existingStudents {
+		if _, ok := students[sourcedID]; !ok {
+			students[sourcedID] = student.StudentID
+		}
+	}
+
+	intervals, err := s.loadIntervals(existingClassrooms)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, enrollment := range roster.Enrollments {
+		classroomID, classKnown := classrooms[enrollment.ClassSourcedID]
+		if !classKnown && !archived[enrollment.ClassSourcedID] {
+			fail(FileEnrollments, enrollment.Line, enrollment.SourcedID, "unknown classSourcedId "+enrollment.ClassSourcedID)
+			continue
+		}
+		studentID, studentKnown := students[enrollment.UserSourcedID]
+		if !studentKnown {
+			fail(FileEnrollments, enrollment.Line, enrollment.SourcedID, "unknown userSourcedId "+enrollment.UserSourcedID)
+			continue
+		}
+		if !classKnown {
+			// The class is being archived: close its enrollments, if it was ever imported
+			existing, found := existingClassrooms[enrollment.ClassSourcedID]
+			if !found {
+				report.Enrollments.Unchanged++
+				continue
+			}
+			classroomID = existing.ClassroomID
+		}
+
+		key := enrollmentKey{classroomID, studentID}
+		history := intervals[key]
+		change := EnrollmentChange{
+			SourcedID:      enrollment.SourcedID,
+			ClassSourcedID: enrollment.ClassSourcedID,
+			UserSourcedID:  enrollment.UserSourcedID,
+			ClassroomID:    classroomID,
+			StudentID:      studentID,
+		}
+
+		active := classKnown && !enrollment.Deleted && (enrollment.EndDate == nil || enrollment.EndDate.After(now))
+		if active {
+			if history.open != nil {
+				report.Enrollments.Unchanged++
+				continue
+			}
+			change.EffectiveAt = now
+			if enrollment.BeginDate != nil {
+				change.EffectiveAt = *enrollment.BeginDate
+			}
+			// A re-enrollment cannot start before the previous interval ended
+			if history.lastEnded != nil && history.lastEnded.After(change.EffectiveAt) {
+				change.EffectiveAt = *history.lastEnded
+			}
+			report.Enrollments.Enroll = append(report.Enrollments.Enroll, change)
+			batch.Enrollments = append(batch.Enrollments, repository.RosterEnrollment{ClassroomID: classroomID, StudentID: studentID, Active: true, EffectiveAt: change.EffectiveAt})
+			intervals[key] = enrollmentState{open: &change.EffectiveAt, lastEnded: history.lastEnded}
+			continue
+		}
+
+		if history.open == nil {
+			report.Enrollments.Unchanged++
+			continue
+		}
+		change.EffectiveAt = now
+		if enrollment.EndDate != nil && enrollment.EndDate.Before(now) {
+			change.EffectiveAt = *enrollment.EndDate
+		}
+		if !change.EffectiveAt.After(*history.open) {
+			fail(FileEnrollments, enrollment.Line, enrollment.SourcedID, "endDate is before the current enrollment started")
+			continue
+		}
+		report.Enrollments.Unenroll = append(report.Enrollments.Unenroll, change)
+		batch.Enrollments = append(batch.Enrollments, repository.RosterEnrollment{ClassroomID: classroomID, StudentID: studentID, Active: false, EffectiveAt: change.EffectiveAt})
+		intervals[key] = enrollmentState{lastEnded: &change.EffectiveAt}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+
+	result, err := s.ClassroomRepo.ApplyRoster(batch)
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = result
+	return report, nil
+}
+
+type enrollmentKey struct {
+	classroomID uuid.UUID
+	studentID   uuid.UUID
+}
+
+// enrollmentState is the start of the open interval, if any, and the end of the latest closed one
+type enrollmentState struct {
+	open      *time.Time
+	lastEnded *time.Time
+}
+
+// loadExisting fetches classrooms and students already imported under the sourcedIds in the export
+func (s *Service) loadExisting(roster *Roster) (map[string]models.Classroom, map[string]models.Student, error) {
+	classIDs := map[string]bool{}
+	for _, class := range roster.Classes {
+		classIDs[class.SourcedID] = true
+	}
+	userIDs := map[string]bool{}
+	for _, user := range roster.Users {
+		userIDs[user.SourcedID] = true
+	}
+	for _, enrollment := range roster.Enrollments {
+		classIDs[enrollment.ClassSourcedID] = true
+		userIDs[enrollment.UserSourcedID] = true
+	}
+
+	classrooms, err := s.ClassroomRepo.GetClassroomsBySourcedIDs(keys(classIDs))
+	if err != nil {
+		return nil, nil, err
+	}
+	students, err := s.ClassroomRepo.GetStudentsBySourcedIDs(keys(userIDs))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	classroomsBySourcedID := make(map[string]models.Classroom, len(classrooms))
+	for _, classroom := range classrooms {
+		classroomsBySourcedID[*classroom.SourcedID] = classroom
+	}
+	studentsBySourcedID := make(map[string]models.Student, len(students))
+	for _, student := range students {
+		studentsBySourcedID[*student.SourcedID] = student
+	}
+	return classroomsBySourcedID, studentsBySourcedID, nil
+}
+
+// loadExistingSchools fetches schools already imported under the school orgs in the export
+func (s *Service) loadExistingSchools(roster *Roster) (map[string]models.School, error) {
+	orgIDs := map[string]bool{}
+	for _, org := range roster.Orgs {
+		if org.Type == orgTypeSchool {
+			orgIDs[org.SourcedID] = true
+		}
+	}
+	schools, err := s.ClassroomRepo.GetSchoolsBySourcedIDs(keys(orgIDs))
+	if err != nil {
+		return nil, err
+	}
+	schoolsBySourcedID := make(map[string]models.School, len(schools))
+	for _, school := range schools {
+		schoolsBySourcedID[*school.SourcedID] = school
+	}
+	return schoolsBySourcedID, nil
+}
+
+// loadIntervals summarises the enrollment history of the existing classrooms
+func (s *Service) loadIntervals(classrooms map[string]models.Classroom) (map[enrollmentKey]enrollmentState, error) {
+	classroomIDs := make([]uuid.UUID, 0, len(classrooms))
+	for _, classroom := range classrooms {
+		classroomIDs = append(classroomIDs, classroom.ClassroomID)
+	}
+	enrollments, err := s.ClassroomRepo.GetEnrollmentsForClassrooms(classroomIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	intervals := map[enrollmentKey]enrollmentState{}
+	for _, enrollment := range enrollments {
+		key := enrollmentKey{enrollment.ClassroomID, enrollment.StudentID}
+		state := intervals[key]
+		if enrollment.UnenrolledAt == nil {
+			enrolledAt := enrollment.EnrolledAt
+			state.open = &enrolledAt
+		} else if state.lastEnded == nil || enrollment.UnenrolledAt.After(*state.lastEnded) {
+			state.lastEnded = enrollment.UnenrolledAt
+		}
+		intervals[key] = state
+	}
+	return intervals, nil
+}
+
+func keys(set map[string]bool) []string {
+	result := make([]string, 0, len(set))
+	for key := range set {
+		result = append(result, key)
+	}
+	return result
+}
@@ -0,0 +1,297 @@
+package oneroster
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+)
+
+// memoryRoster keeps what ApplyRoster writes, so that an import can be run again against
+// its own result
+type memoryRoster struct {
+	repository.ClassroomRepository
+	schools     map[uuid.UUID]models.School
+	classrooms  map[uuid.UUID]models.Classroom
+	students    map[uuid.UUID]models.Student
+	enrollments []models.ClassroomEnrollment
+	applied     int
+}
+
+func newMemoryRoster() *memoryRoster {
+	return &memoryRoster{
+		schools:    map[uuid.UUID]models.School{},
+		classrooms: map[uuid.UUID]models.Classroom{},
+		students:   map[uuid.UUID]models.Student{},
+	}
+}
+
+func (r *memoryRoster) ForTenant(uuid.UUID) repository.ClassroomRepository {
+	return r
+}
+
+func (r *memoryRoster) GetSchoolsBySourcedIDs(sourcedIDs []string) ([]models.School, error) {
+	var found []models.School
+	for _, school := range r.schools {
+		if contains(sourcedIDs, school.SourcedID) {
+			found = append(found, school)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryRoster) GetClassroomsBySourcedIDs(sourcedIDs []string) ([]models.Classroom, error) {
+	var found []models.Classroom
+	for _, classroom := range r.classrooms {
+		if contains(sourcedIDs, classroom.SourcedID) {
+			found = append(found, classroom)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryRoster) GetStudentsBySourcedIDs(sourcedIDs []string) ([]models.Student, error) {
+	var found []models.Student
+	for _, student := range r.students {
+		if contains(sourcedIDs, student.SourcedID) {
+			found = append(found, student)
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryRoster) GetEnrollmentsForClassrooms(classroomIDs []uuid.UUID) ([]models.ClassroomEnrollment, error) {
+	var found []models.ClassroomEnrollment
+	for _, enrollment := range r.enrollments {
+		for _, id := range classroomIDs {
+			if enrollment.ClassroomID == id {
+				found = append(found, enrollment)
+			}
+		}
+	}
+	return found, nil
+}
+
+func (r *memoryRoster) ApplyRoster(batch repository.RosterBatch) (*repository.RosterResult, error) {
+	r.applied++
+	result := &repository.RosterResult{}
+	for _, school := range batch.Schools {
+		r.schools[school.SchoolID] = school
+		result.SchoolsUpserted++
+	}
+	for _, classroom := range batch.Classrooms {
+		r.classrooms[classroom.ClassroomID] = classroom
+		result.ClassroomsUpserted++
+	}
+	for _, student := range batch.Students {
+		r.students[student.StudentID] = student
+		result.StudentsUpserted++
+	}
+	for _, change := range batch.Enrollments {
+		if change.Active {
+			r.enrollments = append(r.enrollments, models.ClassroomEnrollment{ClassroomID: change.ClassroomID, StudentID: change.StudentID, EnrolledAt: change.EffectiveAt})
+			result.Enrolled++
+			continue
+		}
+		for i := range r.enrollments {
+			e := &r.enrollments[i]
+			if e.ClassroomID == change.ClassroomID && e.StudentID == change.StudentID && e.UnenrolledAt == nil {
+				at := change.EffectiveAt
+				e.UnenrolledAt = &at
+				result.Unenrolled++
+			}
+		}
+	}
+	for _, id := range batch.ArchiveClassrooms {
+		classroom := r.classrooms[id]
+		now := time.Now()
+		classroom.ArchivedAt = &now
+		r.classrooms[id] = classroom
+		result.ClassroomsArchived++
+	}
+	return result, nil
+}
+
+func contains(values []string, value *string) bool {
+	for _, v := range values {
+		if value != nil && v == *value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestImport(t *testing.T) {
+	tenant := uuid.New()
+	cases := []struct {
+		name    string
+		replace map[string]string
+		invalid bool // the export is rejected as a whole
+		errors  []string
+		// counts of the first import: schools, classrooms and students created, enrollments opened
+		schools, classrooms, students, enrolled int
+	}{
+		{
+			name:    "valid",
+			schools: 1, classrooms: 1, students: 1, enrolled: 1,
+		},
+		{
+			name:    "malformed csv",
+			replace: map[string]string{FileEnrollments: "sourcedId,classSourcedId\n\"enr-1,class-1\n"},
+			invalid: true,
+		},
+		{
+			name:    "missing file",
+			replace: map[string]string{FileOrgs: ""},
+			invalid: true,
+		},
+		{
+			name:    "unknown columns",
+			replace: map[string]string{FileClasses: "sourcedId,title,schoolSourcedId,periods,subjects\nclass-1,Algebra,school-1,1,math\n"},
+			schools: 1, classrooms: 1, students: 1, enrolled: 1,
+		},
+		{
+			name: "duplicate sourcedIds",
+			replace: map[string]string{
+				FileUsers:       "sourcedId,givenName,role\nuser-1,Ada,student\nuser-1,Grace,student\n",
+				FileEnrollments: "sourcedId,classSourcedId,userSourcedId,role\nenr-1,class-1,user-1,student\nenr-1,class-1,user-1,student\n",
+			},
+			errors:  []string{"users.csv:3:user-1", "enrollments.csv:3:enr-1"},
+			schools: 1, classrooms: 1, students: 1, enrolled: 1,
+		},
+		{
+			name: "unknown references",
+			replace: map[string]string{
+				FileClasses:     "sourcedId,title,schoolSourcedId\nclass-1,Algebra,school-1\nclass-2,Geometry,school-9\n",
+				FileEnrollments: "sourcedId,classSourcedId,userSourcedId,role\nenr-1,class-1,user-1,student\nenr-2,class-2,user-1,student\nenr-3,class-1,user-9,student\n",
+			},
+			errors:  []string{"classes.csv:3:class-2", "enrollments.csv:3:enr-2", "enrollments.csv:4:enr-3"},
+			schools: 1, classrooms: 1, students: 1, enrolled: 1,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newMemoryRoster()
+			service := NewService(repo).ForTenant(tenant)
+
+			report, err := service.Import(export(tc.replace), false)
+			if tc.invalid {
+				var invalid *ValidationError
+				if !errors.As(err, &invalid) {
+					t.Fatalf("got %v, want a ValidationError", err)
+				}
+				if repo.applied != 0 {
+					t.Error("an invalid export was applied")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var rowErrors []string
+			for _, e := range report.Errors {
+				rowErrors = append(rowErrors, fmt.Sprintf("%s:%d:%s", e.File, e.Line, e.SourcedID))
+			}
+			if fmt.Sprint(rowErrors) != fmt.Sprint(tc.errors) {
+				t.Errorf("row errors %v, want %v", rowErrors, tc.errors)
+			}
+			if len(report.Schools.Create) != tc.schools || len(report.Classrooms.Create) != tc.classrooms ||
+				len(report.Students.Create) != tc.students || len(report.Enrollments.Enroll) != tc.enrolled {
+				t.Errorf("created %d schools, %d classrooms, %d students, %d enrollments; want %d, %d, %d, %d",
+					len(report.Schools.Create), len(report.Classrooms.Create), len(report.Students.Create), len(report.Enrollments.Enroll),
+					tc.schools, tc.classrooms, tc.students, tc.enrolled)
+			}
+			if report.Applied == nil || report.Applied.Enrolled != tc.enrolled {
+				t.Errorf("applied %+v", report.Applied)
+			}
+
+			// Importing the same export again changes nothing
+			again, err := service.Import(export(tc.replace), false)
+			if err != nil {
+				t.Fatalf("re-import: %v", err)
+			}
+			if n := len(again.Schools.Create) + len(again.Schools.Update) + len(again.Classrooms.Create) + len(again.Classrooms.Update) +
+				len(again.Students.Create) + len(again.Students.Update) + len(again.Enrollments.Enroll) + len(again.Enrollments.Unenroll); n != 0 {
+				t.Errorf("re-import made %d changes: %+v", n, again)
+			}
+			if again.Classrooms.Unchanged != tc.classrooms || again.Students.Unchanged != tc.students || again.Enrollments.Unchanged < tc.enrolled {
+				t.Errorf("re-import left %d classrooms, %d students, %d enrollments unchanged",
+					again.Classrooms.Unchanged, again.Students.Unchanged, again.Enrollments.Unchanged)
+			}
+		})
+	}
+}
+
+func TestImportUpserts(t *testing.T) {
+	repo := newMemoryRoster()
+	service := NewService(repo).ForTenant(uuid.New())
+	first, err := service.Import(export(nil), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	studentID := first.Students.Create[0].ID
+	classroomID := first.Classrooms.Create[0].ID
+
+	report, err := service.Import(export(map[string]string{
+		FileClasses:     "sourcedId,title,schoolSourcedId\nclass-1,Algebra II,school-1\n",
+		FileUsers:       "sourcedId,givenName,familyName,role\nuser-1,Ada,King,student\nuser-2,Grace,Hopper,student\n",
+		FileEnrollments: "sourcedId,classSourcedId,userSourcedId,role,status\nenr-1,class-1,user-1,student,tobedeleted\nenr-2,class-1,user-2,student,active\n",
+	}), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Classrooms.Update) != 1 || report.Classrooms.Update[0].ID != classroomID || *report.Classrooms.Update[0].PreviousName != "Algebra" {
+		t.Errorf("classroom updates %+v", report.Classrooms.Update)
+	}
+	if len(report.Students.Update) != 1 || report.Students.Update[0].ID != studentID || *report.Students.Update[0].PreviousName != "Ada Lovelace" {
+		t.Errorf("student updates %+v", report.Students.Update)
+	}
+	if len(report.Students.Create) != 1 || report.Students.Create[0].SourcedID != "user-2" {
+		t.Errorf("student creates %+v", report.Students.Create)
+	}
+	if len(report.Enrollments.Unenroll) != 1 || report.Enrollments.Unenroll[0].StudentID != studentID ||
+		len(report.Enrollments.Enroll) != 1 || report.Enrollments.Enroll[0].UserSourcedID != "user-2" {
+		t.Errorf("enrollments %+v", report.Enrollments)
+	}
+	if len(repo.classrooms) != 1 || repo.classrooms[classroomID].Name != "Algebra II" {
+		t.Errorf("classrooms %+v", repo.classrooms)
+	}
+	if len(repo.students) != 2 || *repo.students[studentID].Name != "Ada King" {
+		t.Errorf("students %+v", repo.students)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	repo := newMemoryRoster()
+	report, err := NewService(repo).ForTenant(uuid.New()).Import(export(nil), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Applied != nil || repo.applied != 0 {
+		t.Errorf("dry run applied the import: %+v", report)
+	}
+	if len(report.Students.Create) != 1 {
+		t.Errorf("dry run reported %+v", report.Students)
+	}
+}
+
+// Two tenants importing the same sourcedIds get rows of their own
+func TestImportIDsPerTenant(t *testing.T) {
+	a, err := NewService(newMemoryRoster()).ForTenant(uuid.New()).Import(export(nil), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := NewService(newMemoryRoster()).ForTenant(uuid.New()).Import(export(nil), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Students.Create[0].ID == b.Students.Create[0].ID {
+		t.Error("two tenants derived the same student ID")
+	}
+}
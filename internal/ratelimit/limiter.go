@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+)
+
+// Store keeps the token buckets. MemoryStore serves a single instance; a shared
+// backend such as Redis lets several instances enforce the same limits.
+type Store interface {
+	// Take removes a token from the bucket under key, creating it full if needed
+	Take(ctx context.Context, key string, limit config.RateLimit) (Result, error)
+}
+
+// Result is the state of a bucket after taking a token from it
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until the next token, when the request was not allowed
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+// Limiter enforces per-caller token-bucket limits on route groups
+type Limiter struct {
+	store Store
+}
+
+func NewLimiter(store Store) *Limiter {
+	return &Limiter{store: store}
+}
+
+// Limit returns a middleware limiting each caller within the named group. It goes after
+// the auth middleware so that callers are told apart by user or device key, not just IP.
+func (l *Limiter) Limit(group string, limit config.RateLimit) gin.HandlerFunc {
+	if limit.Requests == 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	return func(c *gin.Context) {
+		result, err := l.store.Take(c.Request.Context(), group+"|"+auth.Caller(c), limit)
+		if err != nil {
+			// an unavailable backend must not take the API down with it
+			slog.WarnContext(c.Request.Context(), "rate limiter failed, allowing request", "error", err)
+			c.Next()
+			return
+		}
+
+		// with stacked limits the headers describe the one closest to running out
+		if current := c.Writer.Header().Get("X-RateLimit-Remaining"); current == "" || !result.Allowed || tighter(result, current) {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Requests))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Header("X-RateLimit-Reset", strconv.Itoa(seconds(result.Reset)))
+		}
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(seconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
+
+func tighter(result Result, currentRemaining string) bool {
+	remaining, err := strconv.Atoi(currentRemaining)
+	return err != nil || result.Remaining < remaining
+}
+
+// seconds rounds up, so that clients waiting that long are not turned away again
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,77 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+)
+
+// sweepInterval is how often full buckets are dropped to bound memory
+const sweepInterval = time.Minute
+
+// MemoryStore keeps token buckets in process memory; limits are per instance
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// now is replaced by tests
+	now func() time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// per is how long the bucket takes to refill completely
+	per time.Duration
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]*bucket{}, lastSweep: time.Now(), now: time.Now}
+}
+
+// Take refills the bucket for the time since it was last used and removes a token
+func (s *MemoryStore) Take(_ context.Context, key string, limit config.RateLimit) (Result, error) {
+	capacity := float64(limit.Requests)
+	// in nanoseconds, not rounded: limits of more requests than their period has
+	// nanoseconds would otherwise refill every bucket in no time
+	perToken := math.Max(float64(limit.Per)/capacity, math.SmallestNonzeroFloat64)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+
+	s.sweep(now)
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now, per: limit.Per}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+float64(now.Sub(b.updated))/perToken)
+	b.updated = now
+
+	result := Result{Allowed: b.tokens >= 1}
+	if result.Allowed {
+		b.tokens--
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - b.tokens) * perToken))
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = time.Duration(math.Ceil((capacity - b.tokens) * perToken))
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely; they are recreated full when needed
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.per {
+			delete(s.buckets, key)
+		}
+	}
+}
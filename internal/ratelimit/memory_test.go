@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+)
+
+// clock is a stopped clock that tests move forward by hand
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestStore() (*MemoryStore, *clock) {
+	c := &clock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	s := NewMemoryStore()
+	s.now = func() time.Time { return c.now }
+	s.lastSweep = c.now
+	return s, c
+}
+
+func take(t *testing.T, s *MemoryStore, key string, limit config.RateLimit) Result {
+	t.Helper()
+	result, err := s.Take(context.Background(), key, limit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestMemoryStoreTake(t *testing.T) {
+	s, c := newTestStore()
+	limit := config.RateLimit{Requests: 3, Per: 3 * time.Second}
+
+	for want := 2; want >= 0; want-- {
+		result := take(t, s, "a", limit)
+		if !result.Allowed || result.Remaining != want {
+			t.Fatalf("got %+v, want allowed with %d remaining", result, want)
+		}
+	}
+	result := take(t, s, "a", limit)
+	if result.Allowed || result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("over the limit: got %+v, want RetryAfter 1s and Reset 3s", result)
+	}
+	if other := take(t, s, "b", limit); !other.Allowed || other.Remaining != 2 {
+		t.Errorf("another key: got %+v", other)
+	}
+
+	// a token refills every second, and a partial one shortens the wait
+	c.advance(400 * time.Millisecond)
+	if result := take(t, s, "a", limit); result.Allowed || result.RetryAfter != 600*time.Millisecond {
+		t.Errorf("after 400ms: got %+v, want RetryAfter 600ms", result)
+	}
+	c.advance(600 * time.Millisecond)
+	if result := take(t, s, "a", limit); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("after 1s: got %+v, want allowed", result)
+	}
+
+	// an idle bucket refills to its capacity, no further
+	c.advance(time.Hour)
+	if result := take(t, s, "a", limit); !result.Allowed || result.Remaining != 2 || result.Reset != time.Second {
+		t.Errorf("after an hour: got %+v, want 2 remaining", result)
+	}
+}
+
+func TestMemoryStoreSweeps(t *testing.T) {
+	s, c := newTestStore()
+	short := config.RateLimit{Requests: 1, Per: time.Second}
+	long := config.RateLimit{Requests: 1, Per: time.Hour}
+	take(t, s, "short", short)
+	take(t, s, "long", long)
+
+	// buckets are kept until the next sweep
+	c.advance(sweepInterval / 2)
+	take(t, s, "other", short)
+	if len(s.buckets) != 3 {
+		t.Fatalf("%d buckets before the sweep, want 3", len(s.buckets))
+	}
+
+	// full buckets are dropped; the long one is still refilling
+	c.advance(sweepInterval / 2)
+	take(t, s, "long", long)
+	if _, ok := s.buckets["short"]; ok {
+		t.Error("a full bucket was kept")
+	}
+	if _, ok := s.buckets["long"]; !ok {
+		t.Error("a refilling bucket was dropped")
+	}
+	// a dropped bucket comes back full
+	if result := take(t, s, "short", short); !result.Allowed {
+		t.Errorf("a swept bucket: got %+v", result)
+	}
+}
+
+// Limits of more requests than their period has nanoseconds leave less than a
+// nanosecond per token, which must not round down to zero
+func TestMemoryStoreSubNanosecondTokens(t *testing.T) {
+	s, c := newTestStore()
+	limit := config.RateLimit{Requests: 2, Per: time.Nanosecond}
+
+	for i := 0; i < 2; i++ {
+		if result := take(t, s, "a", limit); !result.Allowed {
+			t.Fatalf("request %d: got %+v, want allowed", i+1, result)
+		}
+	}
+	if result := take(t, s, "a", limit); result.Allowed || result.RetryAfter <= 0 {
+		t.Errorf("over the limit: got %+v, want denied with a RetryAfter", result)
+	}
+	c.advance(time.Nanosecond)
+	if result := take(t, s, "a", limit); !result.Allowed || result.Remaining != 1 {
+		t.Errorf("after the period: got %+v, want allowed with 1 remaining", result)
+	}
+}
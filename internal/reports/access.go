@@ -0,0 +1,115 @@
+package reports
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/analytics"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"gorm.io/gorm"
+)
+
+// resource names the IDs a report reads. The zero value is a report across the
+// whole tenant, e.g. quiz or question analytics, which needs tenant-wide access.
+type resource struct {
+	classroomID *uuid.UUID
+	sessionID   *uuid.UUID
+	studentID   *uuid.UUID
+	teacherID   *uuid.UUID
+}
+
+// authorize writes a 403 and returns false when the caller's access does not cover the resource
+func (h *Handler) authorize(c *gin.Context, res resource) bool {
+	allowed, err := h.tenantService(c).authorized(auth.UserAccess(c), res)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "the requested resource is outside your access"})
+		return false
+	}
+	return true
+}
+
+// authorized applies the access rules: tenant access sees everything, school and teacher
+// access see the sessions of their classrooms, a teacher also sees their own session
+// history, and student access only sees the student's own results.
+func (s *Service) authorized(access auth.Access, res resource) (bool, error) {
+	switch access.Level {
+	case auth.AccessTenant:
+		return true, nil
+	case auth.AccessStudent:
+		return res.studentID != nil && *res.studentID == *access.StudentID && res.sessionID == nil && res.teacherID == nil, nil
+	}
+
+	if res.teacherID != nil {
+		return access.Level == auth.AccessTeacher && *res.teacherID == *access.TeacherID &&
+			res.classroomID == nil && res.sessionID == nil, nil
+	}
+	if res.classroomID == nil && res.sessionID == nil {
+		return false, nil
+	}
+
+	classroomIDs, err := s.visibleClassrooms(access)
+	if err != nil {
+		return false, err
+	}
+	visible := make(map[uuid.UUID]bool, len(classroomIDs))
+	for _, id := range classroomIDs {
+		visible[id] = true
+	}
+
+	if res.classroomID != nil && !visible[*res.classroomID] {
+		return false, nil
+	}
+	if res.sessionID != nil {
+		session, err := s.SessionRepo.GetSessionByID(*res.sessionID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if !visible[session.ClassroomID] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// visibleClassrooms lists the classrooms of a school or teacher access
+func (s *Service) visibleClassrooms(access auth.Access) ([]uuid.UUID, error) {
+	switch access.Level {
+	case auth.AccessSchool:
+		return s.ClassroomRepo.GetSchoolClassroomIDs(*access.SchoolID)
+	case auth.AccessTeacher:
+		assignments, err := s.TeacherRepo.GetTeacherClassrooms(*access.TeacherID)
+		if err != nil {
+			return nil, err
+		}
+		classroomIDs := make([]uuid.UUID, len(assignments))
+		for i, assignment := range assignments {
+			classroomIDs[i] = assignment.ClassroomID
+		}
+		return classroomIDs, nil
+	}
+	return nil, nil
+}
+
+// queryRestriction is the mandatory filter of generic queries; nil for tenant-wide access
+func (s *Service) queryRestriction(access auth.Access) (*analytics.Restriction, error) {
+	switch access.Level {
+	case auth.AccessTenant:
+		return nil, nil
+	case auth.AccessStudent:
+		return &analytics.Restriction{StudentID: access.StudentID}, nil
+	}
+	classroomIDs, err := s.visibleClassrooms(access)
+	if err != nil {
+		return nil, err
+	}
+	return &analytics.Restriction{ClassroomIDs: classroomIDs}, nil
+}
@@ -1,6 +1,8 @@
 package reports
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,22 +10,42 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/rohanreddymelachervu/ingestor/internal/analytics"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
 	"github.com/rohanreddymelachervu/ingestor/internal/repository"
 )
 
 type Handler struct {
 	service *Service
+	limits  config.ReportsConfig
 }
 
-func NewHandler(s *Service) *Handler {
-	return &Handler{service: s}
+func NewHandler(s *Service, limits config.ReportsConfig) *Handler {
+	return &Handler{service: s, limits: limits}
 }
 
-// Helper function to parse pagination parameters
-func parsePaginationParams(c *gin.Context) repository.PaginationParams {
+// tenantService returns the service scoped to the caller's tenant
+func (h *Handler) tenantService(c *gin.Context) *Service {
+	return h.service.ForTenant(auth.TenantID(c)).WithContext(c.Request.Context())
+}
+
+// parseDenominator reads the denominator query parameter (enrolled or present, default enrolled)
+func parseDenominator(c *gin.Context) (repository.Denominator, bool) {
+	denominator := repository.Denominator(c.DefaultQuery("denominator", string(repository.DenominatorEnrolled)))
+	switch denominator {
+	case repository.DenominatorEnrolled, repository.DenominatorPresent:
+		return denominator, true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "denominator must be enrolled or present"})
+		return "", false
+	}
+}
+
+// pagination parses the page and page_size query parameters within the configured limits
+func (h *Handler) pagination(c *gin.Context) repository.PaginationParams {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
-	return repository.NewPaginationParams(page, pageSize)
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(h.limits.DefaultPageSize)))
+	return repository.NewPaginationParams(page, min(pageSize, h.limits.MaxPageSize))
 }
 
 func (h *Handler) GetActiveParticipants(c *gin.Context) {
@@ -47,9 +69,13 @@ func (h *Handler) GetActiveParticipants(c *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	pagination := parsePaginationParams(c)
+	pagination := h.pagination(c)
 
-	data, err := h.service.GetActiveParticipants(sessionID, timeRange, pagination)
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetActiveParticipants(sessionID, timeRange, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -71,7 +97,11 @@ func (h *Handler) GetQuestionsPerMinute(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetQuestionsPerMinute(sessionID)
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetQuestionsPerMinute(sessionID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -101,7 +131,11 @@ func (h *Handler) GetStudentPerformance(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetStudentPerformance(studentID, classroomID)
+	if !h.authorize(c, resource{studentID: &studentID, classroomID: &classroomID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetStudentPerformance(studentID, classroomID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -130,7 +164,11 @@ func (h *Handler) GetClassroomEngagement(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetClassroomEngagement(classroomID, dateRange)
+	if !h.authorize(c, resource{classroomID: &classroomID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetClassroomEngagement(classroomID, dateRange)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -152,7 +190,11 @@ func (h *Handler) GetContentEffectiveness(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetContentEffectiveness(quizID)
+	if !h.authorize(c, resource{}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetContentEffectiveness(quizID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -182,7 +224,16 @@ func (h *Handler) GetResponseRate(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetResponseRate(sessionID, questionID)
+	denominator, ok := parseDenominator(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetResponseRate(sessionID, questionID, denominator)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -212,7 +263,11 @@ func (h *Handler) GetLatencyAnalysis(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetLatencyAnalysis(sessionID, questionID)
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetLatencyAnalysis(sessionID, questionID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -242,7 +297,46 @@ func (h *Handler) GetTimeoutAnalysis(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetTimeoutAnalysis(sessionID, questionID)
+	denominator, ok := parseDenominator(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetTimeoutAnalysis(sessionID, questionID, denominator)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetSessionAttendance handles GET /api/reports/session-attendance
+func (h *Handler) GetSessionAttendance(c *gin.Context) {
+	sessionIDStr := c.Query("session_id")
+	if sessionIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "session_id is required"})
+		return
+	}
+
+	sessionID, err := uuid.Parse(sessionIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session_id format"})
+		return
+	}
+
+	// Parse pagination parameters
+	pagination := h.pagination(c)
+
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetSessionAttendance(sessionID, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -264,7 +358,16 @@ func (h *Handler) GetCompletionRate(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetCompletionRate(sessionID)
+	denominator, ok := parseDenominator(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetCompletionRate(sessionID, denominator)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -286,7 +389,16 @@ func (h *Handler) GetDropoffAnalysis(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetDropoffAnalysis(sessionID)
+	denominator, ok := parseDenominator(c)
+	if !ok {
+		return
+	}
+
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetDropoffAnalysis(sessionID, denominator)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -310,9 +422,13 @@ func (h *Handler) GetStudentPerformanceList(c *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	pagination := parsePaginationParams(c)
+	pagination := h.pagination(c)
 
-	data, err := h.service.GetStudentPerformanceList(classroomID, pagination)
+	if !h.authorize(c, resource{classroomID: &classroomID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetStudentPerformanceList(classroomID, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -343,9 +459,13 @@ func (h *Handler) GetClassroomEngagementHistory(c *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	pagination := parsePaginationParams(c)
+	pagination := h.pagination(c)
+
+	if !h.authorize(c, resource{classroomID: &classroomID}) {
+		return
+	}
 
-	data, err := h.service.GetClassroomEngagementHistory(classroomID, dateRange, pagination)
+	data, err := h.tenantService(c).GetClassroomEngagementHistory(classroomID, dateRange, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -394,7 +514,11 @@ func (h *Handler) GetQuizSummary(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetQuizSummary(quizID)
+	if !h.authorize(c, resource{}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetQuizSummary(quizID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -416,7 +540,11 @@ func (h *Handler) GetQuestionAnalysis(c *gin.Context) {
 		return
 	}
 
-	data, err := h.service.GetQuestionAnalysis(questionID)
+	if !h.authorize(c, resource{}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetQuestionAnalysis(questionID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -439,9 +567,13 @@ func (h *Handler) GetQuizQuestionsList(c *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	pagination := parsePaginationParams(c)
+	pagination := h.pagination(c)
+
+	if !h.authorize(c, resource{}) {
+		return
+	}
 
-	data, err := h.service.GetQuizQuestionsList(quizID, pagination)
+	data, err := h.tenantService(c).GetQuizQuestionsList(quizID, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -464,9 +596,13 @@ func (h *Handler) GetClassroomSessions(c *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	pagination := parsePaginationParams(c)
+	pagination := h.pagination(c)
 
-	data, err := h.service.GetClassroomSessions(classroomID, pagination)
+	if !h.authorize(c, resource{classroomID: &classroomID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetClassroomSessions(classroomID, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -489,9 +625,13 @@ func (h *Handler) GetQuizSessions(c *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	pagination := parsePaginationParams(c)
+	pagination := h.pagination(c)
 
-	data, err := h.service.GetQuizSessions(quizID, pagination)
+	if !h.authorize(c, resource{}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetQuizSessions(quizID, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -514,9 +654,13 @@ func (h *Handler) GetClassroomStudentRankings(c *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	pagination := parsePaginationParams(c)
+	pagination := h.pagination(c)
 
-	data, err := h.service.GetClassroomStudentRankings(classroomID, pagination)
+	if !h.authorize(c, resource{classroomID: &classroomID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetClassroomStudentRankings(classroomID, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -539,9 +683,13 @@ func (h *Handler) GetSessionStudentRankings(c *gin.Context) {
 	}
 
 	// Parse pagination parameters
-	pagination := parsePaginationParams(c)
+	pagination := h.pagination(c)
+
+	if !h.authorize(c, resource{sessionID: &sessionID}) {
+		return
+	}
 
-	data, err := h.service.GetSessionStudentRankings(sessionID, pagination)
+	data, err := h.tenantService(c).GetSessionStudentRankings(sessionID, pagination)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -560,7 +708,11 @@ func (h *Handler) GetClassroomOverview(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.GetClassroomOverview(classroomID)
+	if !h.authorize(c, resource{classroomID: &classroomID}) {
+		return
+	}
+
+	response, err := h.tenantService(c).GetClassroomOverview(classroomID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get classroom overview"})
 		return
@@ -577,7 +729,11 @@ func (h *Handler) GetClassPerformanceSummary(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.GetClassPerformanceSummary(classroomID)
+	if !h.authorize(c, resource{classroomID: &classroomID}) {
+		return
+	}
+
+	response, err := h.tenantService(c).GetClassPerformanceSummary(classroomID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get class performance summary"})
 		return
@@ -600,7 +756,11 @@ func (h *Handler) GetStudentActivitySummary(c *gin.Context) {
 		return
 	}
 
-	response, err := h.service.GetStudentActivitySummary(studentID, classroomID)
+	if !h.authorize(c, resource{studentID: &studentID, classroomID: &classroomID}) {
+		return
+	}
+
+	response, err := h.tenantService(c).GetStudentActivitySummary(studentID, classroomID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get student activity summary"})
 		return
@@ -609,6 +769,54 @@ func (h *Handler) GetStudentActivitySummary(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// GetTeacherSummary handles GET /api/reports/teacher-summary
+func (h *Handler) GetTeacherSummary(c *gin.Context) {
+	// Parse pagination parameters
+	pagination := h.pagination(c)
+
+	if !h.authorize(c, resource{}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetTeacherSummary(pagination)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetTeacherSessions handles GET /api/reports/teacher-sessions
+func (h *Handler) GetTeacherSessions(c *gin.Context) {
+	teacherIDStr := c.Query("teacher_id")
+	if teacherIDStr == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "teacher_id is required"})
+		return
+	}
+
+	teacherID, err := uuid.Parse(teacherIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid teacher_id format"})
+		return
+	}
+
+	// Parse pagination parameters
+	pagination := h.pagination(c)
+
+	if !h.authorize(c, resource{teacherID: &teacherID}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetTeacherSessions(teacherID, pagination)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
 // GenericQuery handles cube.dev-style analytics queries with measures and dimensions
 func (h *Handler) GenericQuery(c *gin.Context) {
 	var request analytics.QueryRequest
@@ -623,15 +831,33 @@ func (h *Handler) GenericQuery(c *gin.Context) {
 		return
 	}
 
+	// Callers limited to a school, teacher or student get their access as a mandatory filter
+	restriction, err := h.tenantService(c).queryRestriction(auth.UserAccess(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve access", "details": err.Error()})
+		return
+	}
+
+	// No query returns more than the configured number of rows
+	if request.Limit <= 0 || request.Limit > h.limits.QueryMaxRows {
+		request.Limit = h.limits.QueryMaxRows
+	}
+
 	// Generate SQL from the query request
-	sql, err := request.BuildSQL()
+	sql, err := request.BuildSQL(restriction)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to build query", "details": err.Error()})
 		return
 	}
 
-	// Execute the generated SQL
-	results, err := h.service.ExecuteGenericQuery(sql)
+	// Execute the generated SQL; Postgres cancels it at the configured timeout
+	ctx, cancel := context.WithTimeout(c.Request.Context(), h.limits.QueryTimeout)
+	defer cancel()
+	results, err := h.service.ForTenant(auth.TenantID(c)).WithContext(ctx).ExecuteGenericQuery(sql)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Query timed out", "details": "queries may run for " + h.limits.QueryTimeout.String()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to execute query", "details": err.Error()})
 		return
@@ -654,3 +880,29 @@ func (h *Handler) GenericQuery(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// GetProvisioningReconciliation handles GET /api/reports/provisioning-reconciliation
+func (h *Handler) GetProvisioningReconciliation(c *gin.Context) {
+	entityType := c.Query("entity_type")
+	switch entityType {
+	case "", "session", "question", "student", "teacher":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "entity_type must be session, question, student or teacher"})
+		return
+	}
+
+	// Parse pagination parameters
+	pagination := h.pagination(c)
+
+	if !h.authorize(c, resource{}) {
+		return
+	}
+
+	data, err := h.tenantService(c).GetProvisioningReconciliation(entityType, pagination)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
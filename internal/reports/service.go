@@ -1,22 +1,57 @@
 package reports
 
 import (
+	"context"
 	"math"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/grading"
+	"github.com/rohanreddymelachervu/ingestor/internal/metrics"
 	"github.com/rohanreddymelachervu/ingestor/internal/repository"
 )
 
 type Service struct {
-	EventRepo     repository.EventRepository
-	ClassroomRepo repository.ClassroomRepository
+	EventRepo        repository.EventRepository
+	ClassroomRepo    repository.ClassroomRepository
+	SessionRepo      repository.SessionRepository
+	TeacherRepo      repository.TeacherRepository
+	ProvisioningRepo repository.ProvisioningRepository
 }
 
-func NewService(eventRepo repository.EventRepository, classroomRepo repository.ClassroomRepository) *Service {
+func NewService(eventRepo repository.EventRepository, classroomRepo repository.ClassroomRepository,
+	sessionRepo repository.SessionRepository, teacherRepo repository.TeacherRepository,
+	provisioningRepo repository.ProvisioningRepository) *Service {
 	return &Service{
-		EventRepo:     eventRepo,
-		ClassroomRepo: classroomRepo,
+		EventRepo:        eventRepo,
+		ClassroomRepo:    classroomRepo,
+		SessionRepo:      sessionRepo,
+		TeacherRepo:      teacherRepo,
+		ProvisioningRepo: provisioningRepo,
+	}
+}
+
+// ForTenant returns a copy of the service that only reports on the tenant's data
+func (s *Service) ForTenant(tenantID uuid.UUID) *Service {
+	return &Service{
+		EventRepo:        s.EventRepo.ForTenant(tenantID),
+		ClassroomRepo:    s.ClassroomRepo.ForTenant(tenantID),
+		SessionRepo:      s.SessionRepo.ForTenant(tenantID),
+		TeacherRepo:      s.TeacherRepo.ForTenant(tenantID),
+		ProvisioningRepo: s.ProvisioningRepo.ForTenant(tenantID),
+	}
+}
+
+// WithContext returns a copy of the service whose queries run under ctx, so they are traced
+// as part of the request
+func (s *Service) WithContext(ctx context.Context) *Service {
+	return &Service{
+		EventRepo:        s.EventRepo.WithContext(ctx),
+		ClassroomRepo:    s.ClassroomRepo.WithContext(ctx),
+		SessionRepo:      s.SessionRepo.WithContext(ctx),
+		TeacherRepo:      s.TeacherRepo.WithContext(ctx),
+		ProvisioningRepo: s.ProvisioningRepo.WithContext(ctx),
 	}
 }
 
@@ -135,8 +170,8 @@ func (s *Service) GetContentEffectiveness(quizID uuid.UUID) (interface{}, error)
 	return response, nil
 }
 
-func (s *Service) GetResponseRate(sessionID, questionID uuid.UUID) (interface{}, error) {
-	data, err := s.EventRepo.GetResponseRate(sessionID, questionID)
+func (s *Service) GetResponseRate(sessionID, questionID uuid.UUID, denominator repository.Denominator) (interface{}, error) {
+	data, err := s.EventRepo.GetResponseRate(sessionID, questionID, denominator)
 	if err != nil {
 		return nil, err
 	}
@@ -144,6 +179,7 @@ func (s *Service) GetResponseRate(sessionID, questionID uuid.UUID) (interface{},
 	response := map[string]interface{}{
 		"question_id":       data.QuestionID,
 		"session_id":        data.SessionID,
+		"denominator":       denominator,
 		"students_received": data.StudentsReceived,
 		"students_answered": data.StudentsAnswered,
 		"response_rate":     data.ResponseRate,
@@ -177,20 +213,25 @@ func (s *Service) GetLatencyAnalysis(sessionID, questionID uuid.UUID) (interface
 	return response, nil
 }
 
-func (s *Service) GetTimeoutAnalysis(sessionID, questionID uuid.UUID) (interface{}, error) {
-	data, err := s.EventRepo.GetTimeoutAndSkippedRate(sessionID, questionID)
+func (s *Service) GetTimeoutAnalysis(sessionID, questionID uuid.UUID, denominator repository.Denominator) (interface{}, error) {
+	data, err := s.EventRepo.GetTimeoutAndSkippedRate(sessionID, questionID, denominator)
 	if err != nil {
 		return nil, err
 	}
 
 	response := map[string]interface{}{
-		"question_id":    data.QuestionID,
-		"session_id":     data.SessionID,
-		"total_students": data.TotalStudents,
-		"timeout_count":  data.TimeoutCount,
-		"skipped_count":  data.SkippedCount,
-		"timeout_rate":   data.TimeoutRate,
-		"skipped_rate":   data.SkippedRate,
+		"question_id":      data.QuestionID,
+		"session_id":       data.SessionID,
+		"denominator":      denominator,
+		"total_students":   data.TotalStudents,
+		"answered_count":   data.AnsweredCount,
+		"timeout_count":    data.TimeoutCount,
+		"skipped_count":    data.SkippedCount,
+		"absent_count":     data.AbsentCount,
+		"timeout_rate":     data.TimeoutRate,
+		"skipped_rate":     data.SkippedRate,
+		"absent_rate":      data.AbsentRate,
+		"presence_tracked": data.PresenceTracked,
 		"analysis": map[string]interface{}{
 			"difficulty_indicator": getDifficultyIndicator(data.TimeoutRate, data.SkippedRate),
 			"recommendation":       getTimeoutRecommendation(data.TimeoutRate),
@@ -200,14 +241,51 @@ func (s *Service) GetTimeoutAnalysis(sessionID, questionID uuid.UUID) (interface
 	return response, nil
 }
 
-func (s *Service) GetCompletionRate(sessionID uuid.UUID) (interface{}, error) {
-	data, err := s.EventRepo.GetCompletionRate(sessionID)
+// GetSessionAttendance reports who was in the session, built from STUDENT_JOINED / STUDENT_LEFT events
+func (s *Service) GetSessionAttendance(sessionID uuid.UUID, pagination repository.PaginationParams) (interface{}, error) {
+	paginatedData, err := s.EventRepo.GetSessionAttendance(sessionID, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	joined := 0
+	for _, student := range paginatedData.Data {
+		if student.JoinCount > 0 {
+			joined++
+		}
+	}
+
+	response := map[string]interface{}{
+		"session_id": sessionID,
+		"pagination": map[string]interface{}{
+			"page":         paginatedData.Page,
+			"page_size":    paginatedData.PageSize,
+			"total_count":  paginatedData.TotalCount,
+			"total_pages":  paginatedData.TotalPages,
+			"has_more":     paginatedData.HasMore,
+			"has_previous": paginatedData.HasPrevious,
+		},
+		"students": paginatedData.Data,
+		"summary": map[string]interface{}{
+			"total_students":  paginatedData.TotalCount,
+			"page_students":   len(paginatedData.Data),
+			"page_joined":     joined,
+			"page_never_seen": len(paginatedData.Data) - joined,
+		},
+	}
+
+	return response, nil
+}
+
+func (s *Service) GetCompletionRate(sessionID uuid.UUID, denominator repository.Denominator) (interface{}, error) {
+	data, err := s.EventRepo.GetCompletionRate(sessionID, denominator)
 	if err != nil {
 		return nil, err
 	}
 
 	response := map[string]interface{}{
 		"session_id":         data.SessionID,
+		"denominator":        denominator,
 		"total_students":     data.TotalStudents,
 		"completed_students": data.CompletedStudents,
 		"completion_rate":    data.CompletionRate,
@@ -222,14 +300,15 @@ func (s *Service) GetCompletionRate(sessionID uuid.UUID) (interface{}, error) {
 	return response, nil
 }
 
-func (s *Service) GetDropoffAnalysis(sessionID uuid.UUID) (interface{}, error) {
-	dropoffPoints, err := s.EventRepo.GetDropoffPoints(sessionID)
+func (s *Service) GetDropoffAnalysis(sessionID uuid.UUID, denominator repository.Denominator) (interface{}, error) {
+	dropoffPoints, err := s.EventRepo.GetDropoffPoints(sessionID, denominator)
 	if err != nil {
 		return nil, err
 	}
 
 	response := map[string]interface{}{
 		"session_id":     sessionID,
+		"denominator":    denominator,
 		"dropoff_points": dropoffPoints,
 		"analysis": map[string]interface{}{
 			"critical_points": getCriticalDropoffPoints(dropoffPoints),
@@ -430,17 +509,27 @@ func (s *Service) GetQuestionAnalysis(questionID uuid.UUID) (interface{}, error)
 	response := map[string]interface{}{
 		"question_id": analysis.QuestionID,
 		"quiz_id":     analysis.QuizID,
+		"question": map[string]interface{}{
+			"text":     analysis.QuestionText,
+			"options":  analysis.Options,
+			"position": analysis.Position,
+			"points":   analysis.Points,
+			"version":  analysis.Version,
+		},
 		"usage_stats": map[string]interface{}{
 			"total_attempts":   analysis.TotalAttempts,
 			"correct_attempts": analysis.CorrectAttempts,
 			"usage_count":      analysis.UsageCount,
 		},
+		"question_type": analysis.QuestionType,
 		"performance_metrics": map[string]interface{}{
 			"accuracy_rate":         analysis.AccuracyRate,
+			"average_score":         analysis.AverageScore,
 			"average_response_time": analysis.AverageResponseTime,
 			"difficulty_rating":     analysis.DifficultyRating,
 		},
-		"answer_distribution": analysis.AnswerDistribution,
+		"answer_distribution":         analysis.AnswerDistribution,
+		"labeled_answer_distribution": labelAnswerDistribution(analysis),
 		"insights": map[string]interface{}{
 			"difficulty_level": getDifficultyInsight(analysis.AccuracyRate),
 			"response_quality": getResponseQuality(analysis.AverageResponseTime),
@@ -581,6 +670,45 @@ func (s *Service) GetSessionStudentRankings(sessionID uuid.UUID, pagination repo
 	return response, nil
 }
 
+// labelAnswerDistribution pairs each submitted answer with its option label, in option
+// order, followed by the answers matching no option in sorted order. Answers are matched
+// to options the way they are graded, so "b" and " B" count towards option B; answers to
+// questions without options are left as submitted.
+func labelAnswerDistribution(analysis *repository.QuestionAnalysisData) []map[string]interface{} {
+	counts := make(map[string]int, len(analysis.AnswerDistribution))
+	for answer, count := range analysis.AnswerDistribution {
+		if len(analysis.Options) > 0 {
+			answer = grading.OptionKey(answer)
+		}
+		counts[answer] += count
+	}
+
+	labeled := []map[string]interface{}{}
+	for _, opt := range analysis.Options {
+		key := grading.OptionKey(opt.Key)
+		labeled = append(labeled, map[string]interface{}{
+			"answer": opt.Key,
+			"label":  opt.Label,
+			"count":  counts[key],
+		})
+		delete(counts, key)
+	}
+
+	unlisted := make([]string, 0, len(counts))
+	for answer := range counts {
+		unlisted = append(unlisted, answer)
+	}
+	sort.Strings(unlisted)
+	for _, answer := range unlisted {
+		labeled = append(labeled, map[string]interface{}{
+			"answer": answer,
+			"label":  nil,
+			"count":  counts[answer],
+		})
+	}
+	return labeled
+}
+
 // Helper functions for insights and analysis
 func getPerformanceRating(score float64) string {
 	if score >= 90 {
@@ -730,6 +858,59 @@ func (s *Service) GetStudentActivitySummary(studentID, classroomID uuid.UUID) (i
 	return response, nil
 }
 
+// GetTeacherSummary lists every active teacher with sessions taught, pacing and average class accuracy
+func (s *Service) GetTeacherSummary(pagination repository.PaginationParams) (interface{}, error) {
+	paginatedData, err := s.EventRepo.GetTeacherSummaries(pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	response := map[string]interface{}{
+		"pagination": map[string]interface{}{
+			"page":         paginatedData.Page,
+			"page_size":    paginatedData.PageSize,
+			"total_count":  paginatedData.TotalCount,
+			"total_pages":  paginatedData.TotalPages,
+			"has_more":     paginatedData.HasMore,
+			"has_previous": paginatedData.HasPrevious,
+		},
+		"teachers": paginatedData.Data,
+		"summary": map[string]interface{}{
+			"total_teachers": paginatedData.TotalCount,
+			"page_teachers":  len(paginatedData.Data),
+		},
+	}
+
+	return response, nil
+}
+
+// GetTeacherSessions lists the sessions attributed to one teacher with per-session pacing
+func (s *Service) GetTeacherSessions(teacherID uuid.UUID, pagination repository.PaginationParams) (interface{}, error) {
+	paginatedData, err := s.EventRepo.GetTeacherSessions(teacherID, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	response := map[string]interface{}{
+		"teacher_id": teacherID,
+		"pagination": map[string]interface{}{
+			"page":         paginatedData.Page,
+			"page_size":    paginatedData.PageSize,
+			"total_count":  paginatedData.TotalCount,
+			"total_pages":  paginatedData.TotalPages,
+			"has_more":     paginatedData.HasMore,
+			"has_previous": paginatedData.HasPrevious,
+		},
+		"sessions": paginatedData.Data,
+		"summary": map[string]interface{}{
+			"total_sessions": paginatedData.TotalCount,
+			"page_sessions":  len(paginatedData.Data),
+		},
+	}
+
+	return response, nil
+}
+
 // NEW: Additional helper functions for overview insights
 
 func getActivityLevel(recentSessions, totalSessions int) string {
@@ -900,5 +1081,44 @@ func calculateQuizVarietyScore(uniqueQuizzes, totalSessions int) float64 {
 
 // ExecuteGenericQuery executes a generic SQL query for cube.dev-style analytics
 func (s *Service) ExecuteGenericQuery(sql string) ([]map[string]interface{}, error) {
-	return s.EventRepo.ExecuteGenericQuery(sql)
+	start := time.Now()
+	results, err := s.EventRepo.ExecuteGenericQuery(sql)
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	metrics.QueryDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	return results, err
+}
+
+// GetProvisioningReconciliation lists auto-created placeholders that still need real metadata
+func (s *Service) GetProvisioningReconciliation(entityType string, pagination repository.PaginationParams) (interface{}, error) {
+	paginatedData, err := s.ProvisioningRepo.GetUnreconciledEntities(entityType, pagination)
+	if err != nil {
+		return nil, err
+	}
+
+	byType := map[string]int{}
+	for _, stub := range paginatedData.Data {
+		byType[stub.EntityType]++
+	}
+
+	response := map[string]interface{}{
+		"entity_type": entityType,
+		"pagination": map[string]interface{}{
+			"page":         paginatedData.Page,
+			"page_size":    paginatedData.PageSize,
+			"total_count":  paginatedData.TotalCount,
+			"total_pages":  paginatedData.TotalPages,
+			"has_more":     paginatedData.HasMore,
+			"has_previous": paginatedData.HasPrevious,
+		},
+		"entities": paginatedData.Data,
+		"summary": map[string]interface{}{
+			"total_unreconciled": paginatedData.TotalCount,
+			"page_by_type":       byType,
+		},
+	}
+
+	return response, nil
 }
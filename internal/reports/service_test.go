@@ -0,0 +1,50 @@
+package reports
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+)
+
+func TestLabelAnswerDistribution(t *testing.T) {
+	analysis := &repository.QuestionAnalysisData{
+		Options: models.QuestionOptions{{Key: "A", Label: "Mitochondria"}, {Key: "B", Label: "Nucleus"}, {Key: "C", Label: "Ribosome"}},
+		AnswerDistribution: map[string]int{
+			"A": 4, "b": 2, " B": 1, "B": 3, "E": 1, "D": 2, "z": 1,
+		},
+	}
+	type row struct {
+		answer string
+		label  interface{}
+		count  int
+	}
+	want := []row{
+		{"A", "Mitochondria", 4},
+		{"B", "Nucleus", 6},
+		{"C", "Ribosome", 0},
+		{"D", nil, 2},
+		{"E", nil, 1},
+		{"Z", nil, 1},
+	}
+
+	// the unlisted answers come from a map, so check the order holds across runs
+	for run := 0; run < 20; run++ {
+		var got []row
+		for _, entry := range labelAnswerDistribution(analysis) {
+			got = append(got, row{entry["answer"].(string), entry["label"], entry["count"].(int)})
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
+
+func TestLabelAnswerDistributionWithoutOptions(t *testing.T) {
+	analysis := &repository.QuestionAnalysisData{AnswerDistribution: map[string]int{"paris": 2, "Paris": 1}}
+	got := labelAnswerDistribution(analysis)
+	if len(got) != 2 || got[0]["answer"] != "Paris" || got[1]["answer"] != "paris" {
+		t.Errorf("free-text answers must stay as submitted, in sorted order: %v", got)
+	}
+}
@@ -1,54 +1,155 @@
 package repository
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rohanreddymelachervu/ingestor/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository implementations
 type eventRepository struct {
-	db *gorm.DB
+	tenantScope
 }
 
 type quizRepository struct {
-	db *gorm.DB
+	tenantScope
 }
 
 type sessionRepository struct {
-	db *gorm.DB
+	tenantScope
 }
 
 type classroomRepository struct {
+	tenantScope
+}
+
+type teacherRepository struct {
+	tenantScope
+}
+
+type provisioningRepository struct {
+	tenantScope
+}
+
+type tenantRepository struct {
 	db *gorm.DB
 }
 
+type auditRepository struct {
+	tenantScope
+}
+
 // Constructor functions
 func NewEventRepository(db *gorm.DB) EventRepository {
-	return &eventRepository{db: db}
+	return &eventRepository{tenantScope{db: db}}
 }
 
 func NewQuizRepository(db *gorm.DB) QuizRepository {
-	return &quizRepository{db: db}
+	return &quizRepository{tenantScope{db: db}}
 }
 
 func NewSessionRepository(db *gorm.DB) SessionRepository {
-	return &sessionRepository{db: db}
+	return &sessionRepository{tenantScope{db: db}}
 }
 
 func NewClassroomRepository(db *gorm.DB) ClassroomRepository {
-	return &classroomRepository{db: db}
+	return &classroomRepository{tenantScope{db: db}}
+}
+
+func NewTeacherRepository(db *gorm.DB) TeacherRepository {
+	return &teacherRepository{tenantScope{db: db}}
+}
+
+func NewProvisioningRepository(db *gorm.DB) ProvisioningRepository {
+	return &provisioningRepository{tenantScope{db: db}}
+}
+
+func NewTenantRepository(db *gorm.DB) TenantRepository {
+	return &tenantRepository{db: db}
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{tenantScope{db: db}}
+}
+
+// Tenant scoping - each repository only sees the rows of its tenant
+func (r *eventRepository) ForTenant(tenantID uuid.UUID) EventRepository {
+	return &eventRepository{tenantScope{db: r.db, tenantID: tenantID}}
+}
+
+func (r *eventRepository) WithContext(ctx context.Context) EventRepository {
+	return &eventRepository{r.withContext(ctx)}
+}
+
+func (r *quizRepository) ForTenant(tenantID uuid.UUID) QuizRepository {
+	return &quizRepository{tenantScope{db: r.db, tenantID: tenantID}}
+}
+
+func (r *quizRepository) WithContext(ctx context.Context) QuizRepository {
+	return &quizRepository{r.withContext(ctx)}
+}
+
+func (r *sessionRepository) ForTenant(tenantID uuid.UUID) SessionRepository {
+	return &sessionRepository{tenantScope{db: r.db, tenantID: tenantID}}
+}
+
+func (r *sessionRepository) WithContext(ctx context.Context) SessionRepository {
+	return &sessionRepository{r.withContext(ctx)}
+}
+
+func (r *classroomRepository) ForTenant(tenantID uuid.UUID) ClassroomRepository {
+	return &classroomRepository{tenantScope{db: r.db, tenantID: tenantID}}
+}
+
+func (r *classroomRepository) WithContext(ctx context.Context) ClassroomRepository {
+	return &classroomRepository{r.withContext(ctx)}
+}
+
+func (r *teacherRepository) ForTenant(tenantID uuid.UUID) TeacherRepository {
+	return &teacherRepository{tenantScope{db: r.db, tenantID: tenantID}}
+}
+
+func (r *teacherRepository) WithContext(ctx context.Context) TeacherRepository {
+	return &teacherRepository{r.withContext(ctx)}
+}
+
+func (r *provisioningRepository) ForTenant(tenantID uuid.UUID) ProvisioningRepository {
+	return &provisioningRepository{tenantScope{db: r.db, tenantID: tenantID}}
+}
+
+func (r *provisioningRepository) WithContext(ctx context.Context) ProvisioningRepository {
+	return &provisioningRepository{r.withContext(ctx)}
+}
+
+func (r *auditRepository) ForTenant(tenantID uuid.UUID) AuditRepository {
+	return &auditRepository{tenantScope{db: r.db, tenantID: tenantID}}
 }
 
 // EventRepository implementations
 func (r *eventRepository) SaveQuestionPublishedEvent(event *models.QuestionPublishedEvent) error {
+	event.TenantID = r.tenantID
 	return r.db.Create(event).Error
 }
 
 func (r *eventRepository) SaveAnswerSubmittedEvent(event *models.AnswerSubmittedEvent) error {
+	event.TenantID = r.tenantID
+	return r.db.Create(event).Error
+}
+
+func (r *eventRepository) SavePresenceEvent(event *models.SessionPresenceEvent) error {
+	event.TenantID = r.tenantID
+	return r.db.Create(event).Error
+}
+
+func (r *eventRepository) SaveQuestionSkippedEvent(event *models.QuestionSkippedEvent) error {
+	event.TenantID = r.tenantID
 	return r.db.Create(event).Error
 }
 
@@ -59,7 +160,7 @@ func (r *eventRepository) GetActiveParticipants(sessionID uuid.UUID, timeRange t
 	cutoffTime := time.Now().Add(-timeRange)
 
 	// First, get the total count for pagination
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(DISTINCT s.student_id)
 		FROM answer_submitted_events ase
 		JOIN students s ON ase.student_id = s.student_id
@@ -71,7 +172,7 @@ func (r *eventRepository) GetActiveParticipants(sessionID uuid.UUID, timeRange t
 	}
 
 	// Then get the paginated results
-	err = r.db.Raw(`
+	err = r.raw(`
 		SELECT 
 			s.student_id,
 			s.name,
@@ -100,7 +201,7 @@ func (r *eventRepository) GetActiveParticipants(sessionID uuid.UUID, timeRange t
 func (r *eventRepository) GetQuestionsPerMinuteStats(sessionID uuid.UUID) (*QuestionsPerMinuteStats, error) {
 	var stats QuestionsPerMinuteStats
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT 
 			COUNT(*) as total_questions,
 			ROUND(
@@ -121,7 +222,7 @@ func (r *eventRepository) GetQuestionsPerMinuteStats(sessionID uuid.UUID) (*Ques
 func (r *eventRepository) GetStudentPerformance(studentID, classroomID uuid.UUID) (*StudentPerformanceData, error) {
 	var performance StudentPerformanceData
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT 
 			COUNT(*) as questions_attempted,
 			SUM(CASE WHEN is_correct THEN 1 ELSE 0 END) as correct_answers,
@@ -140,7 +241,7 @@ func (r *eventRepository) GetClassroomEngagement(classroomID uuid.UUID, dateRang
 	cutoffTime := time.Now().Add(-dateRange)
 
 	// Get total students in classroom
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(*) as total_students
 		FROM classroom_students 
 		WHERE classroom_id = ?
@@ -151,7 +252,7 @@ func (r *eventRepository) GetClassroomEngagement(classroomID uuid.UUID, dateRang
 	}
 
 	// Get engagement metrics
-	err = r.db.Raw(`
+	err = r.raw(`
 		SELECT 
 			COUNT(DISTINCT ase.student_id) as active_students,
 			COUNT(DISTINCT qpe.question_id) as total_questions,
@@ -178,7 +279,7 @@ func (r *eventRepository) GetClassroomEngagement(classroomID uuid.UUID, dateRang
 func (r *eventRepository) GetContentEffectiveness(quizID uuid.UUID) (*ContentEffectivenessData, error) {
 	var effectiveness ContentEffectivenessData
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT 
 			? as quiz_id,
 			COUNT(DISTINCT qpe.question_id) as total_questions,
@@ -218,7 +319,7 @@ func (r *eventRepository) ValidateAnswerTiming(sessionID, questionID uuid.UUID,
 		TimerDurationSec int       `db:"timer_duration_sec"`
 	}
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT published_at, timer_duration_sec
 		FROM question_published_events 
 		WHERE session_id = ? AND question_id = ?
@@ -232,31 +333,71 @@ func (r *eventRepository) ValidateAnswerTiming(sessionID, questionID uuid.UUID,
 
 	deadline := result.PublishedAt.Add(time.Duration(result.TimerDurationSec) * time.Second)
 	if answerTimestamp.After(deadline) {
-		return fmt.Errorf("answer submitted at %v is after deadline %v", answerTimestamp, deadline)
+		return &LateAnswerError{Deadline: deadline, SubmittedAt: answerTimestamp}
 	}
 
 	return nil
 }
 
-func (r *eventRepository) GetResponseRate(sessionID, questionID uuid.UUID) (*ResponseRateData, error) {
+// sessionRosterCTE selects the students enrolled in the session's classroom when the session
+// started, so later roster changes do not rewrite historical rates. With present_only it keeps
+// only students who sent an event in the session. Parameters: session_id, present_only.
+const sessionRosterCTE = `
+		session_roster AS (
+			SELECT DISTINCT ce.student_id
+			FROM quiz_sessions qs
+			JOIN classroom_enrollments ce ON ce.classroom_id = qs.classroom_id
+				AND ce.enrolled_at <= qs.started_at
+				AND (ce.unenrolled_at IS NULL OR ce.unenrolled_at > qs.started_at)
+			WHERE qs.session_id = ?
+				AND (NOT ? OR EXISTS (
+					SELECT 1 FROM answer_submitted_events pe
+					WHERE pe.session_id = qs.session_id AND pe.student_id = ce.student_id
+				) OR EXISTS (
+					SELECT 1 FROM session_presence_events pe
+					WHERE pe.session_id = qs.session_id AND pe.student_id = ce.student_id
+				) OR EXISTS (
+					SELECT 1 FROM question_skipped_events pe
+					WHERE pe.session_id = qs.session_id AND pe.student_id = ce.student_id
+				))
+		)`
+
+// presenceIntervalsCTE turns STUDENT_JOINED / STUDENT_LEFT events into intervals: each join
+// lasts until the student's next presence event, or stays open. Parameters: session_id.
+const presenceIntervalsCTE = `
+		presence_intervals AS (
+			SELECT student_id, event_type, started_at, ended_at
+			FROM (
+				SELECT 
+					student_id,
+					event_type,
+					occurred_at as started_at,
+					LEAD(occurred_at) OVER (PARTITION BY student_id ORDER BY occurred_at) as ended_at
+				FROM session_presence_events
+				WHERE session_id = ?
+			) e
+			WHERE event_type = 'STUDENT_JOINED'
+		)`
+
+func (r *eventRepository) GetResponseRate(sessionID, questionID uuid.UUID, denominator Denominator) (*ResponseRateData, error) {
 	var data ResponseRateData
 
-	err := r.db.Raw(`
+	err := r.raw(`
+		WITH `+sessionRosterCTE+`
 		SELECT 
 			? as question_id,
 			? as session_id,
-			COUNT(DISTINCT cs.student_id) as students_received,
+			COUNT(DISTINCT sr.student_id) as students_received,
 			COUNT(DISTINCT ase.student_id) as students_answered,
 			ROUND(
 				COUNT(DISTINCT ase.student_id) * 100.0 / 
-				GREATEST(COUNT(DISTINCT cs.student_id), 1), 2
+				GREATEST(COUNT(DISTINCT sr.student_id), 1), 2
 			) as response_rate
-		FROM quiz_sessions qs
-		JOIN classroom_students cs ON cs.classroom_id = qs.classroom_id
-		LEFT JOIN answer_submitted_events ase ON ase.session_id = qs.session_id 
+		FROM session_roster sr
+		LEFT JOIN answer_submitted_events ase ON ase.student_id = sr.student_id
+			AND ase.session_id = ?
 			AND ase.question_id = ?
-		WHERE qs.session_id = ?
-	`, questionID, sessionID, questionID, sessionID).Scan(&data).Error
+	`, sessionID, denominator == DenominatorPresent, questionID, sessionID, sessionID, questionID).Scan(&data).Error
 
 	return &data, err
 }
@@ -272,7 +413,7 @@ func (r *eventRepository) GetLatencyToFirstAnswer(sessionID, questionID uuid.UUI
 		MedianTime      *int       `db:"median_time"`
 	}
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		WITH answer_latencies AS (
 			SELECT 
 				EXTRACT(EPOCH FROM (ase.submitted_at - qpe.published_at)) as latency_seconds
@@ -315,49 +456,173 @@ func (r *eventRepository) GetLatencyToFirstAnswer(sessionID, questionID uuid.UUI
 	return &data, nil
 }
 
-func (r *eventRepository) GetTimeoutAndSkippedRate(sessionID, questionID uuid.UUID) (*TimeoutData, error) {
+func (r *eventRepository) GetTimeoutAndSkippedRate(sessionID, questionID uuid.UUID, denominator Denominator) (*TimeoutData, error) {
 	var data TimeoutData
 
-	err := r.db.Raw(`
+	err := r.raw(`
+		WITH `+sessionRosterCTE+`,
+		question_window AS (
+			SELECT 
+				MIN(published_at) as published_at,
+				CASE WHEN MAX(timer_duration_sec) > 0
+					THEN MIN(published_at) + MAX(timer_duration_sec) * INTERVAL '1 second'
+				END as deadline
+			FROM question_published_events
+			WHERE session_id = ? AND question_id = ?
+		),
+		`+presenceIntervalsCTE+`,
+		present AS (
+			SELECT DISTINCT pi.student_id
+			FROM presence_intervals pi
+			CROSS JOIN question_window qw
+			WHERE (qw.deadline IS NULL OR pi.started_at <= qw.deadline)
+				AND (pi.ended_at IS NULL OR qw.published_at IS NULL OR pi.ended_at >= qw.published_at)
+		),
+		answered AS (
+			SELECT DISTINCT student_id FROM answer_submitted_events
+			WHERE session_id = ? AND question_id = ?
+		),
+		skipped AS (
+			SELECT DISTINCT student_id FROM question_skipped_events
+			WHERE session_id = ? AND question_id = ?
+		),
+		tracked AS (
+			SELECT EXISTS (SELECT 1 FROM session_presence_events WHERE session_id = ?) as presence_tracked
+		),
+		outcomes AS (
+			SELECT 
+				sr.student_id,
+				CASE
+					WHEN a.student_id IS NOT NULL THEN 'answered'
+					WHEN sk.student_id IS NOT NULL THEN 'skipped'
+					WHEN t.presence_tracked AND p.student_id IS NULL THEN 'absent'
+					ELSE 'timeout'
+				END as outcome
+			FROM session_roster sr
+			CROSS JOIN tracked t
+			LEFT JOIN answered a ON a.student_id = sr.student_id
+			LEFT JOIN skipped sk ON sk.student_id = sr.student_id
+			LEFT JOIN present p ON p.student_id = sr.student_id
+		)
 		SELECT 
 			? as question_id,
 			? as session_id,
-			COUNT(DISTINCT cs.student_id) as total_students,
-			COUNT(DISTINCT cs.student_id) - COUNT(DISTINCT ase.student_id) as timeout_count,
-			0 as skipped_count,
-			ROUND(
-				(COUNT(DISTINCT cs.student_id) - COUNT(DISTINCT ase.student_id)) * 100.0 / 
-				GREATEST(COUNT(DISTINCT cs.student_id), 1), 2
-			) as timeout_rate,
-			0.0 as skipped_rate
-		FROM quiz_sessions qs
-		JOIN classroom_students cs ON cs.classroom_id = qs.classroom_id
-		LEFT JOIN answer_submitted_events ase ON ase.session_id = qs.session_id 
-			AND ase.question_id = ?
-		WHERE qs.session_id = ?
-	`, questionID, sessionID, questionID, sessionID).Scan(&data).Error
+			COUNT(o.student_id) as total_students,
+			COUNT(o.student_id) FILTER (WHERE o.outcome = 'answered') as answered_count,
+			COUNT(o.student_id) FILTER (WHERE o.outcome = 'timeout') as timeout_count,
+			COUNT(o.student_id) FILTER (WHERE o.outcome = 'skipped') as skipped_count,
+			COUNT(o.student_id) FILTER (WHERE o.outcome = 'absent') as absent_count,
+			ROUND(COUNT(o.student_id) FILTER (WHERE o.outcome = 'timeout') * 100.0 / GREATEST(COUNT(o.student_id), 1), 2) as timeout_rate,
+			ROUND(COUNT(o.student_id) FILTER (WHERE o.outcome = 'skipped') * 100.0 / GREATEST(COUNT(o.student_id), 1), 2) as skipped_rate,
+			ROUND(COUNT(o.student_id) FILTER (WHERE o.outcome = 'absent') * 100.0 / GREATEST(COUNT(o.student_id), 1), 2) as absent_rate,
+			(SELECT presence_tracked FROM tracked) as presence_tracked
+		FROM outcomes o
+	`, sessionID, denominator == DenominatorPresent,
+		sessionID, questionID,
+		sessionID,
+		sessionID, questionID,
+		sessionID, questionID,
+		sessionID,
+		questionID, sessionID).Scan(&data).Error
 
 	return &data, err
 }
 
-func (r *eventRepository) GetCompletionRate(sessionID uuid.UUID) (*CompletionRateData, error) {
+// GetSessionAttendance lists students enrolled at session start plus anyone who joined,
+// with their presence intervals summarised. Open intervals end when the session ended,
+// or at the session's last recorded activity.
+func (r *eventRepository) GetSessionAttendance(sessionID uuid.UUID, pagination PaginationParams) (*PaginatedResponse[AttendanceData], error) {
+	var results []AttendanceData
+	var totalCount int64
+
+	const attendees = `
+		WITH ` + sessionRosterCTE + `,
+		attendees AS (
+			SELECT student_id, TRUE as enrolled FROM session_roster
+			UNION
+			SELECT DISTINCT spe.student_id, FALSE as enrolled
+			FROM session_presence_events spe
+			WHERE spe.session_id = ?
+				AND spe.student_id NOT IN (SELECT student_id FROM session_roster)
+		)`
+
+	err := r.raw(attendees+`
+		SELECT COUNT(*) FROM attendees
+	`, sessionID, false, sessionID).Scan(&totalCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.raw(attendees+`,
+		`+presenceIntervalsCTE+`,
+		session_end AS (
+			SELECT COALESCE(qs.ended_at, GREATEST(
+				(SELECT MAX(published_at) FROM question_published_events WHERE session_id = qs.session_id),
+				(SELECT MAX(occurred_at) FROM session_presence_events WHERE session_id = qs.session_id),
+				(SELECT MAX(submitted_at) FROM answer_submitted_events WHERE session_id = qs.session_id)
+			)) as ended_at
+			FROM quiz_sessions qs
+			WHERE qs.session_id = ?
+		),
+		presence AS (
+			SELECT 
+				pi.student_id,
+				MIN(pi.started_at) as first_joined_at,
+				COUNT(*) as join_count,
+				COALESCE(SUM(GREATEST(EXTRACT(EPOCH FROM (COALESCE(pi.ended_at, se.ended_at) - pi.started_at)), 0)), 0) as present_seconds
+			FROM presence_intervals pi
+			CROSS JOIN session_end se
+			GROUP BY pi.student_id
+		)
+		SELECT 
+			att.student_id,
+			s.name as student_name,
+			att.enrolled,
+			p.first_joined_at,
+			(SELECT MAX(occurred_at) FROM session_presence_events
+				WHERE session_id = ? AND student_id = att.student_id AND event_type = 'STUDENT_LEFT') as last_left_at,
+			COALESCE(p.join_count, 0) as join_count,
+			COALESCE(ROUND(p.present_seconds), 0)::int as present_seconds,
+			(SELECT COUNT(DISTINCT question_id) FROM answer_submitted_events
+				WHERE session_id = ? AND student_id = att.student_id) as questions_answered,
+			(SELECT COUNT(DISTINCT question_id) FROM question_skipped_events
+				WHERE session_id = ? AND student_id = att.student_id) as questions_skipped
+		FROM attendees att
+		JOIN students s ON s.student_id = att.student_id
+		LEFT JOIN presence p ON p.student_id = att.student_id
+		ORDER BY s.name NULLS LAST, att.student_id
+		LIMIT ? OFFSET ?
+	`, sessionID, false, sessionID,
+		sessionID,
+		sessionID,
+		sessionID, sessionID, sessionID,
+		pagination.PageSize, pagination.Offset).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(results, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *eventRepository) GetCompletionRate(sessionID uuid.UUID, denominator Denominator) (*CompletionRateData, error) {
 	var data CompletionRateData
 
-	err := r.db.Raw(`
-		WITH session_stats AS (
+	err := r.raw(`
+		WITH `+sessionRosterCTE+`,
+		session_stats AS (
 			SELECT 
-				COUNT(DISTINCT cs.student_id) as total_students,
+				(SELECT COUNT(*) FROM session_roster) as total_students,
 				COUNT(DISTINCT qpe.question_id) as total_questions
-			FROM quiz_sessions qs
-			JOIN classroom_students cs ON cs.classroom_id = qs.classroom_id
-			LEFT JOIN question_published_events qpe ON qpe.session_id = qs.session_id
-			WHERE qs.session_id = ?
+			FROM question_published_events qpe
+			WHERE qpe.session_id = ?
 		),
 		student_completion AS (
 			SELECT 
 				ase.student_id,
 				COUNT(DISTINCT ase.question_id) as questions_answered
 			FROM answer_submitted_events ase
+			JOIN session_roster sr ON sr.student_id = ase.student_id
 			WHERE ase.session_id = ?
 			GROUP BY ase.student_id
 		)
@@ -375,16 +640,17 @@ func (r *eventRepository) GetCompletionRate(sessionID uuid.UUID) (*CompletionRat
 		FROM session_stats ss
 		LEFT JOIN student_completion sc ON sc.questions_answered = ss.total_questions
 		GROUP BY ss.total_students, ss.total_questions
-	`, sessionID, sessionID, sessionID).Scan(&data).Error
+	`, sessionID, denominator == DenominatorPresent, sessionID, sessionID, sessionID).Scan(&data).Error
 
 	return &data, err
 }
 
-func (r *eventRepository) GetDropoffPoints(sessionID uuid.UUID) ([]DropoffPoint, error) {
+func (r *eventRepository) GetDropoffPoints(sessionID uuid.UUID, denominator Denominator) ([]DropoffPoint, error) {
 	var dropoffs []DropoffPoint
 
-	err := r.db.Raw(`
-		WITH question_order AS (
+	err := r.raw(`
+		WITH `+sessionRosterCTE+`,
+		question_order AS (
 			SELECT 
 				question_id,
 				ROW_NUMBER() OVER (ORDER BY published_at) as question_order
@@ -399,16 +665,16 @@ func (r *eventRepository) GetDropoffPoints(sessionID uuid.UUID) ([]DropoffPoint,
 			FROM question_order qo
 			LEFT JOIN answer_submitted_events ase ON ase.question_id = qo.question_id 
 				AND ase.session_id = ?
+				AND ase.student_id IN (SELECT student_id FROM session_roster)
 			GROUP BY qo.question_id, qo.question_order
 		),
 		total_students AS (
-			SELECT COUNT(DISTINCT cs.student_id) as total_count
-			FROM quiz_sessions qs
-			JOIN classroom_students cs ON cs.classroom_id = qs.classroom_id
-			WHERE qs.session_id = ?
+			SELECT COUNT(*) as total_count
+			FROM session_roster
 		)
 		SELECT 
 			sp.question_id,
+			q.text as question_text,
 			sp.question_order,
 			ts.total_count as students_at_start,
 			sp.students_answered as students_at_end,
@@ -418,68 +684,241 @@ func (r *eventRepository) GetDropoffPoints(sessionID uuid.UUID) ([]DropoffPoint,
 			) as dropoff_rate
 		FROM student_progress sp
 		CROSS JOIN total_students ts
+		LEFT JOIN questions q ON q.question_id = sp.question_id
 		ORDER BY sp.question_order
-	`, sessionID, sessionID, sessionID).Scan(&dropoffs).Error
+	`, sessionID, denominator == DenominatorPresent, sessionID, sessionID).Scan(&dropoffs).Error
 
 	return dropoffs, err
 }
 
 // QuizRepository implementations
 func (r *quizRepository) CreateQuiz(quiz *models.Quiz) error {
+	quiz.TenantID = r.tenantID
 	return r.db.Create(quiz).Error
 }
 
 func (r *quizRepository) GetQuizByID(quizID uuid.UUID) (*models.Quiz, error) {
 	var quiz models.Quiz
-	err := r.db.Where("quiz_id = ?", quizID).First(&quiz).Error
+	err := r.scoped().Where("quiz_id = ?", quizID).First(&quiz).Error
 	return &quiz, err
 }
 
 func (r *quizRepository) CreateQuestion(question *models.Question) error {
-	return r.db.Create(question).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Mirror the column defaults so the first snapshot matches the stored row
+		if question.Version == 0 {
+			question.Version = 1
+		}
+		if question.QuestionType == "" {
+			question.QuestionType = "single_choice"
+		}
+		if question.Points == 0 {
+			question.Points = 1
+		}
+		question.TenantID = r.tenantID
+		if err := tx.Create(question).Error; err != nil {
+			return err
+		}
+		return tx.Create(questionSnapshot(question)).Error
+	})
 }
 
 func (r *quizRepository) GetQuestionByID(questionID uuid.UUID) (*models.Question, error) {
 	var question models.Question
-	err := r.db.Where("question_id = ?", questionID).First(&question).Error
+	err := r.scoped().Where("question_id = ?", questionID).First(&question).Error
 	return &question, err
 }
 
+func (r *quizRepository) ListQuizzes(includeArchived bool, pagination PaginationParams) (*PaginatedResponse[models.Quiz], error) {
+	var quizzes []models.Quiz
+	var totalCount int64
+
+	query := r.scoped().Model(&models.Quiz{})
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Order("title").Limit(pagination.PageSize).Offset(pagination.Offset).Find(&quizzes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(quizzes, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *quizRepository) UpdateQuiz(quiz *models.Quiz) error {
+	return r.scoped().Model(quiz).Updates(map[string]interface{}{
+		"title":       quiz.Title,
+		"description": quiz.Description,
+		"updated_at":  gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *quizRepository) ArchiveQuiz(quizID uuid.UUID) error {
+	result := r.scoped().Model(&models.Quiz{}).
+		Where("quiz_id = ? AND archived_at IS NULL", quizID).
+		Updates(map[string]interface{}{"archived_at": gorm.Expr("NOW()"), "updated_at": gorm.Expr("NOW()")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *quizRepository) ListQuizQuestions(quizID uuid.UUID, includeArchived bool, pagination PaginationParams) (*PaginatedResponse[models.Question], error) {
+	var questions []models.Question
+	var totalCount int64
+
+	query := r.scoped().Model(&models.Question{}).Where("quiz_id = ?", quizID)
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Order("position NULLS LAST, created_at, question_id").
+		Limit(pagination.PageSize).Offset(pagination.Offset).Find(&questions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(questions, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *quizRepository) UpdateQuestion(question *models.Question) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the row so concurrent edits get distinct version numbers
+		var current models.Question
+		err := r.scope(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("question_id = ?", question.QuestionID).First(&current).Error
+		if err != nil {
+			return err
+		}
+
+		question.TenantID = current.TenantID
+		question.Version = current.Version + 1
+		err = r.scope(tx).Model(&models.Question{}).Where("question_id = ?", question.QuestionID).Updates(map[string]interface{}{
+			"question_type": question.QuestionType,
+			"answer_key":    question.AnswerKey,
+			"text":          question.Text,
+			"options":       question.Options,
+			"tags":          question.Tags,
+			"position":      question.Position,
+			"points":        question.Points,
+			"version":       question.Version,
+			"updated_at":    gorm.Expr("NOW()"),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Create(questionSnapshot(question)).Error
+	})
+}
+
+func (r *quizRepository) ArchiveQuestion(questionID uuid.UUID) error {
+	result := r.scoped().Model(&models.Question{}).
+		Where("question_id = ? AND archived_at IS NULL", questionID).
+		Updates(map[string]interface{}{"archived_at": gorm.Expr("NOW()"), "updated_at": gorm.Expr("NOW()")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *quizRepository) GetQuestionVersions(questionID uuid.UUID) ([]models.QuestionVersion, error) {
+	var versions []models.QuestionVersion
+	err := r.scoped().Where("question_id = ?", questionID).Order("version DESC").Find(&versions).Error
+	return versions, err
+}
+
+// questionSnapshot copies the versioned content fields of a question
+func questionSnapshot(question *models.Question) *models.QuestionVersion {
+	return &models.QuestionVersion{
+		QuestionID:   question.QuestionID,
+		TenantID:     question.TenantID,
+		Version:      question.Version,
+		QuestionType: question.QuestionType,
+		AnswerKey:    question.AnswerKey,
+		Text:         question.Text,
+		Options:      question.Options,
+		Tags:         question.Tags,
+		Points:       question.Points,
+	}
+}
+
 // SessionRepository implementations
 func (r *sessionRepository) CreateSession(session *models.QuizSession) error {
+	session.TenantID = r.tenantID
 	return r.db.Create(session).Error
 }
 
 func (r *sessionRepository) GetSessionByID(sessionID uuid.UUID) (*models.QuizSession, error) {
 	var session models.QuizSession
-	err := r.db.Where("session_id = ?", sessionID).First(&session).Error
+	err := r.scoped().Where("session_id = ?", sessionID).First(&session).Error
 	return &session, err
 }
 
 func (r *sessionRepository) UpdateSession(session *models.QuizSession) error {
-	return r.db.Save(session).Error
+	return r.scoped().Model(session).Updates(map[string]interface{}{
+		"quiz_id":      session.QuizID,
+		"classroom_id": session.ClassroomID,
+		"teacher_id":   session.TeacherID,
+		"started_at":   session.StartedAt,
+		"ended_at":     session.EndedAt,
+		"ingested_by":  session.IngestedBy,
+	}).Error
+}
+
+func (r *sessionRepository) AttributeTeacher(sessionID, teacherID uuid.UUID) error {
+	return r.scoped().Model(&models.QuizSession{}).
+		Where("session_id = ? AND teacher_id IS NULL", sessionID).
+		Update("teacher_id", teacherID).Error
 }
 
 // ClassroomRepository implementations
 func (r *classroomRepository) CreateClassroom(classroom *models.Classroom) error {
+	classroom.TenantID = r.tenantID
 	return r.db.Create(classroom).Error
 }
 
+func (r *classroomRepository) GetClassroomByID(classroomID uuid.UUID) (*models.Classroom, error) {
+	var classroom models.Classroom
+	err := r.scoped().Where("classroom_id = ?", classroomID).First(&classroom).Error
+	return &classroom, err
+}
+
 func (r *classroomRepository) CreateStudent(student *models.Student) error {
+	student.TenantID = r.tenantID
 	return r.db.Create(student).Error
 }
 
+func (r *classroomRepository) GetStudentByID(studentID uuid.UUID) (*models.Student, error) {
+	var student models.Student
+	err := r.scoped().Where("student_id = ?", studentID).First(&student).Error
+	return &student, err
+}
+
 func (r *classroomRepository) AddStudentToClassroom(classroomID, studentID uuid.UUID) error {
-	classroomStudent := &models.ClassroomStudent{
-		ClassroomID: classroomID,
-		StudentID:   studentID,
-	}
-	return r.db.Create(classroomStudent).Error
+	return r.EnrollStudent(classroomID, studentID, time.Now())
 }
 
 func (r *classroomRepository) GetClassroomStudents(classroomID uuid.UUID) ([]models.Student, error) {
 	var students []models.Student
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT s.* 
 		FROM students s
 		JOIN classroom_students cs ON s.student_id = cs.student_id
@@ -495,7 +934,7 @@ func (r *classroomRepository) GetClassroomStudentsPaginated(classroomID uuid.UUI
 	var totalCount int64
 
 	// Get total count
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(*)
 		FROM students s
 		JOIN classroom_students cs ON s.student_id = cs.student_id
@@ -507,7 +946,7 @@ func (r *classroomRepository) GetClassroomStudentsPaginated(classroomID uuid.UUI
 	}
 
 	// Get paginated results
-	err = r.db.Raw(`
+	err = r.raw(`
 		SELECT s.* 
 		FROM students s
 		JOIN classroom_students cs ON s.student_id = cs.student_id
@@ -524,13 +963,375 @@ func (r *classroomRepository) GetClassroomStudentsPaginated(classroomID uuid.UUI
 	return &response, nil
 }
 
+func (r *classroomRepository) ListClassrooms(includeArchived bool, pagination PaginationParams) (*PaginatedResponse[models.Classroom], error) {
+	var classrooms []models.Classroom
+	var totalCount int64
+
+	query := r.scoped().Model(&models.Classroom{})
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Order("name").Limit(pagination.PageSize).Offset(pagination.Offset).Find(&classrooms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(classrooms, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *classroomRepository) UpdateClassroom(classroom *models.Classroom) error {
+	return r.scoped().Model(classroom).Updates(map[string]interface{}{
+		"name":       classroom.Name,
+		"school_id":  classroom.SchoolID,
+		"updated_at": gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *classroomRepository) ArchiveClassroom(classroomID uuid.UUID) error {
+	result := r.scoped().Model(&models.Classroom{}).
+		Where("classroom_id = ? AND archived_at IS NULL", classroomID).
+		Updates(map[string]interface{}{"archived_at": gorm.Expr("NOW()"), "updated_at": gorm.Expr("NOW()")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *classroomRepository) ListStudents(pagination PaginationParams) (*PaginatedResponse[models.Student], error) {
+	var students []models.Student
+	var totalCount int64
+
+	query := r.scoped().Model(&models.Student{})
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Order("name NULLS LAST, student_id").Limit(pagination.PageSize).Offset(pagination.Offset).Find(&students).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(students, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *classroomRepository) UpdateStudent(student *models.Student) error {
+	return r.scoped().Model(student).Updates(map[string]interface{}{
+		"name":       student.Name,
+		"updated_at": gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *classroomRepository) EnrollStudent(classroomID, studentID uuid.UUID, effectiveAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		_, err := r.enrollTx(tx, classroomID, studentID, effectiveAt)
+		return err
+	})
+}
+
+func (r *classroomRepository) UnenrollStudent(classroomID, studentID uuid.UUID, effectiveAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		closed, err := r.unenrollTx(tx, classroomID, studentID, effectiveAt)
+		if err == nil && !closed {
+			return gorm.ErrRecordNotFound
+		}
+		return err
+	})
+}
+
+func (r *classroomRepository) GetEnrollmentHistory(filter EnrollmentFilter, pagination PaginationParams) (*PaginatedResponse[models.ClassroomEnrollment], error) {
+	var enrollments []models.ClassroomEnrollment
+	var totalCount int64
+
+	query := r.scoped().Model(&models.ClassroomEnrollment{})
+	if filter.ClassroomID != nil {
+		query = query.Where("classroom_id = ?", *filter.ClassroomID)
+	}
+	if filter.StudentID != nil {
+		query = query.Where("student_id = ?", *filter.StudentID)
+	}
+	if filter.ClassroomIDs != nil {
+		query = query.Where("classroom_id IN ?", filter.ClassroomIDs)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Order("enrolled_at DESC, enrollment_id DESC").Limit(pagination.PageSize).Offset(pagination.Offset).Find(&enrollments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(enrollments, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *classroomRepository) ApplyRoster(batch RosterBatch) (*RosterResult, error) {
+	result := &RosterResult{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range batch.Schools {
+			batch.Schools[i].TenantID = r.tenantID
+			upserted := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "school_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"name":       gorm.Expr("EXCLUDED.name"),
+					"sourced_id": gorm.Expr("COALESCE(EXCLUDED.sourced_id, schools.sourced_id)"),
+					"updated_at": gorm.Expr("NOW()"),
+				}),
+				Where: sameTenant("schools"),
+			}).Create(&batch.Schools[i])
+			if upserted.Error != nil {
+				return upserted.Error
+			}
+			if upserted.RowsAffected == 0 {
+				return fmt.Errorf("school %s: %w", batch.Schools[i].SchoolID, ErrTenantMismatch)
+			}
+			result.SchoolsUpserted++
+		}
+
+		for i := range batch.Classrooms {
+			batch.Classrooms[i].TenantID = r.tenantID
+			upserted := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "classroom_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"name":        gorm.Expr("EXCLUDED.name"),
+					"school_id":   gorm.Expr("COALESCE(EXCLUDED.school_id, classrooms.school_id)"),
+					"sourced_id":  gorm.Expr("COALESCE(EXCLUDED.sourced_id, classrooms.sourced_id)"),
+					"archived_at": nil,
+					"updated_at":  gorm.Expr("NOW()"),
+				}),
+				Where: sameTenant("classrooms"),
+			}).Create(&batch.Classrooms[i])
+			if upserted.Error != nil {
+				return upserted.Error
+			}
+			if upserted.RowsAffected == 0 {
+				return fmt.Errorf("classroom %s: %w", batch.Classrooms[i].ClassroomID, ErrTenantMismatch)
+			}
+			result.ClassroomsUpserted++
+		}
+
+		for i := range batch.Students {
+			batch.Students[i].TenantID = r.tenantID
+			upserted := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "student_id"}},
+				DoUpdates: clause.Assignments(map[string]interface{}{
+					"name":       gorm.Expr("EXCLUDED.name"),
+					"sourced_id": gorm.Expr("COALESCE(EXCLUDED.sourced_id, students.sourced_id)"),
+					"updated_at": gorm.Expr("NOW()"),
+				}),
+				Where: sameTenant("students"),
+			}).Create(&batch.Students[i])
+			if upserted.Error != nil {
+				return upserted.Error
+			}
+			if upserted.RowsAffected == 0 {
+				return fmt.Errorf("student %s: %w", batch.Students[i].StudentID, ErrTenantMismatch)
+			}
+			result.StudentsUpserted++
+		}
+
+		for _, enrollment := range batch.Enrollments {
+			if enrollment.Active {
+				opened, err := r.enrollTx(tx, enrollment.ClassroomID, enrollment.StudentID, enrollment.EffectiveAt)
+				if err != nil {
+					return err
+				}
+				if opened {
+					result.Enrolled++
+				}
+			} else {
+				closed, err := r.unenrollTx(tx, enrollment.ClassroomID, enrollment.StudentID, enrollment.EffectiveAt)
+				if err != nil {
+					return err
+				}
+				if closed {
+					result.Unenrolled++
+				}
+			}
+		}
+
+		for _, classroomID := range batch.ArchiveClassrooms {
+			archived := r.scope(tx).Model(&models.Classroom{}).
+				Where("classroom_id = ? AND archived_at IS NULL", classroomID).
+				Updates(map[string]interface{}{"archived_at": gorm.Expr("NOW()"), "updated_at": gorm.Expr("NOW()")})
+			if archived.Error != nil {
+				return archived.Error
+			}
+			result.ClassroomsArchived += int(archived.RowsAffected)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *classroomRepository) CreateSchool(school *models.School) error {
+	school.TenantID = r.tenantID
+	return r.db.Create(school).Error
+}
+
+func (r *classroomRepository) GetSchoolByID(schoolID uuid.UUID) (*models.School, error) {
+	var school models.School
+	err := r.scoped().Where("school_id = ?", schoolID).First(&school).Error
+	return &school, err
+}
+
+func (r *classroomRepository) ListSchools(pagination PaginationParams) (*PaginatedResponse[models.School], error) {
+	var schools []models.School
+	var totalCount int64
+
+	query := r.scoped().Model(&models.School{})
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Order("name, school_id").Limit(pagination.PageSize).Offset(pagination.Offset).Find(&schools).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(schools, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *classroomRepository) UpdateSchool(school *models.School) error {
+	return r.scoped().Model(school).Updates(map[string]interface{}{
+		"name":       school.Name,
+		"updated_at": gorm.Expr("NOW()"),
+	}).Error
+}
+
+// GetSchoolClassroomIDs includes archived classrooms, whose history stays reportable
+func (r *classroomRepository) GetSchoolClassroomIDs(schoolID uuid.UUID) ([]uuid.UUID, error) {
+	var classroomIDs []uuid.UUID
+	err := r.scoped().Model(&models.Classroom{}).Where("school_id = ?", schoolID).Pluck("classroom_id", &classroomIDs).Error
+	return classroomIDs, err
+}
+
+func (r *classroomRepository) GetSchoolsBySourcedIDs(sourcedIDs []string) ([]models.School, error) {
+	var schools []models.School
+	if len(sourcedIDs) == 0 {
+		return schools, nil
+	}
+	err := r.scoped().Where("sourced_id IN ?", sourcedIDs).Find(&schools).Error
+	return schools, err
+}
+
+func (r *classroomRepository) GetClassroomsBySourcedIDs(sourcedIDs []string) ([]models.Classroom, error) {
+	var classrooms []models.Classroom
+	if len(sourcedIDs) == 0 {
+		return classrooms, nil
+	}
+	err := r.scoped().Where("sourced_id IN ?", sourcedIDs).Find(&classrooms).Error
+	return classrooms, err
+}
+
+func (r *classroomRepository) GetStudentsBySourcedIDs(sourcedIDs []string) ([]models.Student, error) {
+	var students []models.Student
+	if len(sourcedIDs) == 0 {
+		return students, nil
+	}
+	err := r.scoped().Where("sourced_id IN ?", sourcedIDs).Find(&students).Error
+	return students, err
+}
+
+func (r *classroomRepository) GetEnrollmentsForClassrooms(classroomIDs []uuid.UUID) ([]models.ClassroomEnrollment, error) {
+	var enrollments []models.ClassroomEnrollment
+	if len(classroomIDs) == 0 {
+		return enrollments, nil
+	}
+	err := r.scoped().Where("classroom_id IN ?", classroomIDs).Order("enrolled_at").Find(&enrollments).Error
+	return enrollments, err
+}
+
+// enrollTx opens an interval and adds the student to the current roster.
+// It reports false when the student already has an open interval.
+func (s tenantScope) enrollTx(tx *gorm.DB, classroomID, studentID uuid.UUID, effectiveAt time.Time) (bool, error) {
+	var overlapping int64
+	err := s.scope(tx).Model(&models.ClassroomEnrollment{}).
+		Where("classroom_id = ? AND student_id = ?", classroomID, studentID).
+		Where("unenrolled_at IS NULL OR unenrolled_at > ?", effectiveAt).
+		Count(&overlapping).Error
+	if err != nil {
+		return false, err
+	}
+	if overlapping > 0 {
+		var open int64
+		err := s.scope(tx).Model(&models.ClassroomEnrollment{}).
+			Where("classroom_id = ? AND student_id = ? AND unenrolled_at IS NULL", classroomID, studentID).
+			Count(&open).Error
+		if err != nil {
+			return false, err
+		}
+		if open > 0 {
+			return false, nil
+		}
+		return false, ErrInvalidEnrollmentInterval
+	}
+
+	err = tx.Create(&models.ClassroomEnrollment{
+		TenantID:    s.tenantID,
+		ClassroomID: classroomID,
+		StudentID:   studentID,
+		EnrolledAt:  effectiveAt,
+	}).Error
+	if err != nil {
+		return false, err
+	}
+	err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ClassroomStudent{
+		TenantID:    s.tenantID,
+		ClassroomID: classroomID,
+		StudentID:   studentID,
+	}).Error
+	return err == nil, err
+}
+
+// unenrollTx closes the open interval and removes the student from the current roster.
+// It reports false when the student was not enrolled.
+func (s tenantScope) unenrollTx(tx *gorm.DB, classroomID, studentID uuid.UUID, effectiveAt time.Time) (bool, error) {
+	var open models.ClassroomEnrollment
+	err := s.scope(tx).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("classroom_id = ? AND student_id = ? AND unenrolled_at IS NULL", classroomID, studentID).
+		First(&open).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !effectiveAt.After(open.EnrolledAt) {
+		return false, ErrInvalidEnrollmentInterval
+	}
+
+	err = tx.Model(&open).Update("unenrolled_at", effectiveAt).Error
+	if err != nil {
+		return false, err
+	}
+	err = s.scope(tx).Where("classroom_id = ? AND student_id = ?", classroomID, studentID).
+		Delete(&models.ClassroomStudent{}).Error
+	return err == nil, err
+}
+
 // New paginated method for student performance across a classroom
 func (r *eventRepository) GetStudentPerformanceList(classroomID uuid.UUID, pagination PaginationParams) (*PaginatedResponse[StudentPerformanceData], error) {
 	var results []StudentPerformanceData
 	var totalCount int64
 
 	// Get total count of students in classroom
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(DISTINCT s.student_id)
 		FROM students s
 		JOIN classroom_students cs ON s.student_id = cs.student_id
@@ -542,7 +1343,7 @@ func (r *eventRepository) GetStudentPerformanceList(classroomID uuid.UUID, pagin
 	}
 
 	// Get paginated student performance data
-	err = r.db.Raw(`
+	err = r.raw(`
 		SELECT 
 			s.student_id,
 			COALESCE(COUNT(ase.event_id), 0) as questions_attempted,
@@ -578,7 +1379,7 @@ func (r *eventRepository) GetClassroomEngagementHistory(classroomID uuid.UUID, d
 	// In a real implementation, you might want different time granularities
 
 	// Get total count of days in the range
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(DISTINCT DATE(qs.started_at))
 		FROM quiz_sessions qs
 		WHERE qs.classroom_id = ? AND qs.started_at >= ?
@@ -589,7 +1390,7 @@ func (r *eventRepository) GetClassroomEngagementHistory(classroomID uuid.UUID, d
 	}
 
 	// Get paginated daily engagement data
-	err = r.db.Raw(`
+	err = r.raw(`
 		WITH daily_sessions AS (
 			SELECT 
 				DATE(qs.started_at) as session_date,
@@ -640,7 +1441,7 @@ func (r *eventRepository) GetClassroomEngagementHistory(classroomID uuid.UUID, d
 func (r *eventRepository) GetQuizSummary(quizID uuid.UUID) (*QuizSummaryData, error) {
 	var summary QuizSummaryData
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		WITH quiz_stats AS (
 			SELECT 
 				q.quiz_id,
@@ -701,20 +1502,27 @@ func (r *eventRepository) GetQuestionAnalysis(questionID uuid.UUID) (*QuestionAn
 	var analysis QuestionAnalysisData
 
 	// Get basic question stats
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT 
 			q.question_id,
 			q.quiz_id,
+			q.question_type,
+			q.text as question_text,
+			q.options,
+			q.position,
+			q.points,
+			q.version,
 			COUNT(ase.event_id) as total_attempts,
 			SUM(CASE WHEN ase.is_correct THEN 1 ELSE 0 END) as correct_attempts,
 			ROUND(AVG(CASE WHEN ase.is_correct THEN 1.0 ELSE 0.0 END) * 100, 2) as accuracy_rate,
+			ROUND((AVG(ase.score) * 100)::numeric, 2) as average_score,
 			ROUND(AVG(EXTRACT(EPOCH FROM (ase.submitted_at - qpe.published_at))), 2) as average_response_time,
 			COUNT(DISTINCT qpe.session_id) as usage_count
 		FROM questions q
 		LEFT JOIN question_published_events qpe ON q.question_id = qpe.question_id
 		LEFT JOIN answer_submitted_events ase ON q.question_id = ase.question_id
 		WHERE q.question_id = ?
-		GROUP BY q.question_id, q.quiz_id
+		GROUP BY q.question_id, q.quiz_id, q.question_type, q.text, q.options, q.position, q.points, q.version
 	`, questionID).Scan(&analysis).Error
 
 	if err != nil {
@@ -734,7 +1542,7 @@ func (r *eventRepository) GetQuestionAnalysis(questionID uuid.UUID) (*QuestionAn
 
 	// Get answer distribution
 	var distributions []AnswerDistribution
-	err = r.db.Raw(`
+	err = r.raw(`
 		SELECT 
 			answer,
 			COUNT(*) as count,
@@ -764,7 +1572,7 @@ func (r *eventRepository) GetQuizQuestionsList(quizID uuid.UUID, pagination Pagi
 	var totalCount int64
 
 	// Get total count
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(*)
 		FROM questions
 		WHERE quiz_id = ?
@@ -775,21 +1583,28 @@ func (r *eventRepository) GetQuizQuestionsList(quizID uuid.UUID, pagination Pagi
 	}
 
 	// Get paginated results
-	err = r.db.Raw(`
+	err = r.raw(`
 		SELECT 
 			q.question_id,
 			q.quiz_id,
+			q.question_type,
+			q.text as question_text,
+			q.options,
+			q.position,
+			q.points,
+			q.version,
 			COALESCE(COUNT(ase.event_id), 0) as total_attempts,
 			COALESCE(SUM(CASE WHEN ase.is_correct THEN 1 ELSE 0 END), 0) as correct_attempts,
 			COALESCE(ROUND(AVG(CASE WHEN ase.is_correct THEN 1.0 ELSE 0.0 END) * 100, 2), 0) as accuracy_rate,
+			COALESCE(ROUND((AVG(ase.score) * 100)::numeric, 2), 0) as average_score,
 			COALESCE(ROUND(AVG(EXTRACT(EPOCH FROM (ase.submitted_at - qpe.published_at))), 2), 0) as average_response_time,
 			COALESCE(COUNT(DISTINCT qpe.session_id), 0) as usage_count
 		FROM questions q
 		LEFT JOIN question_published_events qpe ON q.question_id = qpe.question_id
 		LEFT JOIN answer_submitted_events ase ON q.question_id = ase.question_id
 		WHERE q.quiz_id = ?
-		GROUP BY q.question_id, q.quiz_id
-		ORDER BY q.question_id
+		GROUP BY q.question_id, q.quiz_id, q.question_type, q.text, q.options, q.position, q.points, q.version
+		ORDER BY q.position NULLS LAST, q.question_id
 		LIMIT ? OFFSET ?
 	`, quizID, pagination.PageSize, pagination.Offset).Scan(&results).Error
 
@@ -820,7 +1635,7 @@ func (r *eventRepository) GetClassroomSessions(classroomID uuid.UUID, pagination
 	var totalCount int64
 
 	// Get total count
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(*)
 		FROM quiz_sessions
 		WHERE classroom_id = ?
@@ -831,7 +1646,7 @@ func (r *eventRepository) GetClassroomSessions(classroomID uuid.UUID, pagination
 	}
 
 	// Get paginated results
-	err = r.db.Raw(`
+	err = r.raw(`
 		SELECT 
 			qs.session_id,
 			q.title as quiz_title,
@@ -880,7 +1695,7 @@ func (r *eventRepository) GetQuizSessions(quizID uuid.UUID, pagination Paginatio
 	var totalCount int64
 
 	// Get total count
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(*)
 		FROM quiz_sessions
 		WHERE quiz_id = ?
@@ -891,7 +1706,7 @@ func (r *eventRepository) GetQuizSessions(quizID uuid.UUID, pagination Paginatio
 	}
 
 	// Get paginated results
-	err = r.db.Raw(`
+	err = r.raw(`
 		SELECT 
 			qs.session_id,
 			q.title as quiz_title,
@@ -940,7 +1755,7 @@ func (r *eventRepository) GetClassroomStudentRankings(classroomID uuid.UUID, pag
 	var totalCount int64
 
 	// Get total count
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(DISTINCT s.student_id)
 		FROM students s
 		JOIN classroom_students cs ON s.student_id = cs.student_id
@@ -952,7 +1767,7 @@ func (r *eventRepository) GetClassroomStudentRankings(classroomID uuid.UUID, pag
 	}
 
 	// Get paginated ranked results
-	err = r.db.Raw(`
+	err = r.raw(`
 		WITH student_stats AS (
 			SELECT 
 				s.student_id,
@@ -1000,7 +1815,7 @@ func (r *eventRepository) GetSessionStudentRankings(sessionID uuid.UUID, paginat
 	var totalCount int64
 
 	// Get total count
-	err := r.db.Raw(`
+	err := r.raw(`
 		SELECT COUNT(DISTINCT ase.student_id)
 		FROM answer_submitted_events ase
 		WHERE ase.session_id = ?
@@ -1011,7 +1826,7 @@ func (r *eventRepository) GetSessionStudentRankings(sessionID uuid.UUID, paginat
 	}
 
 	// Get paginated ranked results
-	err = r.db.Raw(`
+	err = r.raw(`
 		WITH student_stats AS (
 			SELECT 
 				s.student_id,
@@ -1057,7 +1872,7 @@ func (r *eventRepository) GetSessionStudentRankings(sessionID uuid.UUID, paginat
 func (r *eventRepository) GetClassroomOverview(classroomID uuid.UUID) (*ClassroomOverviewData, error) {
 	var overview ClassroomOverviewData
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		WITH classroom_info AS (
 			SELECT 
 				c.classroom_id,
@@ -1104,7 +1919,7 @@ func (r *eventRepository) GetClassroomOverview(classroomID uuid.UUID) (*Classroo
 func (r *eventRepository) GetClassPerformanceSummary(classroomID uuid.UUID) (*ClassPerformanceSummaryData, error) {
 	var summary ClassPerformanceSummaryData
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		WITH classroom_info AS (
 			SELECT 
 				c.classroom_id,
@@ -1154,7 +1969,7 @@ func (r *eventRepository) GetClassPerformanceSummary(classroomID uuid.UUID) (*Cl
 func (r *eventRepository) GetStudentActivitySummary(studentID, classroomID uuid.UUID) (*StudentActivitySummaryData, error) {
 	var summary StudentActivitySummaryData
 
-	err := r.db.Raw(`
+	err := r.raw(`
 		WITH student_info AS (
 			SELECT 
 				s.student_id,
@@ -1200,11 +2015,140 @@ func (r *eventRepository) GetStudentActivitySummary(studentID, classroomID uuid.
 	return &summary, err
 }
 
+// teacherSessionStatsCTE computes pacing and accuracy for every attributed session, optionally
+// narrowed to one teacher (params: teacher_id twice, NULL for all teachers). Questions per minute
+// uses the same one-minute floor as GetQuestionsPerMinuteStats.
+const teacherSessionStatsCTE = `
+	WITH session_stats AS (
+		SELECT 
+			qs.session_id,
+			qs.teacher_id,
+			qs.started_at,
+			COUNT(qpe.event_id) as questions_published,
+			EXTRACT(EPOCH FROM (MAX(qpe.published_at) - MIN(qpe.published_at))) as publish_span_seconds
+		FROM quiz_sessions qs
+		LEFT JOIN question_published_events qpe ON qpe.session_id = qs.session_id
+		WHERE qs.teacher_id IS NOT NULL
+			AND (CAST(? AS uuid) IS NULL OR qs.teacher_id = ?)
+		GROUP BY qs.session_id, qs.teacher_id, qs.started_at
+	),
+	session_answers AS (
+		SELECT 
+			ase.session_id,
+			COUNT(DISTINCT ase.student_id) as participating_students,
+			AVG(CASE WHEN ase.is_correct THEN 1.0 ELSE 0.0 END) * 100 as accuracy
+		FROM answer_submitted_events ase
+		JOIN session_stats ss ON ss.session_id = ase.session_id
+		GROUP BY ase.session_id
+	),
+	session_pacing AS (
+		SELECT 
+			ss.session_id,
+			ss.teacher_id,
+			ss.started_at,
+			ss.questions_published,
+			CASE WHEN ss.questions_published > 0
+				THEN ss.questions_published / GREATEST(ss.publish_span_seconds / 60, 1)
+			END as questions_per_minute,
+			CASE WHEN ss.questions_published > 1
+				THEN ss.publish_span_seconds / (ss.questions_published - 1)
+			END as seconds_per_question,
+			COALESCE(sa.participating_students, 0) as participating_students,
+			sa.accuracy
+		FROM session_stats ss
+		LEFT JOIN session_answers sa ON sa.session_id = ss.session_id
+	)
+`
+
+// GetTeacherSummaries - paginated per-teacher totals over all sessions attributed to them.
+// Pacing and accuracy are averaged per session so long sessions do not dominate.
+func (r *eventRepository) GetTeacherSummaries(pagination PaginationParams) (*PaginatedResponse[TeacherSummaryData], error) {
+	var results []TeacherSummaryData
+	var totalCount int64
+
+	err := r.raw(`
+		SELECT COUNT(*)
+		FROM teachers
+		WHERE archived_at IS NULL
+	`).Scan(&totalCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var allTeachers *uuid.UUID
+	err = r.raw(teacherSessionStatsCTE+`
+		SELECT 
+			t.teacher_id,
+			t.name as teacher_name,
+			(SELECT COUNT(*) FROM classroom_teachers ct WHERE ct.teacher_id = t.teacher_id) as classrooms_assigned,
+			COUNT(sp.session_id) as sessions_taught,
+			COALESCE(SUM(sp.questions_published), 0) as questions_published,
+			COALESCE(ROUND(AVG(sp.questions_per_minute), 2), 0) as questions_per_minute,
+			ROUND(AVG(sp.seconds_per_question), 2) as seconds_per_question,
+			ROUND(AVG(sp.accuracy), 2) as average_accuracy,
+			MAX(sp.started_at) as last_session_at
+		FROM teachers t
+		LEFT JOIN session_pacing sp ON sp.teacher_id = t.teacher_id
+		WHERE t.archived_at IS NULL
+		GROUP BY t.teacher_id, t.name
+		ORDER BY t.name NULLS LAST, t.teacher_id
+		LIMIT ? OFFSET ?
+	`, allTeachers, allTeachers, pagination.PageSize, pagination.Offset).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(results, pagination, int(totalCount))
+	return &response, nil
+}
+
+// GetTeacherSessions - paginated sessions taught by one teacher, most recent first
+func (r *eventRepository) GetTeacherSessions(teacherID uuid.UUID, pagination PaginationParams) (*PaginatedResponse[TeacherSessionData], error) {
+	var results []TeacherSessionData
+	var totalCount int64
+
+	err := r.raw(`
+		SELECT COUNT(*)
+		FROM quiz_sessions
+		WHERE teacher_id = ?
+	`, teacherID).Scan(&totalCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.raw(teacherSessionStatsCTE+`
+		SELECT 
+			sp.session_id,
+			q.title as quiz_title,
+			c.classroom_id,
+			c.name as classroom_name,
+			qs.started_at,
+			qs.ended_at,
+			sp.questions_published,
+			COALESCE(ROUND(sp.questions_per_minute, 2), 0) as questions_per_minute,
+			ROUND(sp.seconds_per_question, 2) as seconds_per_question,
+			sp.participating_students,
+			ROUND(sp.accuracy, 2) as average_accuracy
+		FROM session_pacing sp
+		JOIN quiz_sessions qs ON qs.session_id = sp.session_id
+		JOIN quizzes q ON q.quiz_id = qs.quiz_id
+		JOIN classrooms c ON c.classroom_id = qs.classroom_id
+		ORDER BY qs.started_at DESC
+		LIMIT ? OFFSET ?
+	`, teacherID, teacherID, pagination.PageSize, pagination.Offset).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(results, pagination, int(totalCount))
+	return &response, nil
+}
+
 // ExecuteGenericQuery executes a generic SQL query for cube.dev-style analytics
 func (r *eventRepository) ExecuteGenericQuery(sql string) ([]map[string]interface{}, error) {
 	var results []map[string]interface{}
 
-	rows, err := r.db.Raw(sql).Rows()
+	rows, err := r.raw(sql).Rows()
 	if err != nil {
 		return nil, err
 	}
@@ -1247,3 +2191,372 @@ func (r *eventRepository) ExecuteGenericQuery(sql string) ([]map[string]interfac
 
 	return results, rows.Err()
 }
+
+// TeacherRepository implementations
+func (r *teacherRepository) CreateTeacher(teacher *models.Teacher) error {
+	teacher.TenantID = r.tenantID
+	return r.db.Create(teacher).Error
+}
+
+func (r *teacherRepository) GetTeacherByID(teacherID uuid.UUID) (*models.Teacher, error) {
+	var teacher models.Teacher
+	err := r.scoped().Where("teacher_id = ?", teacherID).First(&teacher).Error
+	return &teacher, err
+}
+
+func (r *teacherRepository) ListTeachers(includeArchived bool, pagination PaginationParams) (*PaginatedResponse[models.Teacher], error) {
+	var teachers []models.Teacher
+	var totalCount int64
+
+	query := r.scoped().Model(&models.Teacher{})
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := query.Order("name NULLS LAST, teacher_id").Limit(pagination.PageSize).Offset(pagination.Offset).Find(&teachers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(teachers, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *teacherRepository) UpdateTeacher(teacher *models.Teacher) error {
+	return r.scoped().Model(teacher).Updates(map[string]interface{}{
+		"name":       teacher.Name,
+		"email":      teacher.Email,
+		"updated_at": gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *teacherRepository) ArchiveTeacher(teacherID uuid.UUID) error {
+	result := r.scoped().Model(&models.Teacher{}).
+		Where("teacher_id = ? AND archived_at IS NULL", teacherID).
+		Updates(map[string]interface{}{"archived_at": gorm.Expr("NOW()"), "updated_at": gorm.Expr("NOW()")})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *teacherRepository) AssignTeacher(classroomID, teacherID uuid.UUID, role string) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "classroom_id"}, {Name: "teacher_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role"}),
+		Where:     sameTenant("classroom_teachers"),
+	}).Create(&models.ClassroomTeacher{
+		TenantID:    r.tenantID,
+		ClassroomID: classroomID,
+		TeacherID:   teacherID,
+		Role:        role,
+	}).Error
+}
+
+func (r *teacherRepository) UnassignTeacher(classroomID, teacherID uuid.UUID) error {
+	result := r.scoped().Where("classroom_id = ? AND teacher_id = ?", classroomID, teacherID).Delete(&models.ClassroomTeacher{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+const teacherAssignmentSelect = `
+	SELECT 
+		ct.classroom_id,
+		c.name as classroom_name,
+		ct.teacher_id,
+		t.name as teacher_name,
+		ct.role,
+		ct.assigned_at
+	FROM classroom_teachers ct
+	JOIN classrooms c ON c.classroom_id = ct.classroom_id
+	JOIN teachers t ON t.teacher_id = ct.teacher_id
+`
+
+func (r *teacherRepository) GetClassroomTeachers(classroomID uuid.UUID) ([]TeacherAssignment, error) {
+	var assignments []TeacherAssignment
+	err := r.raw(teacherAssignmentSelect+`
+		WHERE ct.classroom_id = ?
+		ORDER BY ct.role = 'primary' DESC, ct.assigned_at
+	`, classroomID).Scan(&assignments).Error
+	return assignments, err
+}
+
+func (r *teacherRepository) GetTeacherClassrooms(teacherID uuid.UUID) ([]TeacherAssignment, error) {
+	var assignments []TeacherAssignment
+	err := r.raw(teacherAssignmentSelect+`
+		WHERE ct.teacher_id = ?
+		ORDER BY c.name
+	`, teacherID).Scan(&assignments).Error
+	return assignments, err
+}
+
+func (r *teacherRepository) GetPrimaryTeacher(classroomID uuid.UUID) (*models.Teacher, error) {
+	var teacher models.Teacher
+	err := r.scoped().
+		Joins("JOIN classroom_teachers ct ON ct.teacher_id = teachers.teacher_id").
+		Where("ct.classroom_id = ? AND ct.role = ? AND teachers.archived_at IS NULL", classroomID, models.TeacherRolePrimary).
+		Order("ct.assigned_at").
+		First(&teacher).Error
+	return &teacher, err
+}
+
+// ProvisioningRepository implementations
+func (r *provisioningRepository) ProvisionSession(session *models.QuizSession, sourceEventID uuid.UUID) error {
+	session.TenantID = r.tenantID
+	return r.provision("session", session.SessionID, sourceEventID, func(tx *gorm.DB) (int64, error) {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(session)
+		return result.RowsAffected, result.Error
+	})
+}
+
+func (r *provisioningRepository) ProvisionQuestion(question *models.Question, sourceEventID uuid.UUID) error {
+	question.TenantID = r.tenantID
+	return r.provision("question", question.QuestionID, sourceEventID, func(tx *gorm.DB) (int64, error) {
+		question.Version = 1
+		question.QuestionType = "single_choice"
+		question.Points = 1
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(question)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.RowsAffected, result.Error
+		}
+		return result.RowsAffected, tx.Create(questionSnapshot(question)).Error
+	})
+}
+
+func (r *provisioningRepository) ProvisionStudent(student *models.Student, sourceEventID uuid.UUID) error {
+	student.TenantID = r.tenantID
+	return r.provision("student", student.StudentID, sourceEventID, func(tx *gorm.DB) (int64, error) {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(student)
+		return result.RowsAffected, result.Error
+	})
+}
+
+func (r *provisioningRepository) ProvisionTeacher(teacher *models.Teacher, sourceEventID uuid.UUID) error {
+	teacher.TenantID = r.tenantID
+	return r.provision("teacher", teacher.TeacherID, sourceEventID, func(tx *gorm.DB) (int64, error) {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(teacher)
+		return result.RowsAffected, result.Error
+	})
+}
+
+// provision runs the insert and records the placeholder only when a row was actually created
+func (r *provisioningRepository) provision(entityType string, entityID, sourceEventID uuid.UUID, insert func(tx *gorm.DB) (int64, error)) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		created, err := insert(tx)
+		if err != nil || created == 0 {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ProvisionedEntity{
+			TenantID:      r.tenantID,
+			EntityType:    entityType,
+			EntityID:      entityID,
+			SourceEventID: sourceEventID,
+		}).Error
+	})
+}
+
+func (r *provisioningRepository) MarkReconciled(entityType string, entityID uuid.UUID) error {
+	return r.scoped().Model(&models.ProvisionedEntity{}).
+		Where("entity_type = ? AND entity_id = ? AND reconciled_at IS NULL", entityType, entityID).
+		Update("reconciled_at", gorm.Expr("NOW()")).Error
+}
+
+func (r *provisioningRepository) IsPlaceholder(entityType string, entityID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.scoped().Model(&models.ProvisionedEntity{}).
+		Where("entity_type = ? AND entity_id = ? AND reconciled_at IS NULL", entityType, entityID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetUnreconciledEntities lists placeholders that still lack the metadata a real record would have:
+// students and teachers without a name, questions without text, and sessions that never received SESSION_STARTED
+func (r *provisioningRepository) GetUnreconciledEntities(entityType string, pagination PaginationParams) (*PaginatedResponse[ProvisionedStub], error) {
+	var results []ProvisionedStub
+	var totalCount int64
+
+	const pending = `
+		FROM provisioned_entities pe
+		LEFT JOIN students s ON pe.entity_type = 'student' AND s.student_id = pe.entity_id
+		LEFT JOIN questions q ON pe.entity_type = 'question' AND q.question_id = pe.entity_id
+		LEFT JOIN teachers t ON pe.entity_type = 'teacher' AND t.teacher_id = pe.entity_id
+		WHERE (? = '' OR pe.entity_type = ?)
+			AND pe.reconciled_at IS NULL
+			AND (
+				(pe.entity_type = 'student' AND s.student_id IS NOT NULL AND s.name IS NULL)
+				OR (pe.entity_type = 'question' AND q.question_id IS NOT NULL AND q.text IS NULL)
+				OR (pe.entity_type = 'teacher' AND t.teacher_id IS NOT NULL AND t.name IS NULL)
+				OR pe.entity_type = 'session'
+			)
+	`
+
+	err := r.raw(`SELECT COUNT(*) `+pending, entityType, entityType).Scan(&totalCount).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.raw(`
+		SELECT 
+			pe.entity_type,
+			pe.entity_id,
+			pe.source_event_id,
+			pe.provisioned_at,
+			CASE pe.entity_type
+				WHEN 'student' THEN 'name'
+				WHEN 'teacher' THEN 'name'
+				WHEN 'question' THEN 'text'
+				ELSE 'session_started'
+			END as missing_field
+		`+pending+`
+		ORDER BY pe.provisioned_at
+		LIMIT ? OFFSET ?
+	`, entityType, entityType, pagination.PageSize, pagination.Offset).Scan(&results).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(results, pagination, int(totalCount))
+	return &response, nil
+}
+
+// TenantRepository implementations
+func (r *tenantRepository) CreateTenant(tenant *models.Tenant) error {
+	return r.db.Create(tenant).Error
+}
+
+func (r *tenantRepository) GetTenantByID(tenantID uuid.UUID) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := r.db.Where("tenant_id = ?", tenantID).First(&tenant).Error
+	return &tenant, err
+}
+
+func (r *tenantRepository) GetTenantBySlug(slug string) (*models.Tenant, error) {
+	var tenant models.Tenant
+	err := r.db.Where("slug = ?", slug).First(&tenant).Error
+	return &tenant, err
+}
+
+func (r *tenantRepository) ListTenants() ([]models.Tenant, error) {
+	var tenants []models.Tenant
+	err := r.db.Order("slug").Find(&tenants).Error
+	return tenants, err
+}
+
+func (r *tenantRepository) UpdateTenant(tenant *models.Tenant) error {
+	return r.db.Model(tenant).Updates(map[string]interface{}{
+		"name":                  tenant.Name,
+		"late_answer_policy":    tenant.LateAnswerPolicy,
+		"late_answer_grace_sec": tenant.LateAnswerGraceSec,
+		"retention_days":        tenant.RetentionDays,
+		"updated_at":            gorm.Expr("NOW()"),
+	}).Error
+}
+
+func (r *tenantRepository) PurgeExpiredSessions(tenant *models.Tenant, now time.Time, dryRun bool) (int64, error) {
+	if tenant.RetentionDays == nil {
+		return 0, nil
+	}
+	cutoff := now.AddDate(0, 0, -*tenant.RetentionDays)
+	expired := r.db.Model(&models.QuizSession{}).
+		Where("tenant_id = ? AND COALESCE(ended_at, started_at) < ?", tenant.TenantID, cutoff)
+
+	if dryRun {
+		var count int64
+		err := expired.Count(&count).Error
+		return count, err
+	}
+
+	var purged int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("tenant_id = ? AND COALESCE(ended_at, started_at) < ?", tenant.TenantID, cutoff).
+			Delete(&models.QuizSession{})
+		if result.Error != nil {
+			return result.Error
+		}
+		purged = result.RowsAffected
+		// provisioning records are not tied to sessions by a foreign key
+		return tx.Exec(`
+			DELETE FROM provisioned_entities pe
+			WHERE pe.tenant_id = ? AND pe.entity_type = 'session'
+			  AND NOT EXISTS (SELECT 1 FROM quiz_sessions qs WHERE qs.session_id = pe.entity_id)
+		`, tenant.TenantID).Error
+	})
+	return purged, err
+}
+
+// AuditRepository implementations
+func (r *auditRepository) AppendEntry(entry *models.AuditEntry) error {
+	entry.TenantID = nil
+	if r.tenantID != uuid.Nil {
+		tenantID := r.tenantID
+		entry.TenantID = &tenantID
+	}
+	return r.db.Create(entry).Error
+}
+
+func (r *auditRepository) ListEntries(filter AuditFilter, pagination PaginationParams) (*PaginatedResponse[models.AuditEntry], error) {
+	var entries []models.AuditEntry
+	var totalCount int64
+
+	if err := r.auditQuery(filter).Count(&totalCount).Error; err != nil {
+		return nil, err
+	}
+
+	err := r.auditQuery(filter).
+		Order("entry_id DESC").
+		Limit(pagination.PageSize).
+		Offset(pagination.Offset).
+		Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	response := NewPaginatedResponse(entries, pagination, int(totalCount))
+	return &response, nil
+}
+
+func (r *auditRepository) ExportEntries(filter AuditFilter, fn func(entries []models.AuditEntry) error) error {
+	var batch []models.AuditEntry
+	result := r.auditQuery(filter).Order("entry_id").FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+		return fn(batch)
+	})
+	return result.Error
+}
+
+// likeEscaper makes a string match itself literally in a LIKE pattern
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// auditQuery selects the tenant's entries matching the filter
+func (r *auditRepository) auditQuery(filter AuditFilter) *gorm.DB {
+	query := r.scoped().Model(&models.AuditEntry{})
+	if strings.HasSuffix(filter.Action, ".") {
+		query = query.Where("action LIKE ?", likeEscaper.Replace(filter.Action)+"%")
+	} else if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.UserID != nil {
+		query = query.Where("actor_user_id = ?", *filter.UserID)
+	}
+	if filter.From != nil {
+		query = query.Where("occurred_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("occurred_at < ?", *filter.To)
+	}
+	return query
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,8 +10,15 @@ import (
 
 // EventRepository handles event-related database operations
 type EventRepository interface {
+	// ForTenant returns a copy of the repository restricted to the tenant's rows
+	ForTenant(tenantID uuid.UUID) EventRepository
+	// WithContext returns a copy of the repository whose queries run under ctx, e.g. to trace them
+	WithContext(ctx context.Context) EventRepository
+
 	SaveQuestionPublishedEvent(event *models.QuestionPublishedEvent) error
 	SaveAnswerSubmittedEvent(event *models.AnswerSubmittedEvent) error
+	SavePresenceEvent(event *models.SessionPresenceEvent) error
+	SaveQuestionSkippedEvent(event *models.QuestionSkippedEvent) error
 
 	// Analytics methods with pagination support
 	GetActiveParticipants(sessionID uuid.UUID, timeRange time.Duration, pagination PaginationParams) (*PaginatedResponse[ParticipantMetrics], error)
@@ -21,11 +29,12 @@ type EventRepository interface {
 
 	// Critical methods for missing metrics
 	ValidateAnswerTiming(sessionID, questionID uuid.UUID, answerTimestamp time.Time) error
-	GetResponseRate(sessionID, questionID uuid.UUID) (*ResponseRateData, error)
+	GetResponseRate(sessionID, questionID uuid.UUID, denominator Denominator) (*ResponseRateData, error)
 	GetLatencyToFirstAnswer(sessionID, questionID uuid.UUID) (*LatencyData, error)
-	GetTimeoutAndSkippedRate(sessionID, questionID uuid.UUID) (*TimeoutData, error)
-	GetCompletionRate(sessionID uuid.UUID) (*CompletionRateData, error)
-	GetDropoffPoints(sessionID uuid.UUID) ([]DropoffPoint, error)
+	GetTimeoutAndSkippedRate(sessionID, questionID uuid.UUID, denominator Denominator) (*TimeoutData, error)
+	GetCompletionRate(sessionID uuid.UUID, denominator Denominator) (*CompletionRateData, error)
+	GetDropoffPoints(sessionID uuid.UUID, denominator Denominator) ([]DropoffPoint, error)
+	GetSessionAttendance(sessionID uuid.UUID, pagination PaginationParams) (*PaginatedResponse[AttendanceData], error)
 
 	// Paginated methods for large result sets
 	GetStudentPerformanceList(classroomID uuid.UUID, pagination PaginationParams) (*PaginatedResponse[StudentPerformanceData], error)
@@ -55,32 +64,149 @@ type EventRepository interface {
 	// Student activity summary - participation and quiz history
 	GetStudentActivitySummary(studentID, classroomID uuid.UUID) (*StudentActivitySummaryData, error)
 
+	// Teacher analytics - sessions are attributed through quiz_sessions.teacher_id
+	GetTeacherSummaries(pagination PaginationParams) (*PaginatedResponse[TeacherSummaryData], error)
+	GetTeacherSessions(teacherID uuid.UUID, pagination PaginationParams) (*PaginatedResponse[TeacherSessionData], error)
+
 	// Generic query execution for cube.dev-style analytics
 	ExecuteGenericQuery(sql string) ([]map[string]interface{}, error)
 }
 
 // QuizRepository handles quiz-related operations
 type QuizRepository interface {
+	ForTenant(tenantID uuid.UUID) QuizRepository
+	WithContext(ctx context.Context) QuizRepository
+
 	CreateQuiz(quiz *models.Quiz) error
 	GetQuizByID(quizID uuid.UUID) (*models.Quiz, error)
 	CreateQuestion(question *models.Question) error
 	GetQuestionByID(questionID uuid.UUID) (*models.Question, error)
+
+	// Content catalog management
+	ListQuizzes(includeArchived bool, pagination PaginationParams) (*PaginatedResponse[models.Quiz], error)
+	UpdateQuiz(quiz *models.Quiz) error
+	ArchiveQuiz(quizID uuid.UUID) error
+	ListQuizQuestions(quizID uuid.UUID, includeArchived bool, pagination PaginationParams) (*PaginatedResponse[models.Question], error)
+	// UpdateQuestion bumps the question version and snapshots the new content
+	UpdateQuestion(question *models.Question) error
+	ArchiveQuestion(questionID uuid.UUID) error
+	GetQuestionVersions(questionID uuid.UUID) ([]models.QuestionVersion, error)
 }
 
 // SessionRepository handles session-related operations
 type SessionRepository interface {
+	ForTenant(tenantID uuid.UUID) SessionRepository
+	WithContext(ctx context.Context) SessionRepository
+
 	CreateSession(session *models.QuizSession) error
 	GetSessionByID(sessionID uuid.UUID) (*models.QuizSession, error)
 	UpdateSession(session *models.QuizSession) error
+	// AttributeTeacher sets the session's teacher unless one is already recorded
+	AttributeTeacher(sessionID, teacherID uuid.UUID) error
 }
 
 // ClassroomRepository handles classroom and student operations
 type ClassroomRepository interface {
+	ForTenant(tenantID uuid.UUID) ClassroomRepository
+	WithContext(ctx context.Context) ClassroomRepository
+
 	CreateClassroom(classroom *models.Classroom) error
+	GetClassroomByID(classroomID uuid.UUID) (*models.Classroom, error)
 	CreateStudent(student *models.Student) error
+	GetStudentByID(studentID uuid.UUID) (*models.Student, error)
 	AddStudentToClassroom(classroomID, studentID uuid.UUID) error
 	GetClassroomStudents(classroomID uuid.UUID) ([]models.Student, error)
 
 	// Paginated classroom methods
 	GetClassroomStudentsPaginated(classroomID uuid.UUID, pagination PaginationParams) (*PaginatedResponse[models.Student], error)
+
+	// Roster management
+	ListClassrooms(includeArchived bool, pagination PaginationParams) (*PaginatedResponse[models.Classroom], error)
+	UpdateClassroom(classroom *models.Classroom) error
+	ArchiveClassroom(classroomID uuid.UUID) error
+	ListStudents(pagination PaginationParams) (*PaginatedResponse[models.Student], error)
+	UpdateStudent(student *models.Student) error
+	// EnrollStudent opens an enrollment interval at effectiveAt; already enrolled students are left as is
+	EnrollStudent(classroomID, studentID uuid.UUID, effectiveAt time.Time) error
+	// UnenrollStudent closes the open interval at effectiveAt; returns gorm.ErrRecordNotFound if none is open
+	UnenrollStudent(classroomID, studentID uuid.UUID, effectiveAt time.Time) error
+	GetEnrollmentHistory(filter EnrollmentFilter, pagination PaginationParams) (*PaginatedResponse[models.ClassroomEnrollment], error)
+	// ApplyRoster upserts schools, classrooms, students and enrollment changes in one transaction
+	ApplyRoster(batch RosterBatch) (*RosterResult, error)
+
+	// Schools group classrooms for school-level report access
+	CreateSchool(school *models.School) error
+	GetSchoolByID(schoolID uuid.UUID) (*models.School, error)
+	ListSchools(pagination PaginationParams) (*PaginatedResponse[models.School], error)
+	UpdateSchool(school *models.School) error
+	GetSchoolClassroomIDs(schoolID uuid.UUID) ([]uuid.UUID, error)
+
+	// Roster import lookups
+	GetSchoolsBySourcedIDs(sourcedIDs []string) ([]models.School, error)
+	GetClassroomsBySourcedIDs(sourcedIDs []string) ([]models.Classroom, error)
+	GetStudentsBySourcedIDs(sourcedIDs []string) ([]models.Student, error)
+	GetEnrollmentsForClassrooms(classroomIDs []uuid.UUID) ([]models.ClassroomEnrollment, error)
+}
+
+// TeacherRepository handles teachers and their classroom assignments
+type TeacherRepository interface {
+	ForTenant(tenantID uuid.UUID) TeacherRepository
+	WithContext(ctx context.Context) TeacherRepository
+
+	CreateTeacher(teacher *models.Teacher) error
+	GetTeacherByID(teacherID uuid.UUID) (*models.Teacher, error)
+	ListTeachers(includeArchived bool, pagination PaginationParams) (*PaginatedResponse[models.Teacher], error)
+	UpdateTeacher(teacher *models.Teacher) error
+	ArchiveTeacher(teacherID uuid.UUID) error
+
+	// AssignTeacher adds the teacher to the classroom or updates the role of an existing assignment
+	AssignTeacher(classroomID, teacherID uuid.UUID, role string) error
+	// UnassignTeacher returns gorm.ErrRecordNotFound if the teacher is not assigned
+	UnassignTeacher(classroomID, teacherID uuid.UUID) error
+	GetClassroomTeachers(classroomID uuid.UUID) ([]TeacherAssignment, error)
+	GetTeacherClassrooms(teacherID uuid.UUID) ([]TeacherAssignment, error)
+	// GetPrimaryTeacher returns the earliest-assigned primary teacher, or gorm.ErrRecordNotFound
+	GetPrimaryTeacher(classroomID uuid.UUID) (*models.Teacher, error)
+}
+
+// ProvisioningRepository creates placeholder entities for events that reference
+// unknown IDs and reports the ones still missing metadata
+type ProvisioningRepository interface {
+	ForTenant(tenantID uuid.UUID) ProvisioningRepository
+	WithContext(ctx context.Context) ProvisioningRepository
+
+	// Provision* insert the placeholder if it does not exist yet; concurrent callers are safe
+	ProvisionSession(session *models.QuizSession, sourceEventID uuid.UUID) error
+	ProvisionQuestion(question *models.Question, sourceEventID uuid.UUID) error
+	ProvisionStudent(student *models.Student, sourceEventID uuid.UUID) error
+	ProvisionTeacher(teacher *models.Teacher, sourceEventID uuid.UUID) error
+	MarkReconciled(entityType string, entityID uuid.UUID) error
+	// IsPlaceholder reports whether the entity was provisioned and is still waiting for its real data
+	IsPlaceholder(entityType string, entityID uuid.UUID) (bool, error)
+
+	GetUnreconciledEntities(entityType string, pagination PaginationParams) (*PaginatedResponse[ProvisionedStub], error)
+}
+
+// TenantRepository manages tenants and their settings. It is not tenant-scoped.
+type TenantRepository interface {
+	CreateTenant(tenant *models.Tenant) error
+	GetTenantByID(tenantID uuid.UUID) (*models.Tenant, error)
+	GetTenantBySlug(slug string) (*models.Tenant, error)
+	ListTenants() ([]models.Tenant, error)
+	UpdateTenant(tenant *models.Tenant) error
+	// PurgeExpiredSessions deletes sessions (and, by cascade, their events) that
+	// ended before the tenant's retention cutoff; dryRun only counts them
+	PurgeExpiredSessions(tenant *models.Tenant, now time.Time, dryRun bool) (int64, error)
+}
+
+// AuditRepository appends to and reads the audit log. Entries cannot be changed once written.
+type AuditRepository interface {
+	// ForTenant returns a copy of the repository restricted to the tenant's entries.
+	// Without a tenant, entries are appended tenantless and none can be read.
+	ForTenant(tenantID uuid.UUID) AuditRepository
+
+	AppendEntry(entry *models.AuditEntry) error
+	ListEntries(filter AuditFilter, pagination PaginationParams) (*PaginatedResponse[models.AuditEntry], error)
+	// ExportEntries passes the matching entries, oldest first, to fn in batches
+	ExportEntries(filter AuditFilter, fn func(entries []models.AuditEntry) error) error
 }
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantTables lists every table carrying a tenant_id column - matches 000017, 000018 and 000023 migrations
+var tenantTables = []string{
+	"quizzes",
+	"questions",
+	"question_versions",
+	"schools",
+	"classrooms",
+	"students",
+	"teachers",
+	"classroom_students",
+	"classroom_enrollments",
+	"classroom_teachers",
+	"quiz_sessions",
+	"question_published_events",
+	"answer_submitted_events",
+	"session_presence_events",
+	"question_skipped_events",
+	"provisioned_entities",
+	"audit_log",
+}
+
+// tenantScope restricts a repository to the rows of one tenant. Repositories
+// built by the New* constructors use uuid.Nil, which matches no rows, until
+// ForTenant is called.
+type tenantScope struct {
+	db       *gorm.DB
+	tenantID uuid.UUID
+}
+
+// withContext is the scope with queries running under ctx
+func (s tenantScope) withContext(ctx context.Context) tenantScope {
+	return tenantScope{db: s.db.WithContext(ctx), tenantID: s.tenantID}
+}
+
+// scoped restricts builder queries on the statement's table to the tenant
+func (s tenantScope) scoped() *gorm.DB {
+	return s.scope(s.db)
+}
+
+// scope is scoped for a transaction handle
+func (s tenantScope) scope(tx *gorm.DB) *gorm.DB {
+	return tx.Where(clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"},
+		Value:  s.tenantID,
+	})
+}
+
+// sameTenant limits an upsert's DO UPDATE to rows of the inserted row's tenant,
+// so conflicting with another tenant's ID updates nothing instead of its row
+func sameTenant(table string) clause.Where {
+	return clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: table + ".tenant_id = EXCLUDED.tenant_id"}}}
+}
+
+// raw runs a raw query in which every tenant table only contains the tenant's rows
+func (s tenantScope) raw(sql string, values ...interface{}) *gorm.DB {
+	return s.db.Raw(scopeSQL(sql, s.tenantID), values...)
+}
+
+// scopeSQL shadows each tenant table with a CTE of the same name holding only
+// the tenant's rows, so unqualified table references in the query cannot see
+// other tenants. Queries that already start with WITH get the shadows merged
+// in front of their own CTEs. A WITH RECURSIVE query is wrapped in a subquery
+// instead, as under RECURSIVE each shadow would read itself rather than the
+// table. Only SELECTs are supported; writes must scope their target table
+// explicitly.
+func scopeSQL(sql string, tenantID uuid.UUID) string {
+	shadows := make([]string, len(tenantTables))
+	for i, table := range tenantTables {
+		shadows[i] = fmt.Sprintf("%s AS NOT MATERIALIZED (SELECT * FROM %s WHERE tenant_id = '%s')", table, table, tenantID)
+	}
+	prefix := "WITH " + strings.Join(shadows, ",\n")
+
+	if rest, ok := cutKeyword(sql, "WITH"); ok {
+		if _, recursive := cutKeyword(rest, "RECURSIVE"); recursive {
+			return prefix + "\nSELECT * FROM (\n" + sql + "\n) AS scoped"
+		}
+		return prefix + ",\n" + rest
+	}
+	return prefix + "\n" + sql
+}
+
+// cutKeyword returns sql after its leading keyword, matched case-insensitively
+// and followed by whitespace, and whether sql started with it
+func cutKeyword(sql, keyword string) (string, bool) {
+	trimmed := strings.TrimLeft(sql, " \t\r\n")
+	n := len(keyword)
+	if len(trimmed) <= n || !strings.EqualFold(trimmed[:n], keyword) || !strings.ContainsRune(" \t\r\n", rune(trimmed[n])) {
+		return sql, false
+	}
+	return strings.TrimLeft(trimmed[n:], " \t\r\n"), true
+}
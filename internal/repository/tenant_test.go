@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/analytics"
+)
+
+var testTenant = uuid.MustParse("11111111-1111-1111-1111-111111111111")
+
+func TestScopeSQL(t *testing.T) {
+	cases := []struct {
+		name   string
+		sql    string
+		suffix string // what follows the tenant shadows
+	}{
+		{
+			name:   "plain",
+			sql:    "SELECT * FROM classrooms",
+			suffix: "')\nSELECT * FROM classrooms",
+		},
+		{
+			name:   "with",
+			sql:    "  WITH recent AS (SELECT * FROM quiz_sessions) SELECT * FROM recent",
+			suffix: "'),\nrecent AS (SELECT * FROM quiz_sessions) SELECT * FROM recent",
+		},
+		{
+			name:   "with lowercase and newline",
+			sql:    "with\nrecent AS (SELECT 1) SELECT * FROM recent",
+			suffix: "'),\nrecent AS (SELECT 1) SELECT * FROM recent",
+		},
+		{
+			name:   "with recursive",
+			sql:    "WITH RECURSIVE tree AS (SELECT 1 AS n UNION ALL SELECT n + 1 FROM tree WHERE n < 3) SELECT n FROM tree",
+			suffix: "')\nSELECT * FROM (\nWITH RECURSIVE tree AS (SELECT 1 AS n UNION ALL SELECT n + 1 FROM tree WHERE n < 3) SELECT n FROM tree\n) AS scoped",
+		},
+		{
+			name:   "with recursive lowercase",
+			sql:    "with  recursive\ttree AS (SELECT 1) SELECT * FROM tree",
+			suffix: "')\nSELECT * FROM (\nwith  recursive\ttree AS (SELECT 1) SELECT * FROM tree\n) AS scoped",
+		},
+		{
+			name:   "table named like the keyword",
+			sql:    "SELECT * FROM withdrawals",
+			suffix: "')\nSELECT * FROM withdrawals",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			scoped := scopeSQL(tc.sql, testTenant)
+			if !strings.HasPrefix(scoped, "WITH "+tenantTables[0]+" AS NOT MATERIALIZED") {
+				t.Fatalf("shadows missing:\n%s", scoped)
+			}
+			if !strings.HasSuffix(scoped, tc.suffix) {
+				t.Errorf("got\n%s\nwant suffix\n%s", scoped, tc.suffix)
+			}
+			for _, table := range tenantTables {
+				shadow := table + " AS NOT MATERIALIZED (SELECT * FROM " + table + " WHERE tenant_id = '" + testTenant.String() + "')"
+				if !strings.Contains(scoped, shadow) {
+					t.Errorf("no shadow for %s", table)
+				}
+			}
+		})
+	}
+}
+
+// A filter value cannot close its literal and reach tables outside the tenant shadows,
+// such as another tenant's rows through a schema-qualified name
+func TestGenericQueryFilterStaysInTenant(t *testing.T) {
+	payloads := []string{
+		"x' OR '1'='1",
+		"x' UNION SELECT * FROM public.answer_submitted_events --",
+		"x'' OR tenant_id <> tenant_id --",
+		`x\' OR 1=1 --`,
+	}
+	for _, payload := range payloads {
+		request := analytics.QueryRequest{
+			Measures:   []string{"total_answers"},
+			Dimensions: []string{"classroom_name"},
+			Filters:    map[string]string{"classroom_name": payload},
+		}
+		sql, err := request.BuildSQL(nil)
+		if err != nil {
+			t.Fatalf("BuildSQL: %v", err)
+		}
+		code, ok := stripLiterals(scopeSQL(sql, testTenant))
+		if !ok {
+			t.Fatalf("unterminated literal for %q:\n%s", payload, sql)
+		}
+		for _, injected := range []string{"public.", "UNION", "--", " OR ", "<>"} {
+			if strings.Contains(code, injected) {
+				t.Errorf("filter %q reached the query as code (%q):\n%s", payload, injected, code)
+			}
+		}
+		if n := strings.Count(code, "tenant_id"); n != len(tenantTables) {
+			t.Errorf("filter %q: %d tenant_id references outside literals, want %d", payload, n, len(tenantTables))
+		}
+	}
+}
+
+func TestGenericQueryOrderByWhitelist(t *testing.T) {
+	for _, field := range []string{"public.answer_submitted_events", "(SELECT 1)", "total_answers; DROP TABLE users", "student_name"} {
+		request := analytics.QueryRequest{
+			Measures:   []string{"total_answers"},
+			Dimensions: []string{"classroom_name"},
+			OrderBy:    []analytics.OrderBy{{Field: field, Order: "DESC"}},
+		}
+		if _, err := request.BuildSQL(nil); err == nil {
+			t.Errorf("ordering by %q was accepted", field)
+		}
+	}
+
+	request := analytics.QueryRequest{
+		Measures: []string{"total_answers"},
+		OrderBy:  []analytics.OrderBy{{Field: "total_answers", Order: "DESC; DROP TABLE users"}},
+	}
+	if _, err := request.BuildSQL(nil); err == nil {
+		t.Error("an order other than ASC or DESC was accepted")
+	}
+}
+
+// stripLiterals removes the SQL string literals from sql, reading a doubled quote as an
+// escaped one the way Postgres does with standard_conforming_strings on
+func stripLiterals(sql string) (string, bool) {
+	var code strings.Builder
+	inLiteral := false
+	for i := 0; i < len(sql); i++ {
+		if sql[i] != '\'' {
+			if !inLiteral {
+				code.WriteByte(sql[i])
+			}
+			continue
+		}
+		if inLiteral && i+1 < len(sql) && sql[i+1] == '\'' {
+			i++
+			continue
+		}
+		inLiteral = !inLiteral
+		code.WriteString("''")
+	}
+	return code.String(), !inLiteral
+}
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
 )
 
 // Pagination support
@@ -23,6 +26,9 @@ type PaginatedResponse[T any] struct {
 	HasPrevious bool `json:"has_previous"`
 }
 
+// MaxPageSize caps every page, whatever the caller or configuration asks for
+const MaxPageSize = 1000
+
 // Helper function to create pagination params with defaults and validation
 func NewPaginationParams(page, pageSize int) PaginationParams {
 	// Set defaults
@@ -34,9 +40,8 @@ func NewPaginationParams(page, pageSize int) PaginationParams {
 	}
 
 	// Enforce maximum page size for performance
-	maxPageSize := 1000
-	if pageSize > maxPageSize {
-		pageSize = maxPageSize
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
 	}
 
 	return PaginationParams{
@@ -126,14 +131,21 @@ type LatencyData struct {
 	MedianLatency      time.Duration `json:"median_latency"`
 }
 
+// TimeoutData splits students who did not answer into skipped (sent QUESTION_SKIPPED),
+// absent (not in the session while the question was open) and timeout (present but silent).
+// Without presence events for the session nobody is counted as absent.
 type TimeoutData struct {
-	QuestionID    uuid.UUID `json:"question_id"`
-	SessionID     uuid.UUID `json:"session_id"`
-	TotalStudents int       `json:"total_students"`
-	TimeoutCount  int       `json:"timeout_count"`
-	SkippedCount  int       `json:"skipped_count"`
-	TimeoutRate   float64   `json:"timeout_rate"`
-	SkippedRate   float64   `json:"skipped_rate"`
+	QuestionID      uuid.UUID `json:"question_id"`
+	SessionID       uuid.UUID `json:"session_id"`
+	TotalStudents   int       `json:"total_students"`
+	AnsweredCount   int       `json:"answered_count"`
+	TimeoutCount    int       `json:"timeout_count"`
+	SkippedCount    int       `json:"skipped_count"`
+	AbsentCount     int       `json:"absent_count"`
+	TimeoutRate     float64   `json:"timeout_rate"`
+	SkippedRate     float64   `json:"skipped_rate"`
+	AbsentRate      float64   `json:"absent_rate"`
+	PresenceTracked bool      `json:"presence_tracked"`
 }
 
 type CompletionRateData struct {
@@ -145,8 +157,22 @@ type CompletionRateData struct {
 	AverageCompletion float64   `json:"average_completion"`
 }
 
+// AttendanceData is one student's presence in a session
+type AttendanceData struct {
+	StudentID         uuid.UUID  `json:"student_id"`
+	StudentName       *string    `json:"student_name"`
+	Enrolled          bool       `json:"enrolled"` // enrolled when the session started
+	FirstJoinedAt     *time.Time `json:"first_joined_at"`
+	LastLeftAt        *time.Time `json:"last_left_at"`
+	JoinCount         int        `json:"join_count"`
+	PresentSeconds    int        `json:"present_seconds"`
+	QuestionsAnswered int        `json:"questions_answered"`
+	QuestionsSkipped  int        `json:"questions_skipped"`
+}
+
 type DropoffPoint struct {
 	QuestionID      uuid.UUID `json:"question_id"`
+	QuestionText    *string   `json:"question_text"`
 	QuestionOrder   int       `json:"question_order"`
 	StudentsAtStart int       `json:"students_at_start"`
 	StudentsAtEnd   int       `json:"students_at_end"`
@@ -173,15 +199,22 @@ type QuizSummaryData struct {
 
 // Question Analysis - performance of individual questions across all sessions
 type QuestionAnalysisData struct {
-	QuestionID          uuid.UUID      `json:"question_id"`
-	QuizID              uuid.UUID      `json:"quiz_id"`
-	TotalAttempts       int            `json:"total_attempts"`
-	CorrectAttempts     int            `json:"correct_attempts"`
-	AccuracyRate        float64        `json:"accuracy_rate"`
-	AverageResponseTime float64        `json:"average_response_time_seconds"`
-	DifficultyRating    string         `json:"difficulty_rating"`
-	AnswerDistribution  map[string]int `json:"answer_distribution"`
-	UsageCount          int            `json:"usage_count"` // times this question was published
+	QuestionID          uuid.UUID              `json:"question_id"`
+	QuizID              uuid.UUID              `json:"quiz_id"`
+	QuestionText        *string                `json:"question_text"`
+	Options             models.QuestionOptions `json:"options,omitempty"`
+	Position            *int                   `json:"position"`
+	Points              float64                `json:"points"`
+	Version             int                    `json:"version"`
+	TotalAttempts       int                    `json:"total_attempts"`
+	CorrectAttempts     int                    `json:"correct_attempts"`
+	QuestionType        string                 `json:"question_type"`
+	AccuracyRate        float64                `json:"accuracy_rate"`
+	AverageScore        float64                `json:"average_score"` // partial-credit aware, 0-100
+	AverageResponseTime float64                `json:"average_response_time_seconds"`
+	DifficultyRating    string                 `json:"difficulty_rating"`
+	AnswerDistribution  map[string]int         `json:"answer_distribution"`
+	UsageCount          int                    `json:"usage_count"` // times this question was published
 }
 
 // Session Comparison - compare sessions within classroom or across classrooms
@@ -261,3 +294,123 @@ type StudentActivitySummaryData struct {
 	FirstActivity             *time.Time `json:"first_activity"`
 	LastActivity              *time.Time `json:"last_activity"`
 }
+
+// Teacher Summary - sessions taught, pacing and class accuracy per teacher
+type TeacherSummaryData struct {
+	TeacherID          uuid.UUID  `json:"teacher_id"`
+	TeacherName        *string    `json:"teacher_name"`
+	ClassroomsAssigned int        `json:"classrooms_assigned"`
+	SessionsTaught     int        `json:"sessions_taught"`
+	QuestionsPublished int        `json:"questions_published"`
+	QuestionsPerMinute float64    `json:"average_questions_per_minute"`
+	SecondsPerQuestion *float64   `json:"average_seconds_between_questions"` // nil until a session has two questions
+	AverageAccuracy    *float64   `json:"average_class_accuracy"`            // nil until a session has answers
+	LastSessionAt      *time.Time `json:"last_session_at"`
+}
+
+// Teacher Sessions - per-session pacing and accuracy for one teacher
+type TeacherSessionData struct {
+	SessionID             uuid.UUID  `json:"session_id"`
+	QuizTitle             string     `json:"quiz_title"`
+	ClassroomID           uuid.UUID  `json:"classroom_id"`
+	ClassroomName         string     `json:"classroom_name"`
+	StartedAt             time.Time  `json:"started_at"`
+	EndedAt               *time.Time `json:"ended_at"`
+	QuestionsPublished    int        `json:"questions_published"`
+	QuestionsPerMinute    float64    `json:"questions_per_minute"`
+	SecondsPerQuestion    *float64   `json:"average_seconds_between_questions"`
+	ParticipatingStudents int        `json:"participating_students"`
+	AverageAccuracy       *float64   `json:"average_accuracy"`
+}
+
+// ProvisionedStub is an auto-created placeholder that still lacks metadata
+type ProvisionedStub struct {
+	EntityType    string    `json:"entity_type"`
+	EntityID      uuid.UUID `json:"entity_id"`
+	SourceEventID uuid.UUID `json:"source_event_id"`
+	ProvisionedAt time.Time `json:"provisioned_at"`
+	MissingField  string    `json:"missing_field"`
+}
+
+// ErrInvalidEnrollmentInterval is returned when an enrollment change would overlap an
+// earlier interval or close an interval before it started
+var ErrInvalidEnrollmentInterval = errors.New("enrollment interval overlaps existing history")
+
+// LateAnswerError is returned by ValidateAnswerTiming when an answer arrives after the question's deadline
+type LateAnswerError struct {
+	Deadline    time.Time
+	SubmittedAt time.Time
+}
+
+func (e *LateAnswerError) Error() string {
+	return fmt.Sprintf("answer submitted at %v is after deadline %v", e.SubmittedAt, e.Deadline)
+}
+
+// ErrTenantMismatch is returned when a write targets an ID owned by another tenant
+var ErrTenantMismatch = errors.New("record belongs to another tenant")
+
+// EnrollmentFilter narrows enrollment history to a classroom, a student, or both
+type EnrollmentFilter struct {
+	ClassroomID *uuid.UUID
+	StudentID   *uuid.UUID
+	// ClassroomIDs limits history to these classrooms when not nil; an empty list matches nothing
+	ClassroomIDs []uuid.UUID
+}
+
+// TeacherAssignment is a teacher's assignment to a classroom, with both names resolved
+type TeacherAssignment struct {
+	ClassroomID   uuid.UUID `json:"classroom_id"`
+	ClassroomName string    `json:"classroom_name"`
+	TeacherID     uuid.UUID `json:"teacher_id"`
+	TeacherName   *string   `json:"teacher_name"`
+	Role          string    `json:"role"`
+	AssignedAt    time.Time `json:"assigned_at"`
+}
+
+// RosterEnrollment is one enrollment change in a bulk roster upsert
+type RosterEnrollment struct {
+	ClassroomID uuid.UUID `json:"classroom_id"`
+	StudentID   uuid.UUID `json:"student_id"`
+	// Active false unenrolls the student
+	Active      bool      `json:"active"`
+	EffectiveAt time.Time `json:"effective_at"`
+}
+
+// RosterBatch is a bulk roster upsert, applied schools first, then classrooms, students and enrollments
+type RosterBatch struct {
+	Schools     []models.School    `json:"schools,omitempty"`
+	Classrooms  []models.Classroom `json:"classrooms"`
+	Students    []models.Student   `json:"students"`
+	Enrollments []RosterEnrollment `json:"enrollments"`
+	// ArchiveClassrooms lists classrooms to archive after the upserts
+	ArchiveClassrooms []uuid.UUID `json:"archive_classrooms,omitempty"`
+}
+
+// RosterResult counts what a bulk roster upsert changed
+type RosterResult struct {
+	SchoolsUpserted    int `json:"schools_upserted"`
+	ClassroomsUpserted int `json:"classrooms_upserted"`
+	ClassroomsArchived int `json:"classrooms_archived"`
+	StudentsUpserted   int `json:"students_upserted"`
+	Enrolled           int `json:"enrolled"`
+	Unenrolled         int `json:"unenrolled"`
+}
+
+// Denominator selects which students count as the audience of a session
+type Denominator string
+
+const (
+	// DenominatorEnrolled counts students enrolled in the classroom when the session started
+	DenominatorEnrolled Denominator = "enrolled"
+	// DenominatorPresent further restricts to students who sent any event in the session
+	DenominatorPresent Denominator = "present"
+)
+
+// AuditFilter narrows audit log queries; zero fields match everything
+type AuditFilter struct {
+	Action  string // exact action, or a prefix ending in "." such as "user."
+	Outcome string
+	UserID  *uint
+	From    *time.Time
+	To      *time.Time
+}
@@ -0,0 +1,112 @@
+package roster
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+)
+
+// visibility is the part of the roster a caller may read: the whole tenant, the
+// classrooms of a school or teacher and the students enrolled in them, or one student
+type visibility struct {
+	tenantWide   bool
+	classroomIDs []uuid.UUID
+	studentID    *uuid.UUID
+}
+
+// visibility resolves the caller's access the way reports do; a device key limited to
+// classrooms only sees those
+func (h *Handler) visibility(c *gin.Context) (visibility, bool) {
+	v, err := h.tenantService(c).visibility(auth.UserAccess(c), auth.ClassroomIDs(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return visibility{}, false
+	}
+	return v, true
+}
+
+// authorizeClassroom writes a 403 and returns false when the caller cannot see the classroom's students
+func (h *Handler) authorizeClassroom(c *gin.Context, classroomID uuid.UUID) (visibility, bool) {
+	v, ok := h.visibility(c)
+	if !ok {
+		return v, false
+	}
+	if !v.tenantWide && !slices.Contains(v.classroomIDs, classroomID) {
+		forbidden(c)
+		return v, false
+	}
+	return v, true
+}
+
+// authorizeStudent writes a 403 and returns false when the caller cannot see the student
+func (h *Handler) authorizeStudent(c *gin.Context, studentID uuid.UUID) (visibility, bool) {
+	v, ok := h.visibility(c)
+	if !ok {
+		return v, false
+	}
+	allowed, err := h.tenantService(c).studentVisible(v, studentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return v, false
+	}
+	if !allowed {
+		forbidden(c)
+		return v, false
+	}
+	return v, true
+}
+
+func forbidden(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"error": "the requested resource is outside your access"})
+}
+
+func (s *Service) visibility(access auth.Access, keyClassrooms []uuid.UUID) (visibility, error) {
+	switch access.Level {
+	case auth.AccessTenant:
+		if keyClassrooms != nil {
+			return visibility{classroomIDs: keyClassrooms}, nil
+		}
+		return visibility{tenantWide: true}, nil
+	case auth.AccessSchool:
+		classroomIDs, err := s.ClassroomRepo.GetSchoolClassroomIDs(*access.SchoolID)
+		return visibility{classroomIDs: classroomIDs}, err
+	case auth.AccessTeacher:
+		assignments, err := s.TeacherRepo.GetTeacherClassrooms(*access.TeacherID)
+		if err != nil {
+			return visibility{}, err
+		}
+		classroomIDs := make([]uuid.UUID, len(assignments))
+		for i, assignment := range assignments {
+			classroomIDs[i] = assignment.ClassroomID
+		}
+		return visibility{classroomIDs: classroomIDs}, nil
+	case auth.AccessStudent:
+		return visibility{studentID: access.StudentID}, nil
+	}
+	return visibility{}, nil
+}
+
+// studentVisible reports whether the student is the caller or was ever enrolled in one
+// of the caller's classrooms
+func (s *Service) studentVisible(v visibility, studentID uuid.UUID) (bool, error) {
+	if v.tenantWide {
+		return true, nil
+	}
+	if v.studentID != nil {
+		return *v.studentID == studentID, nil
+	}
+	if len(v.classroomIDs) == 0 {
+		return false, nil
+	}
+	enrollments, err := s.ClassroomRepo.GetEnrollmentHistory(
+		repository.EnrollmentFilter{StudentID: &studentID, ClassroomIDs: v.classroomIDs},
+		repository.NewPaginationParams(1, 1))
+	if err != nil {
+		return false, err
+	}
+	return enrollments.TotalCount > 0, nil
+}
@@ -0,0 +1,590 @@
+package roster
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+)
+
+type Handler struct {
+	service *Service
+	audit   auth.Auditor
+}
+
+func NewHandler(s *Service, auditor auth.Auditor) *Handler {
+	return &Handler{service: s, audit: auditor}
+}
+
+// tenantService returns the service scoped to the caller's tenant
+func (h *Handler) tenantService(c *gin.Context) *Service {
+	return h.service.ForTenant(auth.TenantID(c))
+}
+
+// Helper function to parse pagination parameters
+func parsePaginationParams(c *gin.Context) repository.PaginationParams {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	return repository.NewPaginationParams(page, pageSize)
+}
+
+// CreateSchool handles POST /api/schools
+func (h *Handler) CreateSchool(c *gin.Context) {
+	var req SchoolInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	school, err := h.tenantService(c).CreateSchool(req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, school)
+}
+
+// ListSchools handles GET /api/schools
+func (h *Handler) ListSchools(c *gin.Context) {
+	pagination := parsePaginationParams(c)
+
+	data, err := h.tenantService(c).ListSchools(pagination)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetSchool handles GET /api/schools/:school_id
+func (h *Handler) GetSchool(c *gin.Context) {
+	schoolID, ok := parseIDParam(c, "school_id")
+	if !ok {
+		return
+	}
+
+	school, err := h.tenantService(c).GetSchool(schoolID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, school)
+}
+
+// UpdateSchool handles PUT /api/schools/:school_id
+func (h *Handler) UpdateSchool(c *gin.Context) {
+	schoolID, ok := parseIDParam(c, "school_id")
+	if !ok {
+		return
+	}
+
+	var req SchoolInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	school, err := h.tenantService(c).UpdateSchool(schoolID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, school)
+}
+
+// CreateClassroom handles POST /api/classrooms
+func (h *Handler) CreateClassroom(c *gin.Context) {
+	var req ClassroomInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	classroom, err := h.tenantService(c).CreateClassroom(req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, classroom)
+}
+
+// ListClassrooms handles GET /api/classrooms
+func (h *Handler) ListClassrooms(c *gin.Context) {
+	includeArchived := c.Query("include_archived") == "true"
+	pagination := parsePaginationParams(c)
+
+	data, err := h.tenantService(c).ListClassrooms(includeArchived, pagination)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetClassroom handles GET /api/classrooms/:classroom_id
+func (h *Handler) GetClassroom(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+
+	classroom, err := h.tenantService(c).GetClassroom(classroomID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, classroom)
+}
+
+// UpdateClassroom handles PUT /api/classrooms/:classroom_id
+func (h *Handler) UpdateClassroom(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+
+	var req ClassroomInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	classroom, err := h.tenantService(c).UpdateClassroom(classroomID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, classroom)
+}
+
+// ArchiveClassroom handles DELETE /api/classrooms/:classroom_id
+func (h *Handler) ArchiveClassroom(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+
+	if err := h.tenantService(c).ArchiveClassroom(classroomID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListClassroomStudents handles GET /api/classrooms/:classroom_id/students
+func (h *Handler) ListClassroomStudents(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeClassroom(c, classroomID); !ok {
+		return
+	}
+
+	pagination := parsePaginationParams(c)
+
+	data, err := h.tenantService(c).ListClassroomStudents(classroomID, pagination)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// EnrollStudent handles POST /api/classrooms/:classroom_id/students
+func (h *Handler) EnrollStudent(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+
+	var req EnrollmentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tenantService(c).EnrollStudent(classroomID, req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Student enrolled",
+		"classroom_id": classroomID,
+		"student_id":   req.StudentID,
+	})
+}
+
+// UnenrollStudent handles DELETE /api/classrooms/:classroom_id/students/:student_id
+func (h *Handler) UnenrollStudent(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+	studentID, ok := parseIDParam(c, "student_id")
+	if !ok {
+		return
+	}
+
+	var effectiveAt *time.Time
+	if raw := c.Query("effective_at"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid effective_at format, use RFC3339"})
+			return
+		}
+		effectiveAt = &parsed
+	}
+
+	if err := h.tenantService(c).UnenrollStudent(classroomID, studentID, effectiveAt); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetClassroomEnrollments handles GET /api/classrooms/:classroom_id/enrollments
+func (h *Handler) GetClassroomEnrollments(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeClassroom(c, classroomID); !ok {
+		return
+	}
+
+	h.enrollmentHistory(c, repository.EnrollmentFilter{ClassroomID: &classroomID})
+}
+
+// CreateStudent handles POST /api/students
+func (h *Handler) CreateStudent(c *gin.Context) {
+	var req StudentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	student, err := h.tenantService(c).CreateStudent(req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, student)
+}
+
+// ListStudents handles GET /api/students; listing every student needs tenant-wide access
+func (h *Handler) ListStudents(c *gin.Context) {
+	v, ok := h.visibility(c)
+	if !ok {
+		return
+	}
+	if !v.tenantWide {
+		c.JSON(http.StatusForbidden, gin.H{"error": "requires tenant-wide access; list a classroom's students instead"})
+		return
+	}
+
+	pagination := parsePaginationParams(c)
+
+	data, err := h.tenantService(c).ListStudents(pagination)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetStudent handles GET /api/students/:student_id
+func (h *Handler) GetStudent(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "student_id")
+	if !ok {
+		return
+	}
+	if _, ok := h.authorizeStudent(c, studentID); !ok {
+		return
+	}
+
+	student, err := h.tenantService(c).GetStudent(studentID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, student)
+}
+
+// UpdateStudent handles PUT /api/students/:student_id
+func (h *Handler) UpdateStudent(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "student_id")
+	if !ok {
+		return
+	}
+
+	var req StudentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	student, err := h.tenantService(c).UpdateStudent(studentID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, student)
+}
+
+// GetStudentEnrollments handles GET /api/students/:student_id/enrollments
+func (h *Handler) GetStudentEnrollments(c *gin.Context) {
+	studentID, ok := parseIDParam(c, "student_id")
+	if !ok {
+		return
+	}
+	v, ok := h.authorizeStudent(c, studentID)
+	if !ok {
+		return
+	}
+
+	// school and teacher access only see the enrollments in their own classrooms
+	filter := repository.EnrollmentFilter{StudentID: &studentID}
+	if !v.tenantWide && v.studentID == nil {
+		filter.ClassroomIDs = v.classroomIDs
+	}
+	h.enrollmentHistory(c, filter)
+}
+
+// ListClassroomTeachers handles GET /api/classrooms/:classroom_id/teachers
+func (h *Handler) ListClassroomTeachers(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+
+	teachers, err := h.tenantService(c).ListClassroomTeachers(classroomID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"classroom_id": classroomID, "teachers": teachers})
+}
+
+// AssignTeacher handles POST /api/classrooms/:classroom_id/teachers
+func (h *Handler) AssignTeacher(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+
+	var req TeacherAssignmentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tenantService(c).AssignTeacher(classroomID, req); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Teacher assigned",
+		"classroom_id": classroomID,
+		"teacher_id":   req.TeacherID,
+	})
+}
+
+// UnassignTeacher handles DELETE /api/classrooms/:classroom_id/teachers/:teacher_id
+func (h *Handler) UnassignTeacher(c *gin.Context) {
+	classroomID, ok := parseIDParam(c, "classroom_id")
+	if !ok {
+		return
+	}
+	teacherID, ok := parseIDParam(c, "teacher_id")
+	if !ok {
+		return
+	}
+
+	if err := h.tenantService(c).UnassignTeacher(classroomID, teacherID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// CreateTeacher handles POST /api/teachers
+func (h *Handler) CreateTeacher(c *gin.Context) {
+	var req TeacherInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	teacher, err := h.tenantService(c).CreateTeacher(req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, teacher)
+}
+
+// ListTeachers handles GET /api/teachers
+func (h *Handler) ListTeachers(c *gin.Context) {
+	includeArchived := c.Query("include_archived") == "true"
+	pagination := parsePaginationParams(c)
+
+	data, err := h.tenantService(c).ListTeachers(includeArchived, pagination)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// GetTeacher handles GET /api/teachers/:teacher_id
+func (h *Handler) GetTeacher(c *gin.Context) {
+	teacherID, ok := parseIDParam(c, "teacher_id")
+	if !ok {
+		return
+	}
+
+	teacher, err := h.tenantService(c).GetTeacher(teacherID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, teacher)
+}
+
+// UpdateTeacher handles PUT /api/teachers/:teacher_id
+func (h *Handler) UpdateTeacher(c *gin.Context) {
+	teacherID, ok := parseIDParam(c, "teacher_id")
+	if !ok {
+		return
+	}
+
+	var req TeacherInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	teacher, err := h.tenantService(c).UpdateTeacher(teacherID, req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, teacher)
+}
+
+// ArchiveTeacher handles DELETE /api/teachers/:teacher_id
+func (h *Handler) ArchiveTeacher(c *gin.Context) {
+	teacherID, ok := parseIDParam(c, "teacher_id")
+	if !ok {
+		return
+	}
+
+	if err := h.tenantService(c).ArchiveTeacher(teacherID); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTeacherClassrooms handles GET /api/teachers/:teacher_id/classrooms
+func (h *Handler) GetTeacherClassrooms(c *gin.Context) {
+	teacherID, ok := parseIDParam(c, "teacher_id")
+	if !ok {
+		return
+	}
+
+	classrooms, err := h.tenantService(c).ListTeacherClassrooms(teacherID)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"teacher_id": teacherID, "classrooms": classrooms})
+}
+
+// BulkUpsert handles POST /api/roster/bulk
+func (h *Handler) BulkUpsert(c *gin.Context) {
+	var req repository.RosterBatch
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.tenantService(c).BulkUpsert(req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	entry := auth.AuditEntry(c, "roster.bulk_upsert")
+	entry.Details = models.JSONMap{"result": result}
+	h.audit.Record(entry)
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) enrollmentHistory(c *gin.Context, filter repository.EnrollmentFilter) {
+	pagination := parsePaginationParams(c)
+
+	data, err := h.tenantService(c).GetEnrollmentHistory(filter, pagination)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, data)
+}
+
+// parseIDParam reads a UUID path parameter and writes a 400 when it is malformed
+func parseIDParam(c *gin.Context, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(name))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + name + " format"})
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// respondError maps service errors to HTTP status codes
+func respondError(c *gin.Context, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
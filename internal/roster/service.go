@@ -0,0 +1,446 @@
+package roster
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a school, classroom, student, teacher or enrollment does not exist
+var ErrNotFound = errors.New("resource not found")
+
+type Service struct {
+	ClassroomRepo repository.ClassroomRepository
+	TeacherRepo   repository.TeacherRepository
+}
+
+func NewService(classroomRepo repository.ClassroomRepository, teacherRepo repository.TeacherRepository) *Service {
+	return &Service{ClassroomRepo: classroomRepo, TeacherRepo: teacherRepo}
+}
+
+// ForTenant returns a copy of the service that manages the tenant's roster
+func (s *Service) ForTenant(tenantID uuid.UUID) *Service {
+	return &Service{
+		ClassroomRepo: s.ClassroomRepo.ForTenant(tenantID),
+		TeacherRepo:   s.TeacherRepo.ForTenant(tenantID),
+	}
+}
+
+// SchoolInput carries editable school fields
+type SchoolInput struct {
+	SchoolID *uuid.UUID `json:"school_id"`
+	Name     string     `json:"name" binding:"required"`
+}
+
+// ClassroomInput carries editable classroom fields
+type ClassroomInput struct {
+	ClassroomID *uuid.UUID `json:"classroom_id"`
+	Name        string     `json:"name" binding:"required"`
+	SchoolID    *uuid.UUID `json:"school_id"`
+}
+
+// StudentInput carries editable student fields
+type StudentInput struct {
+	StudentID *uuid.UUID `json:"student_id"`
+	Name      *string    `json:"name"`
+}
+
+// EnrollmentInput enrolls a student, optionally backdated
+type EnrollmentInput struct {
+	StudentID   uuid.UUID  `json:"student_id" binding:"required"`
+	EffectiveAt *time.Time `json:"effective_at"`
+}
+
+// TeacherInput carries editable teacher fields
+type TeacherInput struct {
+	TeacherID *uuid.UUID `json:"teacher_id"`
+	Name      *string    `json:"name" binding:"required"`
+	Email     *string    `json:"email"`
+}
+
+// TeacherAssignmentInput assigns a teacher to a classroom; Role defaults to primary
+type TeacherAssignmentInput struct {
+	TeacherID uuid.UUID `json:"teacher_id" binding:"required"`
+	Role      string    `json:"role"`
+}
+
+// ValidationError marks input rejected by roster rules
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+func (s *Service) CreateSchool(input SchoolInput) (*models.School, error) {
+	school := &models.School{
+		SchoolID: uuid.New(),
+		Name:     input.Name,
+	}
+	if input.SchoolID != nil {
+		school.SchoolID = *input.SchoolID
+	}
+	if err := s.ClassroomRepo.CreateSchool(school); err != nil {
+		return nil, err
+	}
+	return s.GetSchool(school.SchoolID)
+}
+
+func (s *Service) GetSchool(schoolID uuid.UUID) (*models.School, error) {
+	school, err := s.ClassroomRepo.GetSchoolByID(schoolID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return school, nil
+}
+
+func (s *Service) ListSchools(pagination repository.PaginationParams) (*repository.PaginatedResponse[models.School], error) {
+	return s.ClassroomRepo.ListSchools(pagination)
+}
+
+func (s *Service) UpdateSchool(schoolID uuid.UUID, input SchoolInput) (*models.School, error) {
+	school, err := s.GetSchool(schoolID)
+	if err != nil {
+		return nil, err
+	}
+	school.Name = input.Name
+	if err := s.ClassroomRepo.UpdateSchool(school); err != nil {
+		return nil, err
+	}
+	return s.GetSchool(schoolID)
+}
+
+func (s *Service) CreateClassroom(input ClassroomInput) (*models.Classroom, error) {
+	if err := s.checkSchool(input.SchoolID); err != nil {
+		return nil, err
+	}
+	classroom := &models.Classroom{
+		ClassroomID: uuid.New(),
+		Name:        input.Name,
+		SchoolID:    input.SchoolID,
+	}
+	if input.ClassroomID != nil {
+		classroom.ClassroomID = *input.ClassroomID
+	}
+	if err := s.ClassroomRepo.CreateClassroom(classroom); err != nil {
+		return nil, err
+	}
+	return s.GetClassroom(classroom.ClassroomID)
+}
+
+func (s *Service) GetClassroom(classroomID uuid.UUID) (*models.Classroom, error) {
+	classroom, err := s.ClassroomRepo.GetClassroomByID(classroomID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return classroom, nil
+}
+
+func (s *Service) ListClassrooms(includeArchived bool, pagination repository.PaginationParams) (*repository.PaginatedResponse[models.Classroom], error) {
+	return s.ClassroomRepo.ListClassrooms(includeArchived, pagination)
+}
+
+func (s *Service) UpdateClassroom(classroomID uuid.UUID, input ClassroomInput) (*models.Classroom, error) {
+	classroom, err := s.GetClassroom(classroomID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSchool(input.SchoolID); err != nil {
+		return nil, err
+	}
+	classroom.Name = input.Name
+	classroom.SchoolID = input.SchoolID
+	if err := s.ClassroomRepo.UpdateClassroom(classroom); err != nil {
+		return nil, err
+	}
+	return s.GetClassroom(classroomID)
+}
+
+// ArchiveClassroom hides the classroom from listings; its roster and history are kept
+func (s *Service) ArchiveClassroom(classroomID uuid.UUID) error {
+	return notFound(s.ClassroomRepo.ArchiveClassroom(classroomID))
+}
+
+func (s *Service) CreateStudent(input StudentInput) (*models.Student, error) {
+	student := &models.Student{
+		StudentID: uuid.New(),
+		Name:      input.Name,
+	}
+	if input.StudentID != nil {
+		student.StudentID = *input.StudentID
+	}
+	if err := s.ClassroomRepo.CreateStudent(student); err != nil {
+		return nil, err
+	}
+	return s.GetStudent(student.StudentID)
+}
+
+func (s *Service) GetStudent(studentID uuid.UUID) (*models.Student, error) {
+	student, err := s.ClassroomRepo.GetStudentByID(studentID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return student, nil
+}
+
+func (s *Service) ListStudents(pagination repository.PaginationParams) (*repository.PaginatedResponse[models.Student], error) {
+	return s.ClassroomRepo.ListStudents(pagination)
+}
+
+func (s *Service) UpdateStudent(studentID uuid.UUID, input StudentInput) (*models.Student, error) {
+	student, err := s.GetStudent(studentID)
+	if err != nil {
+		return nil, err
+	}
+	student.Name = input.Name
+	if err := s.ClassroomRepo.UpdateStudent(student); err != nil {
+		return nil, err
+	}
+	return s.GetStudent(studentID)
+}
+
+// ListClassroomStudents returns the current roster
+func (s *Service) ListClassroomStudents(classroomID uuid.UUID, pagination repository.PaginationParams) (*repository.PaginatedResponse[models.Student], error) {
+	if _, err := s.GetClassroom(classroomID); err != nil {
+		return nil, err
+	}
+	return s.ClassroomRepo.GetClassroomStudentsPaginated(classroomID, pagination)
+}
+
+// EnrollStudent adds the student to the classroom from EffectiveAt (default now).
+// Enrolling a student who is already enrolled is a no-op.
+func (s *Service) EnrollStudent(classroomID uuid.UUID, input EnrollmentInput) error {
+	if _, err := s.GetClassroom(classroomID); err != nil {
+		return err
+	}
+	if _, err := s.GetStudent(input.StudentID); err != nil {
+		return err
+	}
+	return enrollmentError(s.ClassroomRepo.EnrollStudent(classroomID, input.StudentID, effectiveTime(input.EffectiveAt)))
+}
+
+// UnenrollStudent ends the student's current enrollment at effectiveAt (default now)
+func (s *Service) UnenrollStudent(classroomID, studentID uuid.UUID, effectiveAt *time.Time) error {
+	err := s.ClassroomRepo.UnenrollStudent(classroomID, studentID, effectiveTime(effectiveAt))
+	return enrollmentError(notFound(err))
+}
+
+func (s *Service) GetEnrollmentHistory(filter repository.EnrollmentFilter, pagination repository.PaginationParams) (*repository.PaginatedResponse[models.ClassroomEnrollment], error) {
+	if filter.ClassroomID != nil {
+		if _, err := s.GetClassroom(*filter.ClassroomID); err != nil {
+			return nil, err
+		}
+	}
+	if filter.StudentID != nil {
+		if _, err := s.GetStudent(*filter.StudentID); err != nil {
+			return nil, err
+		}
+	}
+	return s.ClassroomRepo.GetEnrollmentHistory(filter, pagination)
+}
+
+// BulkUpsert applies a roster batch atomically. Every row is validated first so a
+// bad row rejects the whole batch with a report of all problems.
+func (s *Service) BulkUpsert(batch repository.RosterBatch) (*repository.RosterResult, error) {
+	var problems []string
+	schools := make(map[uuid.UUID]bool, len(batch.Schools))
+	classrooms := make(map[uuid.UUID]bool, len(batch.Classrooms))
+	students := make(map[uuid.UUID]bool, len(batch.Students))
+
+	for i, school := range batch.Schools {
+		if school.SchoolID == uuid.Nil {
+			problems = append(problems, fmt.Sprintf("schools[%d]: school_id is required", i))
+		}
+		if strings.TrimSpace(school.Name) == "" {
+			problems = append(problems, fmt.Sprintf("schools[%d]: name is required", i))
+		}
+		schools[school.SchoolID] = true
+	}
+	for i, classroom := range batch.Classrooms {
+		if classroom.ClassroomID == uuid.Nil {
+			problems = append(problems, fmt.Sprintf("classrooms[%d]: classroom_id is required", i))
+		}
+		if strings.TrimSpace(classroom.Name) == "" {
+			problems = append(problems, fmt.Sprintf("classrooms[%d]: name is required", i))
+		}
+		if classroom.SchoolID != nil && !schools[*classroom.SchoolID] {
+			if _, err := s.ClassroomRepo.GetSchoolByID(*classroom.SchoolID); err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, err
+				}
+				problems = append(problems, fmt.Sprintf("classrooms[%d]: unknown school %s", i, *classroom.SchoolID))
+			}
+			schools[*classroom.SchoolID] = true
+		}
+		classrooms[classroom.ClassroomID] = true
+	}
+	for i, student := range batch.Students {
+		if student.StudentID == uuid.Nil {
+			problems = append(problems, fmt.Sprintf("students[%d]: student_id is required", i))
+		}
+		students[student.StudentID] = true
+	}
+
+	now := time.Now()
+	for i := range batch.Enrollments {
+		enrollment := &batch.Enrollments[i]
+		if enrollment.EffectiveAt.IsZero() {
+			enrollment.EffectiveAt = now
+		}
+		if !classrooms[enrollment.ClassroomID] {
+			if _, err := s.ClassroomRepo.GetClassroomByID(enrollment.ClassroomID); err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, err
+				}
+				problems = append(problems, fmt.Sprintf("enrollments[%d]: unknown classroom %s", i, enrollment.ClassroomID))
+			}
+			classrooms[enrollment.ClassroomID] = true
+		}
+		if !students[enrollment.StudentID] {
+			if _, err := s.ClassroomRepo.GetStudentByID(enrollment.StudentID); err != nil {
+				if !errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil, err
+				}
+				problems = append(problems, fmt.Sprintf("enrollments[%d]: unknown student %s", i, enrollment.StudentID))
+			}
+			students[enrollment.StudentID] = true
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, &ValidationError{Reason: strings.Join(problems, "; ")}
+	}
+
+	result, err := s.ClassroomRepo.ApplyRoster(batch)
+	if err != nil {
+		return nil, enrollmentError(err)
+	}
+	return result, nil
+}
+
+func (s *Service) CreateTeacher(input TeacherInput) (*models.Teacher, error) {
+	teacher := &models.Teacher{
+		TeacherID: uuid.New(),
+		Name:      input.Name,
+		Email:     input.Email,
+	}
+	if input.TeacherID != nil {
+		teacher.TeacherID = *input.TeacherID
+	}
+	if err := s.TeacherRepo.CreateTeacher(teacher); err != nil {
+		return nil, err
+	}
+	return s.GetTeacher(teacher.TeacherID)
+}
+
+func (s *Service) GetTeacher(teacherID uuid.UUID) (*models.Teacher, error) {
+	teacher, err := s.TeacherRepo.GetTeacherByID(teacherID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return teacher, nil
+}
+
+func (s *Service) ListTeachers(includeArchived bool, pagination repository.PaginationParams) (*repository.PaginatedResponse[models.Teacher], error) {
+	return s.TeacherRepo.ListTeachers(includeArchived, pagination)
+}
+
+// UpdateTeacher replaces name and email; naming a teacher auto-created from events reconciles it
+func (s *Service) UpdateTeacher(teacherID uuid.UUID, input TeacherInput) (*models.Teacher, error) {
+	teacher, err := s.GetTeacher(teacherID)
+	if err != nil {
+		return nil, err
+	}
+	teacher.Name = input.Name
+	teacher.Email = input.Email
+	if err := s.TeacherRepo.UpdateTeacher(teacher); err != nil {
+		return nil, err
+	}
+	return s.GetTeacher(teacherID)
+}
+
+// ArchiveTeacher hides the teacher from listings; sessions stay attributed to them
+func (s *Service) ArchiveTeacher(teacherID uuid.UUID) error {
+	return notFound(s.TeacherRepo.ArchiveTeacher(teacherID))
+}
+
+func (s *Service) ListTeacherClassrooms(teacherID uuid.UUID) ([]repository.TeacherAssignment, error) {
+	if _, err := s.GetTeacher(teacherID); err != nil {
+		return nil, err
+	}
+	return s.TeacherRepo.GetTeacherClassrooms(teacherID)
+}
+
+func (s *Service) ListClassroomTeachers(classroomID uuid.UUID) ([]repository.TeacherAssignment, error) {
+	if _, err := s.GetClassroom(classroomID); err != nil {
+		return nil, err
+	}
+	return s.TeacherRepo.GetClassroomTeachers(classroomID)
+}
+
+// AssignTeacher adds the teacher to the classroom. Assigning an already assigned teacher changes their role.
+func (s *Service) AssignTeacher(classroomID uuid.UUID, input TeacherAssignmentInput) error {
+	role := input.Role
+	switch role {
+	case "":
+		role = models.TeacherRolePrimary
+	case models.TeacherRolePrimary, models.TeacherRoleCoTeacher:
+	default:
+		return &ValidationError{Reason: fmt.Sprintf("role must be %s or %s", models.TeacherRolePrimary, models.TeacherRoleCoTeacher)}
+	}
+	if _, err := s.GetClassroom(classroomID); err != nil {
+		return err
+	}
+	if _, err := s.GetTeacher(input.TeacherID); err != nil {
+		return err
+	}
+	return s.TeacherRepo.AssignTeacher(classroomID, input.TeacherID, role)
+}
+
+func (s *Service) UnassignTeacher(classroomID, teacherID uuid.UUID) error {
+	return notFound(s.TeacherRepo.UnassignTeacher(classroomID, teacherID))
+}
+
+// checkSchool rejects a classroom's reference to a school that does not exist
+func (s *Service) checkSchool(schoolID *uuid.UUID) error {
+	if schoolID == nil {
+		return nil
+	}
+	if _, err := s.GetSchool(*schoolID); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return &ValidationError{Reason: fmt.Sprintf("unknown school %s", *schoolID)}
+		}
+		return err
+	}
+	return nil
+}
+
+func effectiveTime(t *time.Time) time.Time {
+	if t == nil {
+		return time.Now()
+	}
+	return *t
+}
+
+// enrollmentError turns interval conflicts into validation errors
+func enrollmentError(err error) error {
+	if errors.Is(err, repository.ErrInvalidEnrollmentInterval) {
+		return &ValidationError{Reason: err.Error()}
+	}
+	return err
+}
+
+// notFound translates missing-record errors into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -1,92 +1,277 @@
 package server
 
 import (
-	"log"
-	"os"
-	"strings"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"gorm.io/gorm"
 
+	"github.com/rohanreddymelachervu/ingestor/internal/audit"
 	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/catalog"
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
 	"github.com/rohanreddymelachervu/ingestor/internal/events"
+	"github.com/rohanreddymelachervu/ingestor/internal/health"
 	"github.com/rohanreddymelachervu/ingestor/internal/kafka"
+	"github.com/rohanreddymelachervu/ingestor/internal/logging"
+	"github.com/rohanreddymelachervu/ingestor/internal/metrics"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/oneroster"
+	"github.com/rohanreddymelachervu/ingestor/internal/ratelimit"
 	"github.com/rohanreddymelachervu/ingestor/internal/reports"
 	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+	"github.com/rohanreddymelachervu/ingestor/internal/roster"
+	"github.com/rohanreddymelachervu/ingestor/internal/tenant"
 )
 
-// RegisterRoutes sets up all endpoints with proper clean architecture
-func RegisterRoutes(r *gin.Engine, jwtSecret string, db *gorm.DB) {
+// RegisterRoutes sets up all endpoints with proper clean architecture. It returns a
+// function that releases what the handlers hold, such as the Kafka producer, once the
+// server no longer serves requests.
+func RegisterRoutes(r *gin.Engine, cfg *config.Config, db *gorm.DB) (closeHandlers func()) {
 	// Initialize repositories
 	eventRepo := repository.NewEventRepository(db)
 	quizRepo := repository.NewQuizRepository(db)
 	sessionRepo := repository.NewSessionRepository(db)
 	classroomRepo := repository.NewClassroomRepository(db)
+	teacherRepo := repository.NewTeacherRepository(db)
+	provisioningRepo := repository.NewProvisioningRepository(db)
+	tenantRepo := repository.NewTenantRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
 
-	// Initialize services
-	eventsService := events.NewService(eventRepo, quizRepo, sessionRepo, classroomRepo)
-	reportsService := reports.NewService(eventRepo, classroomRepo)
-	authService := auth.NewService(db, jwtSecret)
-
-	// Initialize events handler (with or without Kafka)
-	var eventsHandler *events.Handler
-
-	// Check if Kafka mode is enabled
-	useKafka := os.Getenv("KAFKA_ENABLED") == "true"
+	// Initialize services; they are scoped to the caller's tenant per request
+	eventsService := events.NewService(eventRepo, quizRepo, sessionRepo, classroomRepo, teacherRepo, provisioningRepo, tenantRepo, cfg.Provisioning)
+	reportsService := reports.NewService(eventRepo, classroomRepo, sessionRepo, teacherRepo, provisioningRepo)
+	catalogService := catalog.NewService(quizRepo)
+	rosterService := roster.NewService(classroomRepo, teacherRepo)
+	importService := oneroster.NewService(classroomRepo)
+	tenantService := tenant.NewService(tenantRepo)
+	auditService := audit.NewService(auditRepo)
+	keys, err := auth.NewKeySet(cfg.Auth.JWTSecret, cfg.Auth.JWTKeysDir, cfg.Auth.JWTSigningKID)
+	if err != nil {
+		logging.Fatal("failed to load JWT signing keys", err)
+	}
+	var notifier auth.Notifier
+	if cfg.Auth.NotifierFile != "" {
+		notifier = auth.NewFileNotifier(cfg.Auth.NotifierFile)
+	} else {
+		slog.Warn("password reset is disabled, set NOTIFIER_FILE to deliver reset tokens")
+	}
+	authService := auth.NewService(db, keys, cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL,
+		cfg.Auth.Passwords, cfg.Auth.Lockout, notifier)
+	tokens := auth.NewVerifier(keys, externalProvider(cfg.Auth.OIDC, tenantRepo))
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore())
 
-	if useKafka {
-		log.Println("🚀 Kafka mode enabled - events will be published to Kafka")
+	// Request spans, logs and metrics cover every route registered below; probes are not traced
+	r.Use(otelgin.Middleware("ingestor", otelgin.WithFilter(func(req *http.Request) bool {
+		return !probePaths[req.URL.Path]
+	})))
+	r.Use(logging.Middleware(probePaths))
+	r.Use(metrics.Middleware())
+	if sqlDB, err := db.DB(); err == nil {
+		metrics.RegisterDBStats(sqlDB)
+	}
 
-		// Get Kafka configuration
-		kafkaBrokers := getKafkaBrokers()
-		topicName := getKafkaTopic()
+	// Dependencies checked by /readyz and /status
+	monitor := health.NewMonitor(2 * time.Second)
+	monitor.Add("postgres", health.Postgres(db))
+	monitor.Add("migrations", health.Migrations(db, models.SchemaVersion))
 
-		log.Printf("Kafka brokers: %v", kafkaBrokers)
-		log.Printf("Kafka topic: %s", topicName)
+	// Initialize events handler (with or without Kafka)
+	var eventsHandler *events.Handler
+	closeHandlers = func() {}
 
+	if cfg.Kafka.Enabled {
 		// Initialize Kafka producer
-		producer, err := kafka.NewProducer(kafkaBrokers, topicName)
+		producer, err := kafka.NewProducer(cfg.Kafka)
 		if err != nil {
-			log.Printf("⚠️  Failed to initialize Kafka producer: %v", err)
-			log.Println("🔄 Falling back to direct database mode")
-			eventsHandler = events.NewHandler(eventsService)
+			slog.Error("failed to initialize Kafka producer, processing events directly", "brokers", cfg.Kafka.Brokers, "error", err)
+			eventsHandler = events.NewHandler(eventsService, auditService)
 		} else {
-			log.Println("✅ Kafka producer initialized successfully")
-			eventsHandler = events.NewHandlerWithKafka(eventsService, producer)
+			slog.Info("Kafka mode enabled, events are published to Kafka", "brokers", cfg.Kafka.Brokers, "topic", cfg.Kafka.Topic)
+			eventsHandler = events.NewHandlerWithKafka(eventsService, producer, auditService)
+			monitor.Add("kafka_producer", health.Pinger(producer.Ping))
+			closeHandlers = func() {
+				if err := producer.Close(); err != nil {
+					slog.Error("failed to close Kafka producer", "error", err)
+				}
+			}
 		}
 	} else {
-		log.Println("📊 Direct database mode - events will be processed immediately")
-		eventsHandler = events.NewHandler(eventsService)
+		slog.Info("direct database mode, events are processed immediately")
+		eventsHandler = events.NewHandler(eventsService, auditService)
 	}
 
 	// Initialize other handlers
-	reportsHandler := reports.NewHandler(reportsService)
-	authHandler := auth.NewHandler(authService)
+	reportsHandler := reports.NewHandler(reportsService, cfg.Reports)
+	catalogHandler := catalog.NewHandler(catalogService)
+	rosterHandler := roster.NewHandler(rosterService, auditService)
+	importHandler := oneroster.NewHandler(importService, auditService)
+	tenantHandler := tenant.NewHandler(tenantService, auditService)
+	authHandler := auth.NewHandler(authService, cfg.Auth.AllowSignup, auditService)
+	auditHandler := audit.NewHandler(auditService)
+	healthHandler := health.NewHandler(monitor)
+
+	// Probes and metrics for the orchestrator and Prometheus; no auth, as they only
+	// expose counts and up or down
+	r.GET("/healthz", healthHandler.Liveness)
+	r.GET("/readyz", healthHandler.Readiness)
+	r.GET("/metrics", metrics.Handler())
+
+	// Dependency details may reveal infrastructure, so they are for admins only
+	r.GET("/status", auth.AuthMiddleware(tokens, authService, nil), auth.RequireScope("ADMIN"), auth.RequireTenantAccess(), healthHandler.Status)
+
+	// Public keys for verifying our tokens (RS256/ES256 only; empty with an HS256 secret)
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	api := r.Group("/api")
 	{
 		// Public auth endpoints (no JWT required)
 		authGroup := api.Group("/auth")
+		authGroup.Use(limiter.Limit("auth", cfg.RateLimits.Auth))
 		{
 			authGroup.POST("/signup", authHandler.SignUp)
 			authGroup.POST("/login", authHandler.Login)
+			authGroup.POST("/refresh", authHandler.Refresh)
+			authGroup.POST("/password/forgot", authHandler.ForgotPassword)
+			authGroup.POST("/password/reset", authHandler.ResetPassword)
+		}
+
+		// Event ingestion: WRITE scope required (for Whiteboard & Notebook apps).
+		// Devices authenticate with an API key; these are the only routes accepting one.
+		eventsGroup := api.Group("")
+		eventsGroup.Use(auth.AuthMiddleware(tokens, authService, authService),
+			limiter.Limit("events", cfg.RateLimits.Events), auth.RequireGrantedAccess(), auth.RequireScope("WRITE"))
+		{
+			eventsGroup.POST("/events", eventsHandler.CreateEvent)
+			eventsGroup.POST("/events/batch", eventsHandler.CreateBatchEvents)
 		}
 
 		// Secured routes require a valid JWT
 		secured := api.Group("")
-		secured.Use(auth.AuthMiddleware(jwtSecret))
+		secured.Use(auth.AuthMiddleware(tokens, authService, nil), limiter.Limit("default", cfg.RateLimits.Default))
 		{
-			// Event ingestion: WRITE scope required (for Whiteboard & Notebook apps)
-			eventsGroup := secured.Group("")
-			eventsGroup.Use(auth.RequireScope("WRITE"))
+			// Any authenticated caller may revoke its own tokens
+			secured.POST("/auth/logout", authHandler.Logout)
+
+			// Everything else needs granted access, which self-signed-up users and provider users
+			// without an access mapping lack
+			secured.Use(auth.RequireGrantedAccess())
+
+			// Content catalog: READ to browse, WRITE and tenant-wide access to author quizzes and questions
+			quizzesGroup := secured.Group("/quizzes")
 			{
-				eventsGroup.POST("/events", eventsHandler.CreateEvent)
-				eventsGroup.POST("/events/batch", eventsHandler.CreateBatchEvents)
+				quizzesGroup.GET("", auth.RequireScope("READ"), catalogHandler.ListQuizzes)
+				quizzesGroup.POST("", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), catalogHandler.CreateQuiz)
+				quizzesGroup.GET("/:quiz_id", auth.RequireScope("READ"), catalogHandler.GetQuiz)
+				quizzesGroup.PUT("/:quiz_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), catalogHandler.UpdateQuiz)
+				quizzesGroup.DELETE("/:quiz_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), catalogHandler.ArchiveQuiz)
+				quizzesGroup.GET("/:quiz_id/questions", auth.RequireScope("READ"), catalogHandler.ListQuizQuestions)
+				quizzesGroup.POST("/:quiz_id/questions", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), catalogHandler.CreateQuestion)
+			}
+
+			questionsGroup := secured.Group("/questions")
+			{
+				questionsGroup.GET("/:question_id", auth.RequireScope("READ"), catalogHandler.GetQuestion)
+				questionsGroup.PUT("/:question_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), catalogHandler.UpdateQuestion)
+				questionsGroup.DELETE("/:question_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), catalogHandler.ArchiveQuestion)
+				questionsGroup.GET("/:question_id/versions", auth.RequireScope("READ"), catalogHandler.GetQuestionVersions)
+			}
+
+			// Roster management: READ to browse, WRITE and tenant-wide access for SIS sync and
+			// enrollment changes
+			schoolsGroup := secured.Group("/schools")
+			{
+				schoolsGroup.GET("", auth.RequireScope("READ"), rosterHandler.ListSchools)
+				schoolsGroup.POST("", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.CreateSchool)
+				schoolsGroup.GET("/:school_id", auth.RequireScope("READ"), rosterHandler.GetSchool)
+				schoolsGroup.PUT("/:school_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.UpdateSchool)
+			}
+
+			classroomsGroup := secured.Group("/classrooms")
+			{
+				classroomsGroup.GET("", auth.RequireScope("READ"), rosterHandler.ListClassrooms)
+				classroomsGroup.POST("", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.CreateClassroom)
+				classroomsGroup.GET("/:classroom_id", auth.RequireScope("READ"), rosterHandler.GetClassroom)
+				classroomsGroup.PUT("/:classroom_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.UpdateClassroom)
+				classroomsGroup.DELETE("/:classroom_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.ArchiveClassroom)
+				classroomsGroup.GET("/:classroom_id/students", auth.RequireScope("READ"), rosterHandler.ListClassroomStudents)
+				classroomsGroup.POST("/:classroom_id/students", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.EnrollStudent)
+				classroomsGroup.DELETE("/:classroom_id/students/:student_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.UnenrollStudent)
+				classroomsGroup.GET("/:classroom_id/enrollments", auth.RequireScope("READ"), rosterHandler.GetClassroomEnrollments)
+				classroomsGroup.GET("/:classroom_id/teachers", auth.RequireScope("READ"), rosterHandler.ListClassroomTeachers)
+				classroomsGroup.POST("/:classroom_id/teachers", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.AssignTeacher)
+				classroomsGroup.DELETE("/:classroom_id/teachers/:teacher_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.UnassignTeacher)
+			}
+
+			studentsGroup := secured.Group("/students")
+			{
+				studentsGroup.GET("", auth.RequireScope("READ"), rosterHandler.ListStudents)
+				studentsGroup.POST("", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.CreateStudent)
+				studentsGroup.GET("/:student_id", auth.RequireScope("READ"), rosterHandler.GetStudent)
+				studentsGroup.PUT("/:student_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.UpdateStudent)
+				studentsGroup.GET("/:student_id/enrollments", auth.RequireScope("READ"), rosterHandler.GetStudentEnrollments)
+			}
+
+			teachersGroup := secured.Group("/teachers")
+			{
+				teachersGroup.GET("", auth.RequireScope("READ"), rosterHandler.ListTeachers)
+				teachersGroup.POST("", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.CreateTeacher)
+				teachersGroup.GET("/:teacher_id", auth.RequireScope("READ"), rosterHandler.GetTeacher)
+				teachersGroup.PUT("/:teacher_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.UpdateTeacher)
+				teachersGroup.DELETE("/:teacher_id", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.ArchiveTeacher)
+				teachersGroup.GET("/:teacher_id/classrooms", auth.RequireScope("READ"), rosterHandler.GetTeacherClassrooms)
+			}
+
+			secured.POST("/roster/bulk", auth.RequireScope("WRITE"), auth.RequireTenantAccess(), rosterHandler.BulkUpsert)
+
+			// Tenant settings: late-answer policy and data retention of the caller's tenant
+			secured.GET("/tenant", auth.RequireScope("READ"), tenantHandler.GetTenant)
+			secured.PUT("/tenant", auth.RequireScope("ADMIN"), auth.RequireTenantAccess(), tenantHandler.UpdateTenant)
+
+			// Administrative roster imports (OneRoster CSV): ADMIN scope and tenant-wide access required
+			adminGroup := secured.Group("/admin")
+			{
+				adminGroup.POST("/rosters/import", auth.RequireScope("ADMIN"), auth.RequireTenantAccess(), importHandler.ImportRoster)
+			}
+
+			// User management: ADMIN scope and tenant-wide access required
+			usersGroup := adminGroup.Group("/users")
+			usersGroup.Use(auth.RequireScope("ADMIN"), auth.RequireTenantAccess())
+			{
+				usersGroup.GET("", authHandler.ListUsers)
+				usersGroup.POST("", authHandler.CreateUser)
+				usersGroup.GET("/:user_id", authHandler.GetUser)
+				usersGroup.PUT("/:user_id/role", authHandler.SetRole)
+				usersGroup.PUT("/:user_id/access", authHandler.SetAccess)
+				usersGroup.POST("/:user_id/disable", authHandler.DisableUser)
+				usersGroup.POST("/:user_id/enable", authHandler.EnableUser)
+			}
+
+			// Device API keys: ADMIN scope and tenant-wide access required
+			apiKeysGroup := adminGroup.Group("/api-keys")
+			apiKeysGroup.Use(auth.RequireScope("ADMIN"), auth.RequireTenantAccess())
+			{
+				apiKeysGroup.GET("", authHandler.ListAPIKeys)
+				apiKeysGroup.POST("", authHandler.IssueAPIKey)
+				apiKeysGroup.GET("/:key_id", authHandler.GetAPIKey)
+				apiKeysGroup.POST("/:key_id/rotate", authHandler.RotateAPIKey)
+				apiKeysGroup.POST("/:key_id/revoke", authHandler.RevokeAPIKey)
+			}
+
+			// Audit log: ADMIN scope and tenant-wide access required
+			auditGroup := adminGroup.Group("/audit")
+			auditGroup.Use(auth.RequireScope("ADMIN"), auth.RequireTenantAccess())
+			{
+				auditGroup.GET("", auditHandler.ListEntries)
+				auditGroup.GET("/export", auditHandler.ExportEntries)
 			}
 
 			// Reporting: READ scope required (for Analytics Dashboard)
 			reportsGroup := secured.Group("/reports")
-			reportsGroup.Use(auth.RequireScope("READ"))
+			reportsGroup.Use(limiter.Limit("reports", cfg.RateLimits.Reports), auth.RequireScope("READ"))
 			{
 				reportsGroup.GET("/active-participants", reportsHandler.GetActiveParticipants)
 				reportsGroup.GET("/questions-per-minute", reportsHandler.GetQuestionsPerMinute)
@@ -96,6 +281,7 @@ func RegisterRoutes(r *gin.Engine, jwtSecret string, db *gorm.DB) {
 				reportsGroup.GET("/response-rate", reportsHandler.GetResponseRate)
 				reportsGroup.GET("/latency-analysis", reportsHandler.GetLatencyAnalysis)
 				reportsGroup.GET("/timeout-analysis", reportsHandler.GetTimeoutAnalysis)
+				reportsGroup.GET("/session-attendance", reportsHandler.GetSessionAttendance)
 				reportsGroup.GET("/completion-rate", reportsHandler.GetCompletionRate)
 				reportsGroup.GET("/dropoff-analysis", reportsHandler.GetDropoffAnalysis)
 				reportsGroup.GET("/student-performance-list", reportsHandler.GetStudentPerformanceList)
@@ -110,26 +296,35 @@ func RegisterRoutes(r *gin.Engine, jwtSecret string, db *gorm.DB) {
 				reportsGroup.GET("/classroom-overview", reportsHandler.GetClassroomOverview)
 				reportsGroup.GET("/class-performance-summary", reportsHandler.GetClassPerformanceSummary)
 				reportsGroup.GET("/student-activity-summary", reportsHandler.GetStudentActivitySummary)
+				reportsGroup.GET("/teacher-summary", reportsHandler.GetTeacherSummary)
+				reportsGroup.GET("/teacher-sessions", reportsHandler.GetTeacherSessions)
+				reportsGroup.GET("/provisioning-reconciliation", reportsHandler.GetProvisioningReconciliation)
 
 				// Generic Query: cube.dev-style analytics with measures and dimensions
-				reportsGroup.POST("/query", reportsHandler.GenericQuery)
+				reportsGroup.POST("/query", limiter.Limit("query", cfg.RateLimits.Query), reportsHandler.GenericQuery)
 			}
 		}
 	}
-}
 
-func getKafkaBrokers() []string {
-	brokers := os.Getenv("KAFKA_BROKERS")
-	if brokers == "" {
-		brokers = "localhost:9092" // Default for local development
-	}
-	return strings.Split(brokers, ",")
+	return closeHandlers
 }
 
-func getKafkaTopic() string {
-	topic := os.Getenv("KAFKA_TOPIC")
-	if topic == "" {
-		topic = "quiz-events" // Default topic name
+// probePaths are polled by the orchestrator and Prometheus
+var probePaths = map[string]bool{"/healthz": true, "/readyz": true, "/metrics": true}
+
+// externalProvider sets up the OpenID Connect provider whose tokens are trusted, if any
+func externalProvider(cfg config.OIDCConfig, tenantRepo repository.TenantRepository) *auth.OIDCProvider {
+	if !cfg.Enabled() {
+		return nil
+	}
+	t, err := tenantRepo.GetTenantBySlug(cfg.Tenant)
+	if err != nil {
+		logging.Fatal("failed to load OIDC_TENANT "+cfg.Tenant, err)
+	}
+	provider, err := auth.NewOIDCProvider(cfg, t.TenantID)
+	if err != nil {
+		logging.Fatal("failed to set up OIDC provider", err)
 	}
-	return topic
+	slog.Info("trusting tokens of an external provider", "issuer", cfg.Issuer, "tenant", cfg.Tenant)
+	return provider
 }
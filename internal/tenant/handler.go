@@ -0,0 +1,71 @@
+package tenant
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rohanreddymelachervu/ingestor/internal/auth"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+)
+
+type Handler struct {
+	service *Service
+	audit   auth.Auditor
+}
+
+func NewHandler(s *Service, auditor auth.Auditor) *Handler {
+	return &Handler{service: s, audit: auditor}
+}
+
+// GetTenant handles GET /api/tenant and returns the caller's tenant and its settings
+func (h *Handler) GetTenant(c *gin.Context) {
+	tenant, err := h.service.GetTenant(auth.TenantID(c))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// UpdateTenant handles PUT /api/tenant
+func (h *Handler) UpdateTenant(c *gin.Context) {
+	var req SettingsInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenant, err := h.service.UpdateSettings(auth.TenantID(c), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	entry := auth.AuditEntry(c, "tenant.update")
+	entry.TargetType = "tenant"
+	entry.TargetID = tenant.TenantID.String()
+	entry.Details = models.JSONMap{
+		"name":                  req.Name,
+		"late_answer_policy":    req.LateAnswerPolicy,
+		"late_answer_grace_sec": req.LateAnswerGraceSec,
+		"retention_days":        req.RetentionDays,
+	}
+	h.audit.Record(entry)
+
+	c.JSON(http.StatusOK, tenant)
+}
+
+// respondError maps service errors to HTTP status codes
+func respondError(c *gin.Context, err error) {
+	var validationErr *ValidationError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.As(err, &validationErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,126 @@
+package tenant
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rohanreddymelachervu/ingestor/internal/models"
+	"github.com/rohanreddymelachervu/ingestor/internal/repository"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when a tenant does not exist
+var ErrNotFound = errors.New("resource not found")
+
+// slugPattern keeps slugs usable in URLs and on the command line
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+type Service struct {
+	TenantRepo repository.TenantRepository
+}
+
+func NewService(tenantRepo repository.TenantRepository) *Service {
+	return &Service{TenantRepo: tenantRepo}
+}
+
+// ValidationError marks a request rejected because its input is invalid
+type ValidationError struct {
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Reason
+}
+
+// SettingsInput replaces a tenant's name and configuration
+type SettingsInput struct {
+	Name             string `json:"name" binding:"required"`
+	LateAnswerPolicy string `json:"late_answer_policy" binding:"required"`
+	// LateAnswerGraceSec only applies to the grace policy
+	LateAnswerGraceSec int `json:"late_answer_grace_sec"`
+	// RetentionDays is how long session data is kept; null keeps it forever
+	RetentionDays *int `json:"retention_days"`
+}
+
+// CreateTenant registers a tenant with the default settings (reject late answers, keep data forever)
+func (s *Service) CreateTenant(slug, name string) (*models.Tenant, error) {
+	if !slugPattern.MatchString(slug) {
+		return nil, &ValidationError{Reason: "slug must be lowercase letters, digits and dashes"}
+	}
+	if name == "" {
+		return nil, &ValidationError{Reason: "name is required"}
+	}
+	tenant := &models.Tenant{
+		TenantID:         uuid.New(),
+		Name:             name,
+		Slug:             slug,
+		LateAnswerPolicy: models.LateAnswerReject,
+	}
+	if err := s.TenantRepo.CreateTenant(tenant); err != nil {
+		return nil, err
+	}
+	return s.GetTenant(tenant.TenantID)
+}
+
+func (s *Service) GetTenant(tenantID uuid.UUID) (*models.Tenant, error) {
+	tenant, err := s.TenantRepo.GetTenantByID(tenantID)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return tenant, nil
+}
+
+func (s *Service) GetTenantBySlug(slug string) (*models.Tenant, error) {
+	tenant, err := s.TenantRepo.GetTenantBySlug(slug)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return tenant, nil
+}
+
+func (s *Service) ListTenants() ([]models.Tenant, error) {
+	return s.TenantRepo.ListTenants()
+}
+
+func (s *Service) UpdateSettings(tenantID uuid.UUID, input SettingsInput) (*models.Tenant, error) {
+	tenant, err := s.GetTenant(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	switch input.LateAnswerPolicy {
+	case models.LateAnswerReject, models.LateAnswerGrace, models.LateAnswerAccept:
+	default:
+		return nil, &ValidationError{Reason: fmt.Sprintf("late_answer_policy must be %s, %s or %s",
+			models.LateAnswerReject, models.LateAnswerGrace, models.LateAnswerAccept)}
+	}
+	if input.LateAnswerGraceSec < 0 {
+		return nil, &ValidationError{Reason: "late_answer_grace_sec must not be negative"}
+	}
+	if input.RetentionDays != nil && *input.RetentionDays <= 0 {
+		return nil, &ValidationError{Reason: "retention_days must be positive or null"}
+	}
+
+	tenant.Name = input.Name
+	tenant.LateAnswerPolicy = input.LateAnswerPolicy
+	tenant.LateAnswerGraceSec = input.LateAnswerGraceSec
+	tenant.RetentionDays = input.RetentionDays
+	if err := s.TenantRepo.UpdateTenant(tenant); err != nil {
+		return nil, err
+	}
+	return s.GetTenant(tenantID)
+}
+
+// PurgeExpired applies the tenant's retention setting; dryRun only counts what would be deleted
+func (s *Service) PurgeExpired(tenant *models.Tenant, now time.Time, dryRun bool) (int64, error) {
+	return s.TenantRepo.PurgeExpiredSessions(tenant, now, dryRun)
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,47 @@
+// Package testdb gives integration tests a Postgres database migrated to the latest schema.
+// The database is named by TEST_DATABASE_URL; tests asking for one are skipped without it.
+// Tests share the database, so each one creates rows of its own rather than expecting it empty.
+package testdb
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/rohanreddymelachervu/ingestor/internal/config"
+	"github.com/rohanreddymelachervu/ingestor/internal/database"
+	"gorm.io/gorm"
+)
+
+// Open connects to TEST_DATABASE_URL and applies every pending migration, or skips the test
+func Open(t *testing.T) *gorm.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	cfg := config.Defaults().Database
+	cfg.URL = url
+	db, err := database.Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	migrator, err := database.Migrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrating the test database: %v", err)
+	}
+	return db
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed postgres/*.sql
+var files embed.FS
+
+// Postgres holds the numbered up and down files of migrations/postgres, compiled into
+// every binary that runs or checks them
+func Postgres() fs.FS {
+	sub, err := fs.Sub(files, "postgres")
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
@@ -0,0 +1,8 @@
+ALTER TABLE answer_submitted_events DROP CONSTRAINT IF EXISTS chk_ase_score_range;
+ALTER TABLE answer_submitted_events
+  DROP COLUMN IF EXISTS score,
+  DROP COLUMN IF EXISTS answer_payload;
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS chk_questions_question_type;
+ALTER TABLE questions
+  DROP COLUMN IF EXISTS answer_key,
+  DROP COLUMN IF EXISTS question_type;
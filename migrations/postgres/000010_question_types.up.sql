@@ -0,0 +1,18 @@
+ALTER TABLE questions
+  ADD COLUMN question_type VARCHAR NOT NULL DEFAULT 'single_choice',
+  ADD COLUMN answer_key    JSONB;
+
+ALTER TABLE questions
+  ADD CONSTRAINT chk_questions_question_type
+  CHECK (question_type IN ('single_choice', 'multi_select', 'true_false', 'numeric', 'short_text', 'ordering'));
+
+ALTER TABLE answer_submitted_events
+  ADD COLUMN answer_payload JSONB,
+  ADD COLUMN score          DOUBLE PRECISION NOT NULL DEFAULT 0;
+
+/* existing single-choice answers are all-or-nothing */
+UPDATE answer_submitted_events
+  SET score = CASE WHEN is_correct THEN 1 ELSE 0 END;
+
+ALTER TABLE answer_submitted_events
+  ADD CONSTRAINT chk_ase_score_range CHECK (score >= 0 AND score <= 1);
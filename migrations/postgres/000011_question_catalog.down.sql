@@ -0,0 +1,17 @@
+DROP INDEX IF EXISTS idx_questions_quiz_position;
+ALTER TABLE answer_submitted_events DROP COLUMN IF EXISTS question_version;
+DROP TABLE IF EXISTS question_versions;
+ALTER TABLE questions
+  DROP COLUMN IF EXISTS archived_at,
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS created_at,
+  DROP COLUMN IF EXISTS version,
+  DROP COLUMN IF EXISTS points,
+  DROP COLUMN IF EXISTS position,
+  DROP COLUMN IF EXISTS tags,
+  DROP COLUMN IF EXISTS options,
+  DROP COLUMN IF EXISTS text;
+ALTER TABLE quizzes
+  DROP COLUMN IF EXISTS archived_at,
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,41 @@
+ALTER TABLE quizzes
+  ADD COLUMN created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+  ADD COLUMN updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+  ADD COLUMN archived_at TIMESTAMP;
+
+ALTER TABLE questions
+  ADD COLUMN text        TEXT,
+  ADD COLUMN options     JSONB,
+  ADD COLUMN tags        JSONB,
+  ADD COLUMN position    INT,
+  ADD COLUMN points      DOUBLE PRECISION NOT NULL DEFAULT 1,
+  ADD COLUMN version     INT       NOT NULL DEFAULT 1,
+  ADD COLUMN created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+  ADD COLUMN updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+  ADD COLUMN archived_at TIMESTAMP;
+
+/* immutable content snapshots so edits never rewrite graded history */
+CREATE TABLE question_versions (
+  question_id   UUID             NOT NULL,
+  version       INT              NOT NULL,
+  question_type VARCHAR          NOT NULL,
+  answer_key    JSONB,
+  text          TEXT,
+  options       JSONB,
+  tags          JSONB,
+  points        DOUBLE PRECISION NOT NULL,
+  created_at    TIMESTAMP        NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (question_id, version),
+  FOREIGN KEY (question_id)
+    REFERENCES questions(question_id)
+    ON DELETE CASCADE
+);
+
+INSERT INTO question_versions (question_id, version, question_type, answer_key, points)
+  SELECT question_id, 1, question_type, answer_key, points FROM questions;
+
+ALTER TABLE answer_submitted_events
+  ADD COLUMN question_version INT;
+
+CREATE INDEX idx_questions_quiz_position
+  ON questions (quiz_id, position);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_provisioned_entities_pending;
+DROP TABLE IF EXISTS provisioned_entities;
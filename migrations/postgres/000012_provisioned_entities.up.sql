@@ -0,0 +1,13 @@
+/* placeholders auto-created for events that referenced unknown entities */
+CREATE TABLE provisioned_entities (
+  entity_type     VARCHAR   NOT NULL,
+  entity_id       UUID      NOT NULL,
+  source_event_id UUID      NOT NULL,
+  provisioned_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+  reconciled_at   TIMESTAMP,
+  PRIMARY KEY (entity_type, entity_id),
+  CHECK (entity_type IN ('session', 'question', 'student'))
+);
+CREATE INDEX idx_provisioned_entities_pending
+  ON provisioned_entities (entity_type, provisioned_at)
+  WHERE reconciled_at IS NULL;
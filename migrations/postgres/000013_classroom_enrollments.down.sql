@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_classroom_enrollments_interval;
+DROP INDEX IF EXISTS idx_classroom_enrollments_open;
+DROP TABLE IF EXISTS classroom_enrollments;
+ALTER TABLE students
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS created_at;
+ALTER TABLE classrooms
+  DROP COLUMN IF EXISTS archived_at,
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS created_at;
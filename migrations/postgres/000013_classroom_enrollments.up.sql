@@ -0,0 +1,36 @@
+ALTER TABLE classrooms
+  ADD COLUMN created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+  ADD COLUMN updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+  ADD COLUMN archived_at TIMESTAMP;
+
+ALTER TABLE students
+  ADD COLUMN created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+  ADD COLUMN updated_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+/* enrollment intervals; classroom_students keeps only the current roster */
+CREATE TABLE classroom_enrollments (
+  enrollment_id  BIGSERIAL PRIMARY KEY,
+  classroom_id   UUID      NOT NULL,
+  student_id     UUID      NOT NULL,
+  enrolled_at    TIMESTAMP NOT NULL,
+  unenrolled_at  TIMESTAMP,
+  FOREIGN KEY (classroom_id)
+    REFERENCES classrooms(classroom_id)
+    ON DELETE CASCADE,
+  FOREIGN KEY (student_id)
+    REFERENCES students(student_id)
+    ON DELETE CASCADE,
+  CHECK (unenrolled_at IS NULL OR unenrolled_at > enrolled_at)
+);
+
+/* at most one open interval per student and classroom */
+CREATE UNIQUE INDEX idx_classroom_enrollments_open
+  ON classroom_enrollments (classroom_id, student_id)
+  WHERE unenrolled_at IS NULL;
+
+CREATE INDEX idx_classroom_enrollments_interval
+  ON classroom_enrollments (classroom_id, enrolled_at, unenrolled_at);
+
+/* existing memberships have no known start date, so they count as enrolled since the epoch */
+INSERT INTO classroom_enrollments (classroom_id, student_id, enrolled_at)
+  SELECT classroom_id, student_id, TIMESTAMP '1970-01-01' FROM classroom_students;
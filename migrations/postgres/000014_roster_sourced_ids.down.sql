@@ -0,0 +1,2 @@
+ALTER TABLE students DROP COLUMN IF EXISTS sourced_id;
+ALTER TABLE classrooms DROP COLUMN IF EXISTS sourced_id;
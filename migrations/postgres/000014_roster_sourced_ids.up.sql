@@ -0,0 +1,6 @@
+/* external identifiers (OneRoster sourcedId) used to key idempotent roster imports */
+ALTER TABLE classrooms
+  ADD COLUMN sourced_id VARCHAR UNIQUE;
+
+ALTER TABLE students
+  ADD COLUMN sourced_id VARCHAR UNIQUE;
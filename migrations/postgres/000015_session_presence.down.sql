@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_qse_session_question;
+DROP TABLE IF EXISTS question_skipped_events;
+DROP INDEX IF EXISTS idx_spe_session_student;
+DROP TABLE IF EXISTS session_presence_events;
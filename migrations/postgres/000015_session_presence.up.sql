@@ -0,0 +1,37 @@
+/* STUDENT_JOINED / STUDENT_LEFT events; consecutive events per student form presence intervals */
+CREATE TABLE session_presence_events (
+  event_id     UUID      PRIMARY KEY,
+  session_id   UUID      NOT NULL,
+  student_id   UUID      NOT NULL,
+  event_type   VARCHAR   NOT NULL,
+  occurred_at  TIMESTAMP NOT NULL,
+  FOREIGN KEY (session_id)
+    REFERENCES quiz_sessions(session_id)
+    ON DELETE CASCADE,
+  FOREIGN KEY (student_id)
+    REFERENCES students(student_id)
+    ON DELETE CASCADE,
+  CHECK (event_type IN ('STUDENT_JOINED', 'STUDENT_LEFT'))
+);
+CREATE INDEX idx_spe_session_student
+  ON session_presence_events (session_id, student_id, occurred_at);
+
+/* QUESTION_SKIPPED events: the student saw the question and chose not to answer */
+CREATE TABLE question_skipped_events (
+  event_id     UUID      PRIMARY KEY,
+  session_id   UUID      NOT NULL,
+  question_id  UUID      NOT NULL,
+  student_id   UUID      NOT NULL,
+  skipped_at   TIMESTAMP NOT NULL,
+  FOREIGN KEY (session_id)
+    REFERENCES quiz_sessions(session_id)
+    ON DELETE CASCADE,
+  FOREIGN KEY (question_id)
+    REFERENCES questions(question_id)
+    ON DELETE CASCADE,
+  FOREIGN KEY (student_id)
+    REFERENCES students(student_id)
+    ON DELETE CASCADE
+);
+CREATE INDEX idx_qse_session_question
+  ON question_skipped_events (session_id, question_id);
@@ -0,0 +1,13 @@
+DELETE FROM provisioned_entities WHERE entity_type = 'teacher';
+ALTER TABLE provisioned_entities
+  DROP CONSTRAINT provisioned_entities_entity_type_check,
+  ADD CONSTRAINT provisioned_entities_entity_type_check
+    CHECK (entity_type IN ('session', 'question', 'student'));
+DROP INDEX IF EXISTS idx_quiz_sessions_teacher;
+ALTER TABLE quiz_sessions
+  DROP COLUMN IF EXISTS teacher_id;
+ALTER TABLE question_published_events
+  DROP CONSTRAINT IF EXISTS question_published_events_teacher_id_fkey;
+DROP INDEX IF EXISTS idx_classroom_teachers_teacher;
+DROP TABLE IF EXISTS classroom_teachers;
+DROP TABLE IF EXISTS teachers;
@@ -0,0 +1,56 @@
+CREATE TABLE teachers (
+  teacher_id   UUID      PRIMARY KEY,
+  name         VARCHAR,
+  email        VARCHAR   UNIQUE,
+  sourced_id   VARCHAR   UNIQUE,
+  created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+  archived_at  TIMESTAMP
+);
+
+CREATE TABLE classroom_teachers (
+  classroom_id  UUID      NOT NULL,
+  teacher_id    UUID      NOT NULL,
+  role          VARCHAR   NOT NULL DEFAULT 'primary',
+  assigned_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (classroom_id, teacher_id),
+  FOREIGN KEY (classroom_id)
+    REFERENCES classrooms(classroom_id)
+    ON DELETE CASCADE,
+  FOREIGN KEY (teacher_id)
+    REFERENCES teachers(teacher_id)
+    ON DELETE CASCADE,
+  CHECK (role IN ('primary', 'co_teacher'))
+);
+CREATE INDEX idx_classroom_teachers_teacher
+  ON classroom_teachers (teacher_id);
+
+/* teachers seen on published questions become nameless teacher records */
+INSERT INTO teachers (teacher_id)
+  SELECT DISTINCT teacher_id FROM question_published_events WHERE teacher_id IS NOT NULL;
+
+ALTER TABLE question_published_events
+  ADD FOREIGN KEY (teacher_id)
+    REFERENCES teachers(teacher_id)
+    ON DELETE SET NULL;
+
+ALTER TABLE quiz_sessions
+  ADD COLUMN teacher_id UUID REFERENCES teachers(teacher_id) ON DELETE SET NULL;
+CREATE INDEX idx_quiz_sessions_teacher
+  ON quiz_sessions (teacher_id, started_at);
+
+/* attribute existing sessions to the teacher of their first published question */
+UPDATE quiz_sessions qs
+  SET teacher_id = first_publish.teacher_id
+  FROM (
+    SELECT DISTINCT ON (session_id) session_id, teacher_id
+    FROM question_published_events
+    WHERE teacher_id IS NOT NULL
+    ORDER BY session_id, published_at
+  ) first_publish
+  WHERE first_publish.session_id = qs.session_id;
+
+ALTER TABLE provisioned_entities
+  DROP CONSTRAINT provisioned_entities_entity_type_check,
+  ADD CONSTRAINT provisioned_entities_entity_type_check
+    CHECK (entity_type IN ('session', 'question', 'student', 'teacher'));
@@ -0,0 +1,57 @@
+ALTER TABLE answer_submitted_events DROP COLUMN IF EXISTS is_late;
+DROP INDEX IF EXISTS idx_quiz_sessions_tenant_started_at;
+DROP INDEX IF EXISTS idx_ase_tenant_submitted_at;
+DROP INDEX IF EXISTS idx_users_tenant;
+DROP INDEX IF EXISTS idx_teachers_tenant_email;
+ALTER TABLE teachers ADD CONSTRAINT teachers_email_key UNIQUE (email);
+DROP INDEX IF EXISTS idx_teachers_tenant_sourced_id;
+ALTER TABLE teachers ADD CONSTRAINT teachers_sourced_id_key UNIQUE (sourced_id);
+DROP INDEX IF EXISTS idx_students_tenant_sourced_id;
+ALTER TABLE students ADD CONSTRAINT students_sourced_id_key UNIQUE (sourced_id);
+DROP INDEX IF EXISTS idx_classrooms_tenant_sourced_id;
+ALTER TABLE classrooms ADD CONSTRAINT classrooms_sourced_id_key UNIQUE (sourced_id);
+ALTER TABLE question_skipped_events DROP CONSTRAINT IF EXISTS question_skipped_events_student_tenant_fkey;
+ALTER TABLE question_skipped_events DROP CONSTRAINT IF EXISTS question_skipped_events_question_tenant_fkey;
+ALTER TABLE question_skipped_events DROP CONSTRAINT IF EXISTS question_skipped_events_session_tenant_fkey;
+ALTER TABLE session_presence_events DROP CONSTRAINT IF EXISTS session_presence_events_student_tenant_fkey;
+ALTER TABLE session_presence_events DROP CONSTRAINT IF EXISTS session_presence_events_session_tenant_fkey;
+ALTER TABLE answer_submitted_events DROP CONSTRAINT IF EXISTS answer_submitted_events_student_tenant_fkey;
+ALTER TABLE answer_submitted_events DROP CONSTRAINT IF EXISTS answer_submitted_events_question_tenant_fkey;
+ALTER TABLE answer_submitted_events DROP CONSTRAINT IF EXISTS answer_submitted_events_session_tenant_fkey;
+ALTER TABLE question_published_events DROP CONSTRAINT IF EXISTS question_published_events_teacher_tenant_fkey;
+ALTER TABLE question_published_events DROP CONSTRAINT IF EXISTS question_published_events_question_tenant_fkey;
+ALTER TABLE question_published_events DROP CONSTRAINT IF EXISTS question_published_events_session_tenant_fkey;
+ALTER TABLE classroom_teachers DROP CONSTRAINT IF EXISTS classroom_teachers_teacher_tenant_fkey;
+ALTER TABLE classroom_teachers DROP CONSTRAINT IF EXISTS classroom_teachers_classroom_tenant_fkey;
+ALTER TABLE classroom_enrollments DROP CONSTRAINT IF EXISTS classroom_enrollments_student_tenant_fkey;
+ALTER TABLE classroom_enrollments DROP CONSTRAINT IF EXISTS classroom_enrollments_classroom_tenant_fkey;
+ALTER TABLE classroom_students DROP CONSTRAINT IF EXISTS classroom_students_student_tenant_fkey;
+ALTER TABLE classroom_students DROP CONSTRAINT IF EXISTS classroom_students_classroom_tenant_fkey;
+ALTER TABLE quiz_sessions DROP CONSTRAINT IF EXISTS quiz_sessions_teacher_tenant_fkey;
+ALTER TABLE quiz_sessions DROP CONSTRAINT IF EXISTS quiz_sessions_classroom_tenant_fkey;
+ALTER TABLE quiz_sessions DROP CONSTRAINT IF EXISTS quiz_sessions_quiz_tenant_fkey;
+ALTER TABLE question_versions DROP CONSTRAINT IF EXISTS question_versions_question_tenant_fkey;
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS questions_quiz_tenant_fkey;
+ALTER TABLE quiz_sessions DROP CONSTRAINT IF EXISTS quiz_sessions_tenant_key;
+ALTER TABLE questions DROP CONSTRAINT IF EXISTS questions_tenant_key;
+ALTER TABLE teachers DROP CONSTRAINT IF EXISTS teachers_tenant_key;
+ALTER TABLE students DROP CONSTRAINT IF EXISTS students_tenant_key;
+ALTER TABLE classrooms DROP CONSTRAINT IF EXISTS classrooms_tenant_key;
+ALTER TABLE quizzes DROP CONSTRAINT IF EXISTS quizzes_tenant_key;
+ALTER TABLE provisioned_entities DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE question_skipped_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE session_presence_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE answer_submitted_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE question_published_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE classroom_teachers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE classroom_enrollments DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE classroom_students DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE quiz_sessions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE question_versions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE questions DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE teachers DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE students DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE classrooms DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE quizzes DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+DROP TABLE IF EXISTS tenants;
@@ -0,0 +1,164 @@
+/* organizations (schools or districts) that own all other data */
+CREATE TABLE tenants (
+  tenant_id              UUID      PRIMARY KEY,
+  name                   VARCHAR   NOT NULL,
+  slug                   VARCHAR   NOT NULL UNIQUE,
+  late_answer_policy     VARCHAR   NOT NULL DEFAULT 'reject',
+  late_answer_grace_sec  INT       NOT NULL DEFAULT 0,
+  retention_days         INT,
+  created_at             TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at             TIMESTAMP NOT NULL DEFAULT NOW(),
+  CHECK (late_answer_policy IN ('reject', 'grace', 'accept')),
+  CHECK (late_answer_grace_sec >= 0),
+  CHECK (retention_days IS NULL OR retention_days > 0)
+);
+
+/* everything that exists today belongs to the default tenant */
+INSERT INTO tenants (tenant_id, name, slug)
+  VALUES ('00000000-0000-0000-0000-000000000001', 'Default', 'default');
+
+ALTER TABLE users
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE quizzes
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE quizzes ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE classrooms
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE classrooms ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE students
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE students ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE teachers
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE teachers ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE questions
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE questions ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE question_versions
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE question_versions ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE quiz_sessions
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE quiz_sessions ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE classroom_students
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE classroom_students ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE classroom_enrollments
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE classroom_enrollments ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE classroom_teachers
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE classroom_teachers ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE question_published_events
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE question_published_events ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE answer_submitted_events
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE answer_submitted_events ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE session_presence_events
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE session_presence_events ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE question_skipped_events
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE question_skipped_events ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE provisioned_entities
+  ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000001' REFERENCES tenants(tenant_id);
+ALTER TABLE provisioned_entities ALTER COLUMN tenant_id DROP DEFAULT;
+
+/* composite keys let child rows reference (tenant_id, id), so rows can only point at the same tenant */
+ALTER TABLE quizzes ADD CONSTRAINT quizzes_tenant_key UNIQUE (tenant_id, quiz_id);
+ALTER TABLE classrooms ADD CONSTRAINT classrooms_tenant_key UNIQUE (tenant_id, classroom_id);
+ALTER TABLE students ADD CONSTRAINT students_tenant_key UNIQUE (tenant_id, student_id);
+ALTER TABLE teachers ADD CONSTRAINT teachers_tenant_key UNIQUE (tenant_id, teacher_id);
+ALTER TABLE questions ADD CONSTRAINT questions_tenant_key UNIQUE (tenant_id, question_id);
+ALTER TABLE quiz_sessions ADD CONSTRAINT quiz_sessions_tenant_key UNIQUE (tenant_id, session_id);
+
+ALTER TABLE questions
+  ADD CONSTRAINT questions_quiz_tenant_fkey
+  FOREIGN KEY (tenant_id, quiz_id) REFERENCES quizzes(tenant_id, quiz_id);
+ALTER TABLE question_versions
+  ADD CONSTRAINT question_versions_question_tenant_fkey
+  FOREIGN KEY (tenant_id, question_id) REFERENCES questions(tenant_id, question_id);
+ALTER TABLE quiz_sessions
+  ADD CONSTRAINT quiz_sessions_quiz_tenant_fkey
+  FOREIGN KEY (tenant_id, quiz_id) REFERENCES quizzes(tenant_id, quiz_id);
+ALTER TABLE quiz_sessions
+  ADD CONSTRAINT quiz_sessions_classroom_tenant_fkey
+  FOREIGN KEY (tenant_id, classroom_id) REFERENCES classrooms(tenant_id, classroom_id);
+ALTER TABLE quiz_sessions
+  ADD CONSTRAINT quiz_sessions_teacher_tenant_fkey
+  FOREIGN KEY (tenant_id, teacher_id) REFERENCES teachers(tenant_id, teacher_id);
+ALTER TABLE classroom_students
+  ADD CONSTRAINT classroom_students_classroom_tenant_fkey
+  FOREIGN KEY (tenant_id, classroom_id) REFERENCES classrooms(tenant_id, classroom_id);
+ALTER TABLE classroom_students
+  ADD CONSTRAINT classroom_students_student_tenant_fkey
+  FOREIGN KEY (tenant_id, student_id) REFERENCES students(tenant_id, student_id);
+ALTER TABLE classroom_enrollments
+  ADD CONSTRAINT classroom_enrollments_classroom_tenant_fkey
+  FOREIGN KEY (tenant_id, classroom_id) REFERENCES classrooms(tenant_id, classroom_id);
+ALTER TABLE classroom_enrollments
+  ADD CONSTRAINT classroom_enrollments_student_tenant_fkey
+  FOREIGN KEY (tenant_id, student_id) REFERENCES students(tenant_id, student_id);
+ALTER TABLE classroom_teachers
+  ADD CONSTRAINT classroom_teachers_classroom_tenant_fkey
+  FOREIGN KEY (tenant_id, classroom_id) REFERENCES classrooms(tenant_id, classroom_id);
+ALTER TABLE classroom_teachers
+  ADD CONSTRAINT classroom_teachers_teacher_tenant_fkey
+  FOREIGN KEY (tenant_id, teacher_id) REFERENCES teachers(tenant_id, teacher_id);
+ALTER TABLE question_published_events
+  ADD CONSTRAINT question_published_events_session_tenant_fkey
+  FOREIGN KEY (tenant_id, session_id) REFERENCES quiz_sessions(tenant_id, session_id);
+ALTER TABLE question_published_events
+  ADD CONSTRAINT question_published_events_question_tenant_fkey
+  FOREIGN KEY (tenant_id, question_id) REFERENCES questions(tenant_id, question_id);
+ALTER TABLE question_published_events
+  ADD CONSTRAINT question_published_events_teacher_tenant_fkey
+  FOREIGN KEY (tenant_id, teacher_id) REFERENCES teachers(tenant_id, teacher_id);
+ALTER TABLE answer_submitted_events
+  ADD CONSTRAINT answer_submitted_events_session_tenant_fkey
+  FOREIGN KEY (tenant_id, session_id) REFERENCES quiz_sessions(tenant_id, session_id);
+ALTER TABLE answer_submitted_events
+  ADD CONSTRAINT answer_submitted_events_question_tenant_fkey
+  FOREIGN KEY (tenant_id, question_id) REFERENCES questions(tenant_id, question_id);
+ALTER TABLE answer_submitted_events
+  ADD CONSTRAINT answer_submitted_events_student_tenant_fkey
+  FOREIGN KEY (tenant_id, student_id) REFERENCES students(tenant_id, student_id);
+ALTER TABLE session_presence_events
+  ADD CONSTRAINT session_presence_events_session_tenant_fkey
+  FOREIGN KEY (tenant_id, session_id) REFERENCES quiz_sessions(tenant_id, session_id);
+ALTER TABLE session_presence_events
+  ADD CONSTRAINT session_presence_events_student_tenant_fkey
+  FOREIGN KEY (tenant_id, student_id) REFERENCES students(tenant_id, student_id);
+ALTER TABLE question_skipped_events
+  ADD CONSTRAINT question_skipped_events_session_tenant_fkey
+  FOREIGN KEY (tenant_id, session_id) REFERENCES quiz_sessions(tenant_id, session_id);
+ALTER TABLE question_skipped_events
+  ADD CONSTRAINT question_skipped_events_question_tenant_fkey
+  FOREIGN KEY (tenant_id, question_id) REFERENCES questions(tenant_id, question_id);
+ALTER TABLE question_skipped_events
+  ADD CONSTRAINT question_skipped_events_student_tenant_fkey
+  FOREIGN KEY (tenant_id, student_id) REFERENCES students(tenant_id, student_id);
+
+/* sourcedIds and teacher emails are unique per tenant; idx_* are the names AutoMigrate used */
+ALTER TABLE classrooms DROP CONSTRAINT IF EXISTS classrooms_sourced_id_key;
+DROP INDEX IF EXISTS idx_classrooms_sourced_id;
+CREATE UNIQUE INDEX idx_classrooms_tenant_sourced_id ON classrooms (tenant_id, sourced_id);
+ALTER TABLE students DROP CONSTRAINT IF EXISTS students_sourced_id_key;
+DROP INDEX IF EXISTS idx_students_sourced_id;
+CREATE UNIQUE INDEX idx_students_tenant_sourced_id ON students (tenant_id, sourced_id);
+ALTER TABLE teachers DROP CONSTRAINT IF EXISTS teachers_sourced_id_key;
+DROP INDEX IF EXISTS idx_teachers_sourced_id;
+CREATE UNIQUE INDEX idx_teachers_tenant_sourced_id ON teachers (tenant_id, sourced_id);
+ALTER TABLE teachers DROP CONSTRAINT IF EXISTS teachers_email_key;
+DROP INDEX IF EXISTS idx_teachers_email;
+CREATE UNIQUE INDEX idx_teachers_tenant_email ON teachers (tenant_id, email);
+
+CREATE INDEX idx_users_tenant ON users (tenant_id);
+CREATE INDEX idx_ase_tenant_submitted_at ON answer_submitted_events (tenant_id, submitted_at);
+CREATE INDEX idx_quiz_sessions_tenant_started_at ON quiz_sessions (tenant_id, started_at);
+
+/* answers accepted after the deadline under a tenant's grace or accept policy */
+ALTER TABLE answer_submitted_events
+  ADD COLUMN is_late BOOLEAN NOT NULL DEFAULT false;
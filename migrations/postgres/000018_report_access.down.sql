@@ -0,0 +1,15 @@
+ALTER TABLE users
+  DROP CONSTRAINT IF EXISTS users_access_level_check,
+  DROP CONSTRAINT IF EXISTS users_student_tenant_fkey,
+  DROP CONSTRAINT IF EXISTS users_teacher_tenant_fkey,
+  DROP CONSTRAINT IF EXISTS users_school_tenant_fkey,
+  DROP COLUMN IF EXISTS student_id,
+  DROP COLUMN IF EXISTS teacher_id,
+  DROP COLUMN IF EXISTS school_id,
+  DROP COLUMN IF EXISTS access_level;
+DROP INDEX IF EXISTS idx_classrooms_school;
+ALTER TABLE classrooms
+  DROP CONSTRAINT IF EXISTS classrooms_school_tenant_fkey,
+  DROP COLUMN IF EXISTS school_id;
+DROP INDEX IF EXISTS idx_schools_tenant_sourced_id;
+DROP TABLE IF EXISTS schools;
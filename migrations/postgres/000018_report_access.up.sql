@@ -0,0 +1,36 @@
+/* schools group a tenant's classrooms; principals see every classroom of their school */
+CREATE TABLE schools (
+  school_id    UUID      PRIMARY KEY,
+  tenant_id    UUID      NOT NULL REFERENCES tenants(tenant_id),
+  name         VARCHAR   NOT NULL,
+  sourced_id   VARCHAR,
+  created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+  CONSTRAINT schools_tenant_key UNIQUE (tenant_id, school_id)
+);
+CREATE UNIQUE INDEX idx_schools_tenant_sourced_id ON schools (tenant_id, sourced_id);
+
+ALTER TABLE classrooms
+  ADD COLUMN school_id UUID,
+  ADD CONSTRAINT classrooms_school_tenant_fkey
+    FOREIGN KEY (tenant_id, school_id) REFERENCES schools(tenant_id, school_id);
+CREATE INDEX idx_classrooms_school ON classrooms (school_id);
+
+/* the classrooms and students a user may report on; existing users keep tenant-wide access */
+ALTER TABLE users
+  ADD COLUMN access_level VARCHAR NOT NULL DEFAULT 'tenant',
+  ADD COLUMN school_id    UUID,
+  ADD COLUMN teacher_id   UUID,
+  ADD COLUMN student_id   UUID,
+  ADD CONSTRAINT users_school_tenant_fkey
+    FOREIGN KEY (tenant_id, school_id) REFERENCES schools(tenant_id, school_id),
+  ADD CONSTRAINT users_teacher_tenant_fkey
+    FOREIGN KEY (tenant_id, teacher_id) REFERENCES teachers(tenant_id, teacher_id),
+  ADD CONSTRAINT users_student_tenant_fkey
+    FOREIGN KEY (tenant_id, student_id) REFERENCES students(tenant_id, student_id),
+  ADD CONSTRAINT users_access_level_check CHECK (
+    (access_level = 'tenant'  AND school_id IS NULL     AND teacher_id IS NULL     AND student_id IS NULL) OR
+    (access_level = 'school'  AND school_id IS NOT NULL AND teacher_id IS NULL     AND student_id IS NULL) OR
+    (access_level = 'teacher' AND school_id IS NULL     AND teacher_id IS NOT NULL AND student_id IS NULL) OR
+    (access_level = 'student' AND school_id IS NULL     AND teacher_id IS NULL     AND student_id IS NOT NULL)
+  );
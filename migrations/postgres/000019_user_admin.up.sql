@@ -0,0 +1,3 @@
+/* disabled users keep their row for auditing but cannot log in */
+ALTER TABLE users
+  ADD COLUMN disabled_at TIMESTAMP;
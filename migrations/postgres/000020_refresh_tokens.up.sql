@@ -0,0 +1,20 @@
+/* refresh tokens are stored hashed; each login starts a family that rotation extends */
+CREATE TABLE refresh_tokens (
+  id           UUID      PRIMARY KEY,
+  family_id    UUID      NOT NULL,
+  user_id      INTEGER   NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash   VARCHAR   NOT NULL UNIQUE,
+  expires_at   TIMESTAMP NOT NULL,
+  used_at      TIMESTAMP,
+  revoked_at   TIMESTAMP,
+  created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_refresh_tokens_family ON refresh_tokens (family_id);
+CREATE INDEX idx_refresh_tokens_user ON refresh_tokens (user_id);
+
+/* access tokens revoked before they expire, keyed by their jti */
+CREATE TABLE revoked_tokens (
+  token_id     VARCHAR   PRIMARY KEY,
+  expires_at   TIMESTAMP NOT NULL
+);
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
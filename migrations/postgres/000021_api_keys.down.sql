@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS api_key_classrooms;
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,27 @@
+/* device API keys for whiteboard and notebook apps; only a hash of the key is stored */
+CREATE TABLE api_keys (
+  api_key_id    UUID      PRIMARY KEY,
+  tenant_id     UUID      NOT NULL REFERENCES tenants(tenant_id),
+  name          VARCHAR   NOT NULL,
+  prefix        VARCHAR   NOT NULL UNIQUE,
+  key_hash      VARCHAR   NOT NULL,
+  created_by    INTEGER   REFERENCES users(id) ON DELETE SET NULL,
+  last_used_at  TIMESTAMP,
+  rotated_at    TIMESTAMP,
+  revoked_at    TIMESTAMP,
+  created_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+  updated_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+  CONSTRAINT api_keys_tenant_key UNIQUE (tenant_id, api_key_id)
+);
+
+/* a key with classrooms may only ingest events for them; a key without is tenant-wide */
+CREATE TABLE api_key_classrooms (
+  api_key_id    UUID NOT NULL,
+  tenant_id     UUID NOT NULL,
+  classroom_id  UUID NOT NULL,
+  PRIMARY KEY (api_key_id, classroom_id),
+  CONSTRAINT api_key_classrooms_key_fkey
+    FOREIGN KEY (tenant_id, api_key_id) REFERENCES api_keys(tenant_id, api_key_id) ON DELETE CASCADE,
+  CONSTRAINT api_key_classrooms_classroom_fkey
+    FOREIGN KEY (tenant_id, classroom_id) REFERENCES classrooms(tenant_id, classroom_id)
+);
@@ -0,0 +1,18 @@
+/* failed logins per account ("account:<email>") and per client IP ("ip:<address>") */
+CREATE TABLE login_attempts (
+  attempt_key     VARCHAR   PRIMARY KEY,
+  failures        INTEGER   NOT NULL,
+  last_failed_at  TIMESTAMP NOT NULL,
+  locked_until    TIMESTAMP
+);
+
+/* single-use password reset tokens; only a hash of the token is stored */
+CREATE TABLE password_resets (
+  id           UUID      PRIMARY KEY,
+  user_id      INTEGER   NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  token_hash   VARCHAR   NOT NULL UNIQUE,
+  expires_at   TIMESTAMP NOT NULL,
+  used_at      TIMESTAMP,
+  created_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_password_resets_user ON password_resets (user_id);
@@ -0,0 +1,27 @@
+/* who did what: logins, user and role changes, ingestion batches and admin actions */
+CREATE TABLE audit_log (
+  entry_id          BIGSERIAL PRIMARY KEY,
+  tenant_id         UUID      REFERENCES tenants(tenant_id),
+  occurred_at       TIMESTAMP NOT NULL DEFAULT NOW(),
+  action            VARCHAR   NOT NULL,
+  outcome           VARCHAR   NOT NULL CHECK (outcome IN ('success', 'failure')),
+  actor_user_id     INTEGER,
+  actor_api_key_id  UUID,
+  source_ip         VARCHAR,
+  target_type       VARCHAR,
+  target_id         VARCHAR,
+  details           JSONB
+);
+CREATE INDEX idx_audit_log_tenant_occurred ON audit_log (tenant_id, occurred_at);
+CREATE INDEX idx_audit_log_tenant_action ON audit_log (tenant_id, action);
+
+/* actors are not foreign keys: the log must outlive the users and keys it mentions */
+CREATE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+  BEFORE UPDATE OR DELETE ON audit_log
+  FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_answer_submitted_events_ingested_by;
+
+ALTER TABLE quiz_sessions
+  DROP COLUMN IF EXISTS ingested_by;
+ALTER TABLE question_skipped_events
+  DROP COLUMN IF EXISTS ingested_by;
+ALTER TABLE session_presence_events
+  DROP COLUMN IF EXISTS ingested_by;
+ALTER TABLE answer_submitted_events
+  DROP COLUMN IF EXISTS ingested_by;
+ALTER TABLE question_published_events
+  DROP COLUMN IF EXISTS ingested_by;
@@ -0,0 +1,15 @@
+/* the user (user:<id>), device key (key:<api_key_id>) or external subject (sub:<subject>)
+   that sent the event; NULL for events ingested before it was recorded */
+ALTER TABLE question_published_events
+  ADD COLUMN ingested_by TEXT;
+ALTER TABLE answer_submitted_events
+  ADD COLUMN ingested_by TEXT;
+ALTER TABLE session_presence_events
+  ADD COLUMN ingested_by TEXT;
+ALTER TABLE question_skipped_events
+  ADD COLUMN ingested_by TEXT;
+/* set from the SESSION_STARTED event */
+ALTER TABLE quiz_sessions
+  ADD COLUMN ingested_by TEXT;
+
+CREATE INDEX idx_answer_submitted_events_ingested_by ON answer_submitted_events (tenant_id, ingested_by);
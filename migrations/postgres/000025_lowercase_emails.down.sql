@@ -0,0 +1 @@
+/* the original case of emails is not kept; lowercased emails stay valid */
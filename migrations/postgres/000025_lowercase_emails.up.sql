@@ -0,0 +1,3 @@
+/* emails are stored lowercased so that logins and lockouts ignore case; this fails on
+   two accounts whose emails differ only in case, which must be merged or renamed first */
+UPDATE users SET email = lower(btrim(email)) WHERE email <> lower(btrim(email));
@@ -0,0 +1,12 @@
+/* the old schema has no level without access, so pending users are disabled rather than
+   given tenant-wide access */
+UPDATE users SET access_level = 'tenant', disabled_at = COALESCE(disabled_at, NOW()) WHERE access_level = 'none';
+
+ALTER TABLE users
+  DROP CONSTRAINT users_access_level_check,
+  ADD CONSTRAINT users_access_level_check CHECK (
+    (access_level = 'tenant'  AND school_id IS NULL     AND teacher_id IS NULL     AND student_id IS NULL) OR
+    (access_level = 'school'  AND school_id IS NOT NULL AND teacher_id IS NULL     AND student_id IS NULL) OR
+    (access_level = 'teacher' AND school_id IS NULL     AND teacher_id IS NOT NULL AND student_id IS NULL) OR
+    (access_level = 'student' AND school_id IS NULL     AND teacher_id IS NULL     AND student_id IS NOT NULL)
+  );
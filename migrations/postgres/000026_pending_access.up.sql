@@ -0,0 +1,9 @@
+/* users who signed up themselves have no access until an admin grants it */
+ALTER TABLE users
+  DROP CONSTRAINT users_access_level_check,
+  ADD CONSTRAINT users_access_level_check CHECK (
+    (access_level IN ('tenant', 'none') AND school_id IS NULL AND teacher_id IS NULL AND student_id IS NULL) OR
+    (access_level = 'school'  AND school_id IS NOT NULL AND teacher_id IS NULL     AND student_id IS NULL) OR
+    (access_level = 'teacher' AND school_id IS NULL     AND teacher_id IS NOT NULL AND student_id IS NULL) OR
+    (access_level = 'student' AND school_id IS NULL     AND teacher_id IS NULL     AND student_id IS NOT NULL)
+  );